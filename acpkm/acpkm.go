@@ -0,0 +1,109 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// ACPKM key meshing and CTR-ACPKM mode of operation
+// (R 1323565.1.017-2018, RFC 8645).
+package acpkm
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"fmt"
+)
+
+const KeySize = 32
+
+// Constant D = 0x80 || 0x81 || ... || 0x9F.
+var D = func() (d [KeySize]byte) {
+	for i := 0; i < KeySize; i++ {
+		d[i] = 0x80 + byte(i)
+	}
+	return
+}()
+
+// Next section key: first KeySize bytes of E_K(D_1) || E_K(D_2) || ...
+func Key(c cipher.Block) []byte {
+	key := make([]byte, KeySize)
+	for i := 0; i < KeySize; i += c.BlockSize() {
+		c.Encrypt(key[i:], D[i:])
+	}
+	return key
+}
+
+type CTR struct {
+	newCipher   func(key []byte) cipher.Block
+	c           cipher.Block
+	sectionSize int
+	sectionLeft int
+	ctr         []byte
+	ks          []byte
+	ksOff       int
+}
+
+// Create CTR-ACPKM stream. IV is half the block size long. Key is
+// changed with Key after each sectionSize bytes, that must be multiple
+// of the block size.
+func NewCTR(
+	newCipher func(key []byte) cipher.Block,
+	key, iv []byte,
+	sectionSize int,
+) (*CTR, error) {
+	c := newCipher(key)
+	bs := c.BlockSize()
+	if len(iv) != bs/2 {
+		return nil, fmt.Errorf("gogost/acpkm: invalid IV length (%d!=%d)", len(iv), bs/2)
+	}
+	if sectionSize <= 0 || sectionSize%bs != 0 {
+		return nil, fmt.Errorf("gogost/acpkm: section size %d is not multiple of block size", sectionSize)
+	}
+	s := CTR{
+		newCipher:   newCipher,
+		c:           c,
+		sectionSize: sectionSize,
+		sectionLeft: sectionSize,
+		ctr:         make([]byte, bs),
+		ks:          make([]byte, bs),
+		ksOff:       bs,
+	}
+	copy(s.ctr, iv)
+	return &s, nil
+}
+
+func (s *CTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var n int
+	for len(src) > 0 {
+		if s.ksOff == len(s.ks) {
+			if s.sectionLeft == 0 {
+				s.c = s.newCipher(Key(s.c))
+				s.sectionLeft = s.sectionSize
+			}
+			s.c.Encrypt(s.ks, s.ctr)
+			for i := len(s.ctr) - 1; i >= 0; i-- {
+				s.ctr[i]++
+				if s.ctr[i] != 0 {
+					break
+				}
+			}
+			s.sectionLeft -= len(s.ks)
+			s.ksOff = 0
+		}
+		n = subtle.XORBytes(dst, src, s.ks[s.ksOff:])
+		s.ksOff += n
+		dst, src = dst[n:], src[n:]
+	}
+}
@@ -0,0 +1,165 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package acpkm
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+	"testing/quick"
+
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+)
+
+func newKuznechik(key []byte) cipher.Block {
+	return gost3412128.NewCipher(key)
+}
+
+func newMagma(key []byte) cipher.Block {
+	return gost341264.NewCipher(key)
+}
+
+func TestFirstSectionIsCTR(t *testing.T) {
+	f := func(key [KeySize]byte, iv [8]byte, pt []byte) bool {
+		expected := make([]byte, len(pt))
+		gost3412128.NewCipher(key[:]).NewCTR3413(iv[:]).XORKeyStream(expected, pt)
+		s, err := NewCTR(newKuznechik, key[:], iv[:], 1<<20)
+		if err != nil {
+			return false
+		}
+		got := make([]byte, len(pt))
+		s.XORKeyStream(got, pt)
+		return bytes.Equal(got, expected)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// RFC 8645 A.1: CTR-ACPKM with Kuznyechik and 256-bit sections.
+func TestCTRKuznechikVector(t *testing.T) {
+	key, _ := hex.DecodeString("" +
+		"8899aabbccddeeff0011223344556677" +
+		"fedcba98765432100123456789abcdef")
+	iv, _ := hex.DecodeString("1234567890abcef0")
+	pt, _ := hex.DecodeString("" +
+		"1122334455667700ffeeddccbbaa9988" +
+		"00112233445566778899aabbcceeff0a" +
+		"112233445566778899aabbcceeff0a00" +
+		"2233445566778899aabbcceeff0a0011" +
+		"33445566778899aabbcceeff0a001122" +
+		"445566778899aabbcceeff0a00112233" +
+		"5566778899aabbcceeff0a0011223344")
+	ct, _ := hex.DecodeString("" +
+		"f195d8bec10ed1dbd57b5fa240bda1b8" +
+		"85eee733f6a13e5df33ce4b33c45dee4" +
+		"4bceeb8f646f4c55001706275e85e800" +
+		"587c4df568d094393e4834afd0805046" +
+		"cf30f57686aeece11cfc6c316b8a896e" +
+		"dffd07ec813636460c4f3b743423163e" +
+		"6409a9c282fac8d469d221e7fbd6de5d")
+	s, err := NewCTR(newKuznechik, key, iv, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(pt))
+	for i := 0; i < len(pt); i += 7 {
+		end := i + 7
+		if end > len(pt) {
+			end = len(pt)
+		}
+		s.XORKeyStream(got[i:end], pt[i:end])
+	}
+	if !bytes.Equal(got, ct) {
+		t.FailNow()
+	}
+}
+
+func TestSections(t *testing.T) {
+	for _, newCipher := range []func([]byte) cipher.Block{newKuznechik, newMagma} {
+		key := make([]byte, KeySize)
+		rand.Read(key)
+		c := newCipher(key)
+		bs := c.BlockSize()
+		iv := make([]byte, bs/2)
+		rand.Read(iv)
+		sectionSize := 2 * bs
+		pt := make([]byte, 5*sectionSize+3)
+		rand.Read(pt)
+
+		// Each section is plain CTR with the next key and continued counter
+		expected := make([]byte, len(pt))
+		ctr := append(append([]byte{}, iv...), make([]byte, bs/2)...)
+		for off := 0; off < len(pt); off += sectionSize {
+			end := off + sectionSize
+			if end > len(pt) {
+				end = len(pt)
+			}
+			cipher.NewCTR(c, ctr).XORKeyStream(expected[off:end], pt[off:end])
+			ctr[len(ctr)-1] += byte(sectionSize / bs)
+			c = newCipher(Key(c))
+		}
+
+		s, err := NewCTR(newCipher, key, iv, sectionSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, len(pt))
+		for i := 0; i < len(pt); i += 5 {
+			end := i + 5
+			if end > len(pt) {
+				end = len(pt)
+			}
+			s.XORKeyStream(got[i:end], pt[i:end])
+		}
+		if !bytes.Equal(got, expected) {
+			t.Fatal("differs", bs)
+		}
+	}
+}
+
+func TestKExp15(t *testing.T) {
+	for _, newCipher := range []func([]byte) cipher.Block{newKuznechik, newMagma} {
+		bs := newCipher(make([]byte, KeySize)).BlockSize()
+		kEnc := make([]byte, KeySize)
+		kMac := make([]byte, KeySize)
+		key := make([]byte, KeySize)
+		iv := make([]byte, bs/2)
+		for _, b := range [][]byte{kEnc, kMac, key, iv} {
+			if _, err := rand.Read(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+		exp := KExp15(newCipher, kEnc, kMac, iv, key)
+		if len(exp) != KeySize+bs {
+			t.Fatal("invalid length", bs)
+		}
+		got, err := KImp15(newCipher, kEnc, kMac, iv, exp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, key) {
+			t.Fatal("differs", bs)
+		}
+		exp[0] ^= 1
+		if _, err = KImp15(newCipher, kEnc, kMac, iv, exp); err != ErrKExp15MAC {
+			t.Fatal("corrupted key accepted", bs)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package acpkm
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"errors"
+
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost3413"
+)
+
+var ErrKExp15MAC = errors.New("gogost/acpkm: invalid KExp15 MAC")
+
+func ctr(c cipher.Block, iv []byte) cipher.Stream {
+	ctrIV := make([]byte, c.BlockSize())
+	copy(ctrIV, iv)
+	return cipher.NewCTR(c, ctrIV)
+}
+
+func omac(c cipher.Block, iv, key []byte) []byte {
+	mac, err := gost3413.NewMAC(c, c.BlockSize())
+	if err != nil {
+		panic(err)
+	}
+	mac.Write(iv)
+	mac.Write(key)
+	return mac.Sum(nil)
+}
+
+// KExp15 key export: CTR encryption under kEnc of the key and its OMAC
+// under kMac, computed over IV||key. IV is half the block size long.
+func KExp15(newCipher func(key []byte) cipher.Block, kEnc, kMac, iv, key []byte) []byte {
+	out := append(append([]byte{}, key...), omac(newCipher(kMac), iv, key)...)
+	ctr(newCipher(kEnc), iv).XORKeyStream(out, out)
+	return out
+}
+
+// KImp15 key import, reverse of KExp15. ErrKExp15MAC is returned if MAC
+// does not match.
+func KImp15(newCipher func(key []byte) cipher.Block, kEnc, kMac, iv, data []byte) ([]byte, error) {
+	cMac := newCipher(kMac)
+	if len(data) <= cMac.BlockSize() {
+		return nil, errors.New("gogost/acpkm: KExp15 data is too short")
+	}
+	out := make([]byte, len(data))
+	ctr(newCipher(kEnc), iv).XORKeyStream(out, data)
+	key, tag := out[:len(out)-cMac.BlockSize()], out[len(out)-cMac.BlockSize():]
+	if !hmac.Equal(omac(cMac, iv, key), tag) {
+		return nil, ErrKExp15MAC
+	}
+	return key, nil
+}
+
+// CTR-ACPKM-OMAC encryption and MAC keys (RFC 9337), derived with
+// KDF_TREE_GOSTR3411_2012_256 from the key and the seed.
+func OMACKeys(key, seed []byte) (kEnc, kMac []byte) {
+	keys := gost34112012256.KDFTree(nil, key, []byte("kdf tree"), seed, 1, 2*KeySize)
+	return keys[:KeySize], keys[KeySize:]
+}
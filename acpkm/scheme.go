@@ -0,0 +1,104 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package acpkm
+
+import (
+	"crypto/cipher"
+	"encoding/asn1"
+
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+	"github.com/pedroalbanese/gogost/gost3413"
+)
+
+// Length of the CTR-ACPKM-OMAC key derivation seed in UKM.
+const SeedSize = 8
+
+var (
+	OIDMagmaCTRACPKM         = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 1, 1}
+	OIDMagmaCTRACPKMOMAC     = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 1, 2}
+	OIDKuznechikCTRACPKM     = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 2, 1}
+	OIDKuznechikCTRACPKMOMAC = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 2, 2}
+)
+
+// Kuznechik/Magma CTR-ACPKM(-OMAC) encryption scheme (RFC 9337,
+// R 1323565.1.026-2019).
+type Scheme struct {
+	OID         asn1.ObjectIdentifier
+	NewCipher   func(key []byte) cipher.Block
+	BlockSize   int
+	SectionSize int
+	OMAC        bool
+}
+
+func NewKuznechik(key []byte) cipher.Block {
+	return gost3412128.NewCipher(key)
+}
+
+func NewMagma(key []byte) cipher.Block {
+	return gost341264.NewCipher(key)
+}
+
+var Schemes = []Scheme{
+	{OIDKuznechikCTRACPKM, NewKuznechik, gost3412128.BlockSize, 4096, false},
+	{OIDKuznechikCTRACPKMOMAC, NewKuznechik, gost3412128.BlockSize, 4096, true},
+	{OIDMagmaCTRACPKM, NewMagma, gost341264.BlockSize, 1024, false},
+	{OIDMagmaCTRACPKMOMAC, NewMagma, gost341264.BlockSize, 1024, true},
+}
+
+// Find the scheme by its identifier, nil if unknown.
+func SchemeByOID(oid asn1.ObjectIdentifier) *Scheme {
+	for i := range Schemes {
+		if Schemes[i].OID.Equal(oid) {
+			return &Schemes[i]
+		}
+	}
+	return nil
+}
+
+// Gost3412-15-Encryption-Parameters.
+type Params struct {
+	UKM []byte
+}
+
+// UKM length: half the block size IV, followed by the seed for OMAC
+// schemes.
+func (s *Scheme) UKMSize() int {
+	if s.OMAC {
+		return s.BlockSize/2 + SeedSize
+	}
+	return s.BlockSize / 2
+}
+
+// Encrypt or decrypt src to dst in CTR-ACPKM mode with the scheme's
+// section size. IV is half the block size long.
+func (s *Scheme) Crypt(key, iv, dst, src []byte) {
+	ctr, err := NewCTR(s.NewCipher, key, iv, s.SectionSize)
+	if err != nil {
+		panic(err)
+	}
+	ctr.XORKeyStream(dst, src)
+}
+
+// Full block size OMAC of the data.
+func (s *Scheme) MAC(key, data []byte) []byte {
+	mac, err := gost3413.NewMAC(s.NewCipher(key), s.BlockSize)
+	if err != nil {
+		panic(err)
+	}
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,86 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/internal/caindex"
+)
+
+const (
+	FileCert      = "ca.pem"
+	FileKey       = "ca.key"
+	FileSerial    = "serial"
+	FileCRLNumber = "crlnumber"
+	FileIndex     = "index.txt"
+	DirCerts      = "certs"
+)
+
+func readIndex(dir string) ([]*caindex.Entry, error) {
+	return caindex.Read(filepath.Join(dir, FileIndex))
+}
+
+// Atomically replace the file in the directory.
+func writeFile(dir, name string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Chmod(perm)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if errClose := tmp.Close(); err == nil {
+		err = errClose
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, name))
+}
+
+func writeIndex(dir string, entries []*caindex.Entry) error {
+	return writeFile(dir, FileIndex, caindex.Marshal(entries), 0o644)
+}
+
+// Take the hexadecimal number stored in the file, storing the next one.
+func nextNumber(dir, name string) (*big.Int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+	n, ok := new(big.Int).SetString(strings.TrimSpace(string(data)), 16)
+	if !ok || n.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid number in %s", name)
+	}
+	next := new(big.Int).Add(n, big.NewInt(1))
+	if err = writeFile(dir, name, []byte(caindex.HexSerial(next)+"\n"), 0o644); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func certPath(dir string, serial *big.Int) string {
+	return filepath.Join(dir, DirCerts, caindex.HexSerial(serial)+".pem")
+}
@@ -0,0 +1,479 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Minimal GOST certificate authority: CA initialization, CSR signing,
+// revocation and CRL issuing, with OpenSSL-like on-disk database.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/caindex"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+const (
+	PEMKey = "PRIVATE KEY"
+	PEMCer = "CERTIFICATE"
+	PEMCSR = "CERTIFICATE REQUEST"
+	PEMCRL = "X509 CRL"
+
+	day = 24 * time.Hour
+)
+
+var keyUsages = map[string]x509.KeyUsage{
+	"digitalSignature":  x509.KeyUsageDigitalSignature,
+	"contentCommitment": x509.KeyUsageContentCommitment,
+	"keyEncipherment":   x509.KeyUsageKeyEncipherment,
+	"dataEncipherment":  x509.KeyUsageDataEncipherment,
+	"keyAgreement":      x509.KeyUsageKeyAgreement,
+	"keyCertSign":       x509.KeyUsageCertSign,
+	"cRLSign":           x509.KeyUsageCRLSign,
+	"encipherOnly":      x509.KeyUsageEncipherOnly,
+	"decipherOnly":      x509.KeyUsageDecipherOnly,
+}
+
+var extKeyUsages = map[string]x509.ExtKeyUsage{
+	"any":             x509.ExtKeyUsageAny,
+	"serverAuth":      x509.ExtKeyUsageServerAuth,
+	"clientAuth":      x509.ExtKeyUsageClientAuth,
+	"codeSigning":     x509.ExtKeyUsageCodeSigning,
+	"emailProtection": x509.ExtKeyUsageEmailProtection,
+	"timeStamping":    x509.ExtKeyUsageTimeStamping,
+	"OCSPSigning":     x509.ExtKeyUsageOCSPSigning,
+}
+
+// Comma separated list flag value.
+type list []string
+
+func (l *list) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *list) Set(s string) error {
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s COMMAND [options]
+
+Commands:
+  init    create CA keypair, self-signed certificate and database
+  sign    issue the certificate for the CSR
+  revoke  revoke the issued certificate
+  crl     issue the CRL
+  list    print issued certificates
+  curves  print available curve names
+
+Run "%s COMMAND -h" for command's options.
+`, os.Args[0], os.Args[0])
+	os.Exit(2)
+}
+
+func main() {
+	log.SetFlags(log.Lshortfile)
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+	var err error
+	switch cmd {
+	case "init":
+		err = cmdInit(args)
+	case "sign":
+		err = cmdSign(args)
+	case "revoke":
+		err = cmdRevoke(args)
+	case "crl":
+		err = cmdCRL(args)
+	case "list":
+		err = cmdList(args)
+	case "curves":
+		for _, name := range gost3410.CurveNames() {
+			fmt.Println(name)
+		}
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writePEM(path, typ string, der []byte, perm os.FileMode) error {
+	data := pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+func readPEM(path, typ string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var block *pem.Block
+	for len(data) > 0 {
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type == typ {
+			return block.Bytes, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no %s", path, typ)
+}
+
+func loadCA(dir string) (*x509.Certificate, *gost3410.PrivateKey, error) {
+	der, err := readPEM(filepath.Join(dir, FileCert), PEMCer)
+	if err != nil {
+		return nil, nil, err
+	}
+	cer, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	if der, err = readPEM(filepath.Join(dir, FileKey), PEMKey); err != nil {
+		return nil, nil, err
+	}
+	prv, err := gost3410.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cer, prv, nil
+}
+
+func cmdInit(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	dir := fs.String("dir", ".", "CA directory")
+	curveName := fs.String("curve", "id-tc26-gost-3410-2012-512-paramSetA", "Curve name, see \"curves\" command")
+	subject := fs.String("subject", "", "CA's subject name, like \"CN=Example CA,O=Example,C=RU\"")
+	days := fs.Int("days", 3650, "Validity period in days")
+	pathLen := fs.Int("pathlen", -1, "Maximal number of intermediate CAs, -1 for unlimited")
+	fs.Parse(args)
+
+	curve := gost3410.CurveByName(*curveName)
+	if curve == nil {
+		return fmt.Errorf("unknown curve: %s", *curveName)
+	}
+	if curve.OID() == nil {
+		return errors.New("curve has no parameter set identifier")
+	}
+	name, err := parseName(*subject)
+	if err != nil {
+		return err
+	}
+	if name.CommonName == "" {
+		return errors.New("no CommonName is set")
+	}
+	if _, err = os.Stat(filepath.Join(*dir, FileKey)); err == nil {
+		return errors.New("CA is already initialized")
+	}
+	if err = os.MkdirAll(filepath.Join(*dir, DirCerts), 0o755); err != nil {
+		return err
+	}
+	prv, err := gost3410.GenPrivateKey(curve, rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := gost3410.MarshalPKCS8PrivateKey(prv)
+	if err != nil {
+		return err
+	}
+	if err = writePEM(filepath.Join(*dir, FileKey), PEMKey, der, 0o600); err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               name,
+		NotBefore:             now,
+		NotAfter:              now.Add(time.Duration(*days) * day),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            *pathLen,
+		MaxPathLenZero:        *pathLen == 0,
+	}
+	if der, err = x509gost.CreateCertificate(rand.Reader, &tmpl, &tmpl, prv.Public(), prv); err != nil {
+		return err
+	}
+	if err = writePEM(filepath.Join(*dir, FileCert), PEMCer, der, 0o644); err != nil {
+		return err
+	}
+	for _, f := range []string{FileSerial, FileCRLNumber} {
+		if err = writeFile(*dir, f, []byte("02\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	return writeIndex(*dir, nil)
+}
+
+func cmdSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	dir := fs.String("dir", ".", "CA directory")
+	csrPath := fs.String("csr", "", "Path to PEM with CSR")
+	out := fs.String("out", "", "Path to the resulting PEM certificate, stdout by default")
+	days := fs.Int("days", 365, "Validity period in days")
+	subject := fs.String("subject", "", "Subject name template, like \"CN={{.CN}},O=Example\", CSR's subject by default")
+	var dnsNames, ips, emails, uris, ku, eku list
+	fs.Var(&dnsNames, "dns", "Additional DNS names")
+	fs.Var(&ips, "ip", "Additional IP addresses")
+	fs.Var(&emails, "email", "Additional email addresses")
+	fs.Var(&uris, "uri", "Additional URIs")
+	fs.Var(&ku, "ku", "Key usages, digitalSignature,keyEncipherment by default")
+	fs.Var(&eku, "eku", "Extended key usages, serverAuth,clientAuth by default")
+	noCSRSANs := fs.Bool("no-csr-sans", false, "Ignore CSR's subject alternative names")
+	isCA := fs.Bool("ca", false, "Issue intermediate CA certificate")
+	pathLen := fs.Int("pathlen", -1, "Maximal number of intermediate CAs below, for -ca")
+	var crlURLs, ocspURLs list
+	fs.Var(&crlURLs, "crl-url", "CRL distribution point URLs")
+	fs.Var(&ocspURLs, "ocsp-url", "OCSP responder URLs")
+	fs.Parse(args)
+
+	caCer, caPrv, err := loadCA(*dir)
+	if err != nil {
+		return err
+	}
+	der, err := readPEM(*csrPath, PEMCSR)
+	if err != nil {
+		return err
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return err
+	}
+	if err = x509gost.CheckCertificateRequestSignature(csr); err != nil {
+		return err
+	}
+
+	tmpl := x509.Certificate{
+		RawSubject:            csr.RawSubject,
+		OCSPServer:            ocspURLs,
+		CRLDistributionPoints: crlURLs,
+	}
+	if *subject != "" {
+		tmpl.RawSubject = nil
+		tmpl.Subject, err = templateName(*subject, nameData{
+			Subject:  csr.Subject,
+			CN:       csr.Subject.CommonName,
+			DNSNames: csr.DNSNames,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if !*noCSRSANs {
+		tmpl.DNSNames = csr.DNSNames
+		tmpl.IPAddresses = csr.IPAddresses
+		tmpl.EmailAddresses = csr.EmailAddresses
+		tmpl.URIs = csr.URIs
+	}
+	tmpl.DNSNames = append(tmpl.DNSNames, dnsNames...)
+	tmpl.EmailAddresses = append(tmpl.EmailAddresses, emails...)
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address: %s", s)
+		}
+		tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+	}
+	for _, s := range uris {
+		u, err := url.Parse(s)
+		if err != nil {
+			return err
+		}
+		tmpl.URIs = append(tmpl.URIs, u)
+	}
+
+	if *isCA {
+		tmpl.BasicConstraintsValid = true
+		tmpl.IsCA = true
+		tmpl.MaxPathLen = *pathLen
+		tmpl.MaxPathLenZero = *pathLen == 0
+		if len(ku) == 0 {
+			ku = list{"keyCertSign", "cRLSign", "digitalSignature"}
+		}
+	} else {
+		tmpl.BasicConstraintsValid = true
+		if len(ku) == 0 {
+			ku = list{"digitalSignature", "keyEncipherment"}
+		}
+		if len(eku) == 0 {
+			eku = list{"serverAuth", "clientAuth"}
+		}
+	}
+	for _, name := range ku {
+		usage, ok := keyUsages[name]
+		if !ok {
+			return fmt.Errorf("unknown key usage: %s", name)
+		}
+		tmpl.KeyUsage |= usage
+	}
+	for _, name := range eku {
+		usage, ok := extKeyUsages[name]
+		if !ok {
+			return fmt.Errorf("unknown extended key usage: %s", name)
+		}
+		tmpl.ExtKeyUsage = append(tmpl.ExtKeyUsage, usage)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	tmpl.NotBefore = now
+	tmpl.NotAfter = now.Add(time.Duration(*days) * day)
+	if tmpl.NotAfter.After(caCer.NotAfter) {
+		tmpl.NotAfter = caCer.NotAfter
+	}
+	entries, err := readIndex(*dir)
+	if err != nil {
+		return err
+	}
+	if tmpl.SerialNumber, err = nextNumber(*dir, FileSerial); err != nil {
+		return err
+	}
+	der, err = x509gost.CreateCertificate(rand.Reader, &tmpl, caCer, csr.RawSubjectPublicKeyInfo, caPrv)
+	if err != nil {
+		return err
+	}
+	cer, err := x509.ParseCertificate(der)
+	if err != nil {
+		return err
+	}
+	if err = writePEM(certPath(*dir, cer.SerialNumber), PEMCer, der, 0o644); err != nil {
+		return err
+	}
+	entries = append(entries, &caindex.Entry{
+		NotAfter: cer.NotAfter,
+		Serial:   cer.SerialNumber,
+		Subject:  cer.Subject.String(),
+	})
+	if err = writeIndex(*dir, entries); err != nil {
+		return err
+	}
+	return writePEM(*out, PEMCer, der, 0o644)
+}
+
+func cmdRevoke(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	dir := fs.String("dir", ".", "CA directory")
+	serialHex := fs.String("serial", "", "Hexadecimal serial number of the certificate")
+	reasonName := fs.String("reason", "unspecified", "Revocation reason")
+	fs.Parse(args)
+
+	serial, ok := new(big.Int).SetString(*serialHex, 16)
+	if !ok {
+		return fmt.Errorf("invalid serial: %s", *serialHex)
+	}
+	reason, err := caindex.ReasonByName(*reasonName)
+	if err != nil {
+		return err
+	}
+	entries, err := readIndex(*dir)
+	if err != nil {
+		return err
+	}
+	e := caindex.Lookup(entries, serial)
+	if e == nil {
+		return errors.New("certificate is not found")
+	}
+	if e.Revoked {
+		return errors.New("certificate is already revoked")
+	}
+	e.Revoked = true
+	e.RevokedAt = time.Now().UTC().Truncate(time.Second)
+	e.Reason = reason
+	return writeIndex(*dir, entries)
+}
+
+func cmdCRL(args []string) error {
+	fs := flag.NewFlagSet("crl", flag.ExitOnError)
+	dir := fs.String("dir", ".", "CA directory")
+	days := fs.Int("days", 7, "Days till the next update")
+	out := fs.String("out", "", "Path to the resulting PEM CRL, stdout by default")
+	fs.Parse(args)
+
+	caCer, caPrv, err := loadCA(*dir)
+	if err != nil {
+		return err
+	}
+	entries, err := readIndex(*dir)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	tmpl := x509.RevocationList{
+		ThisUpdate: now,
+		NextUpdate: now.Add(time.Duration(*days) * day),
+	}
+	for _, e := range entries {
+		if !e.Revoked {
+			continue
+		}
+		rc := pkix.RevokedCertificate{SerialNumber: e.Serial, RevocationTime: e.RevokedAt}
+		if e.Reason != 0 {
+			ext, err := x509gost.ReasonCodeExtension(e.Reason)
+			if err != nil {
+				return err
+			}
+			rc.Extensions = []pkix.Extension{ext}
+		}
+		tmpl.RevokedCertificates = append(tmpl.RevokedCertificates, rc)
+	}
+	if tmpl.Number, err = nextNumber(*dir, FileCRLNumber); err != nil {
+		return err
+	}
+	der, err := x509gost.CreateRevocationList(rand.Reader, &tmpl, caCer, caPrv)
+	if err != nil {
+		return err
+	}
+	return writePEM(*out, PEMCRL, der, 0o644)
+}
+
+func cmdList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	dir := fs.String("dir", ".", "CA directory")
+	fs.Parse(args)
+	entries, err := readIndex(*dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Println(e)
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// Data available to the subject name template.
+type nameData struct {
+	// CSR's subject and its common name
+	Subject pkix.Name
+	CN      string
+
+	DNSNames []string
+}
+
+// Split by unescaped separator, unescaping the parts.
+func splitEscaped(s string, sep byte) (parts []string) {
+	var part strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+			part.WriteByte(s[i])
+		case s[i] == sep:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(s[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+func parseOID(s string) (oid asn1.ObjectIdentifier, err error) {
+	for _, arc := range strings.Split(s, ".") {
+		var n int
+		if n, err = strconv.Atoi(arc); err != nil {
+			return nil, fmt.Errorf("invalid OID: %s", s)
+		}
+		oid = append(oid, n)
+	}
+	return
+}
+
+// Parse RFC 4514-like "CN=foo,O=bar,C=RU" string. Attributes not
+// having the short name may be set by dotted OID.
+func parseName(s string) (name pkix.Name, err error) {
+	for _, rdn := range splitEscaped(s, ',') {
+		rdn = strings.TrimSpace(rdn)
+		if rdn == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(rdn, "=")
+		if !ok {
+			return name, fmt.Errorf("invalid name attribute: %s", rdn)
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch strings.ToUpper(k) {
+		case "CN":
+			name.CommonName = v
+		case "C":
+			name.Country = append(name.Country, v)
+		case "O":
+			name.Organization = append(name.Organization, v)
+		case "OU":
+			name.OrganizationalUnit = append(name.OrganizationalUnit, v)
+		case "L":
+			name.Locality = append(name.Locality, v)
+		case "ST":
+			name.Province = append(name.Province, v)
+		case "STREET":
+			name.StreetAddress = append(name.StreetAddress, v)
+		case "POSTALCODE":
+			name.PostalCode = append(name.PostalCode, v)
+		case "SERIALNUMBER":
+			name.SerialNumber = v
+		default:
+			oid, err := parseOID(k)
+			if err != nil {
+				return name, fmt.Errorf("unknown name attribute: %s", k)
+			}
+			name.ExtraNames = append(name.ExtraNames, pkix.AttributeTypeAndValue{Type: oid, Value: v})
+		}
+	}
+	return
+}
+
+// Execute the subject name template and parse the result.
+func templateName(tmpl string, data nameData) (pkix.Name, error) {
+	t, err := template.New("subject").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return pkix.Name{}, err
+	}
+	var buf bytes.Buffer
+	if err = t.Execute(&buf, data); err != nil {
+		return pkix.Name{}, err
+	}
+	return parseName(buf.String())
+}
@@ -1,95 +1,109 @@
-package main
-
-import (
-	"crypto/rand"
-	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
-	"fmt"
-	"io"
-	"math/big"
-	"os"
-	"time"
-
-	"github.com/pedroalbanese/gogost/gost3410"
-)
-
-func main() {
-	// CA certificate
-	data, err := os.ReadFile("/home/stargrave/secure/ca/ee/gost/cagost.cypherpunks.ru/www.cypherpunks.ru/cer.pem")
-	if err != nil {
-		panic(err)
-	}
-	b, _ := pem.Decode(data)
-	if b == nil || b.Type != "CERTIFICATE" {
-		panic("no CERTIFICATE")
-	}
-	caCert, err := x509.ParseCertificate(b.Bytes)
-	if err != nil {
-		panic(err)
-	}
-
-	// CA key
-	data, err = os.ReadFile("/home/stargrave/secure/ca/ee/gost/cagost.cypherpunks.ru/www.cypherpunks.ru/key.pem")
-	if err != nil {
-		panic(err)
-	}
-	b, _ = pem.Decode(data)
-	if b == nil || b.Type != "PRIVATE KEY" {
-		panic("no PRIVATE KEY")
-	}
-	caKey, err := x509.ParsePKCS8PrivateKey(b.Bytes)
-	if err != nil {
-		panic(err)
-	}
-
-	// CSR
-	curve := gost3410.CurveIdtc26gost341012256paramSetA()
-	eeKeyRaw := make([]byte, curve.PointSize())
-	if _, err := io.ReadFull(rand.Reader, eeKeyRaw); err != nil {
-		panic(err)
-	}
-	eeKey, err := gost3410.NewPrivateKey(curve, eeKeyRaw)
-	if err != nil {
-		panic(err)
-	}
-	cn := "example.com"
-	csrTmpl := x509.CertificateRequest{
-		SignatureAlgorithm: x509.GOST256,
-		Subject:            pkix.Name{CommonName: cn},
-		DNSNames:           []string{cn},
-	}
-	csrDer, err := x509.CreateCertificateRequest(
-		rand.Reader,
-		&csrTmpl, &gost3410.PrivateKeyReverseDigest{Prv: eeKey},
-	)
-	csr, err := x509.ParseCertificateRequest(csrDer)
-	if err != nil {
-		panic(err)
-	}
-
-	// Issue
-	cerTmpl := x509.Certificate{
-		DNSNames:           csr.DNSNames,
-		KeyUsage:           x509.KeyUsageDigitalSignature,
-		NotBefore:          time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
-		NotAfter:           time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
-		SerialNumber:       big.NewInt(12345),
-		SignatureAlgorithm: x509.GOST256,
-		Subject:            csr.Subject,
-	}
-	cerDer, err := x509.CreateCertificate(
-		rand.Reader,
-		&cerTmpl, caCert, csr.PublicKey,
-		&gost3410.PrivateKeyReverseDigest{Prv: caKey.(*gost3410.PrivateKey)},
-	)
-	if err != nil {
-		panic(err)
-	}
-	cer, err := x509.ParseCertificate(cerDer)
-	if err != nil {
-		panic(err)
-	}
-	fmt.Println(cer)
-	os.WriteFile("/tmp/cer", cerDer, 0o666)
-}
+// Example of CSR creation and its signing by the CA.
+package main
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+func main() {
+	caCertPath := flag.String("ca-cert", "cer.pem", "Path to PEM with CA certificate")
+	caKeyPath := flag.String("ca-key", "key.pem", "Path to PEM with CA PKCS#8 private key")
+	cn := flag.String("cn", "example.com", "Subject's CommonName and DNS name")
+	out := flag.String("out", "", "Path to the resulting DER certificate")
+	flag.Parse()
+
+	// CA certificate
+	data, err := os.ReadFile(*caCertPath)
+	if err != nil {
+		panic(err)
+	}
+	b, _ := pem.Decode(data)
+	if b == nil || b.Type != "CERTIFICATE" {
+		panic("no CERTIFICATE")
+	}
+	caCert, err := x509.ParseCertificate(b.Bytes)
+	if err != nil {
+		panic(err)
+	}
+
+	// CA key
+	data, err = os.ReadFile(*caKeyPath)
+	if err != nil {
+		panic(err)
+	}
+	b, _ = pem.Decode(data)
+	if b == nil || b.Type != "PRIVATE KEY" {
+		panic("no PRIVATE KEY")
+	}
+	caKey, err := gost3410.ParsePKCS8PrivateKey(b.Bytes)
+	if err != nil {
+		panic(err)
+	}
+
+	// CSR
+	curve := gost3410.CurveIdtc26gost341012256paramSetA()
+	eeKey, err := gost3410.GenPrivateKey(curve, rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	csrTmpl := x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: *cn},
+		DNSNames: []string{*cn},
+	}
+	csrDer, err := x509gost.CreateCertificateRequest(rand.Reader, &csrTmpl, eeKey)
+	if err != nil {
+		panic(err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrDer)
+	if err != nil {
+		panic(err)
+	}
+	if err = x509gost.CheckCertificateRequestSignature(csr); err != nil {
+		panic(err)
+	}
+
+	// Issue
+	cerTmpl := x509.Certificate{
+		DNSNames:     csr.DNSNames,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		NotBefore:    time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		SerialNumber: big.NewInt(12345),
+		Subject:      csr.Subject,
+	}
+	cerDer, err := x509gost.CreateCertificate(
+		rand.Reader, &cerTmpl, caCert, csr.RawSubjectPublicKeyInfo, caKey,
+	)
+	if err != nil {
+		panic(err)
+	}
+	if err = x509gost.CheckSignatureFrom(mustParse(cerDer), caCert); err != nil {
+		panic(err)
+	}
+	if *out == "" {
+		fmt.Println(mustParse(cerDer).Subject)
+		return
+	}
+	if err = os.WriteFile(*out, cerDer, 0o666); err != nil {
+		panic(err)
+	}
+}
+
+func mustParse(der []byte) *x509.Certificate {
+	cer, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(err)
+	}
+	return cer
+}
@@ -13,35 +13,188 @@
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// DANE's SPKI hash calculator
+// DANE TLSA records generator and verifier
 package main
 
 import (
-	"crypto/sha256"
+	"bufio"
 	"crypto/x509"
-	"encoding/hex"
 	"encoding/pem"
 	"flag"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/dane"
 )
 
+func readCerts(data []byte) (certs []*x509.Certificate, err error) {
+	var block *pem.Block
+	for len(data) > 0 {
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		var cer *x509.Certificate
+		if cer, err = x509.ParseCertificate(block.Bytes); err != nil {
+			return
+		}
+		certs = append(certs, cer)
+	}
+	return
+}
+
+func parseNums(s string) (nums []uint8, err error) {
+	for _, f := range strings.Split(s, ",") {
+		var n uint64
+		if n, err = strconv.ParseUint(strings.TrimSpace(f), 10, 8); err != nil {
+			return
+		}
+		nums = append(nums, uint8(n))
+	}
+	return
+}
+
+// Certificates the usage applies to: end entity one for *-EE, CA ones
+// (or the last one, if there are none) for *-TA.
+func usageCerts(chain []*x509.Certificate, usage uint8) (certs []*x509.Certificate) {
+	if usage == dane.UsageDANEEE || usage == dane.UsagePKIXEE {
+		return chain[:1]
+	}
+	for _, cer := range chain {
+		if cer.IsCA {
+			certs = append(certs, cer)
+		}
+	}
+	if len(certs) == 0 {
+		certs = chain[len(chain)-1:]
+	}
+	return
+}
+
+func verify(chain []*x509.Certificate, path string, opts dane.VerifyOptions) bool {
+	fd, err := os.Open(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer fd.Close()
+	matched := false
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' {
+			continue
+		}
+		tlsa, err := dane.ParseTLSA(line)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = dane.Verify(tlsa, chain, opts); err == nil {
+			fmt.Println("OK", tlsa)
+			matched = true
+		} else {
+			fmt.Println("FAIL", tlsa, err)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return matched
+}
+
 func main() {
+	name := flag.String("name", "", "Owner name, like _443._tcp.example.com., to output whole resource records")
+	usages := flag.String("usage", "3", "Comma-separated certificate usages")
+	selectors := flag.String("selector", "1", "Comma-separated selectors")
+	matchings := flag.String("matching", "1", "Comma-separated matching types")
+	all := flag.Bool("all", false, "Output all usages, selectors and matching types")
+	streebog256 := flag.Uint("streebog256", 0, "Matching type number for Streebog-256, like 255, 0 to disable")
+	streebog512 := flag.Uint("streebog512", 0, "Matching type number for Streebog-512, 0 to disable")
+	verifyPath := flag.String("verify", "", "Verify the chain against TLSA records from that file")
+	rootsPath := flag.String("roots", "", "Path to PEM with trust anchors for PKIX-* usages")
+	dnsName := flag.String("dnsname", "", "Check that the certificate is valid for that name")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] < chain.pem\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Chain starts with the end entity certificate.")
+		flag.PrintDefaults()
+	}
 	flag.Parse()
+	log.SetFlags(0)
+
+	if *streebog256 > 255 || *streebog512 > 255 {
+		log.Fatal("too big matching type")
+	}
+	hs := dane.StreebogHashes(uint8(*streebog256), uint8(*streebog512))
 	data, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		log.Fatal(err)
 	}
-	b, _ := pem.Decode(data)
-	if b == nil || b.Type != "CERTIFICATE" {
-		log.Fatal("no CERTIFICATE")
-	}
-	cer, err := x509.ParseCertificate(b.Bytes)
+	chain, err := readCerts(data)
 	if err != nil {
 		log.Fatal(err)
 	}
-	h := sha256.Sum256(cer.RawSubjectPublicKeyInfo)
-	fmt.Println(hex.EncodeToString(h[:]))
+	if len(chain) == 0 {
+		log.Fatal("no CERTIFICATE")
+	}
+
+	if *verifyPath != "" {
+		opts := dane.VerifyOptions{DNSName: *dnsName, Hashes: hs}
+		if *rootsPath != "" {
+			if data, err = os.ReadFile(*rootsPath); err != nil {
+				log.Fatal(err)
+			}
+			if opts.Roots, err = readCerts(data); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if !verify(chain, *verifyPath, opts) {
+			os.Exit(1)
+		}
+		return
+	}
+
+	var us, ss, ms []uint8
+	if *all {
+		us = []uint8{dane.UsagePKIXTA, dane.UsagePKIXEE, dane.UsageDANETA, dane.UsageDANEEE}
+		ss = []uint8{dane.SelectorCert, dane.SelectorSPKI}
+		ms = []uint8{dane.MatchingExact, dane.MatchingSHA256, dane.MatchingSHA512}
+		for _, mt := range []uint{*streebog256, *streebog512} {
+			if mt != 0 {
+				ms = append(ms, uint8(mt))
+			}
+		}
+	} else {
+		if us, err = parseNums(*usages); err != nil {
+			log.Fatal(err)
+		}
+		if ss, err = parseNums(*selectors); err != nil {
+			log.Fatal(err)
+		}
+		if ms, err = parseNums(*matchings); err != nil {
+			log.Fatal(err)
+		}
+	}
+	prefix := ""
+	if *name != "" {
+		prefix = *name + " IN TLSA "
+	}
+	for _, u := range us {
+		for _, cer := range usageCerts(chain, u) {
+			for _, s := range ss {
+				for _, m := range ms {
+					tlsa, err := dane.NewTLSA(cer, u, s, m, hs)
+					if err != nil {
+						log.Fatal(err)
+					}
+					fmt.Println(prefix + tlsa.String())
+				}
+			}
+		}
+	}
 }
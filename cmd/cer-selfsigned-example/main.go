@@ -26,10 +26,12 @@ import (
 	"log"
 	"math/big"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/pedroalbanese/gogost/gost3410"
 	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/x509gost"
 )
 
 const (
@@ -37,7 +39,7 @@ const (
 	PEMCer = "CERTIFICATE"
 )
 
-func loadKeypair(filename string) (cer *x509.Certificate, prv any, err error) {
+func loadKeypair(filename string) (cer *x509.Certificate, prv *gost3410.PrivateKey, err error) {
 	var data []byte
 	data, err = os.ReadFile(filename)
 	if err != nil {
@@ -53,7 +55,7 @@ func loadKeypair(filename string) (cer *x509.Certificate, prv any, err error) {
 		case PEMCer:
 			cer, err = x509.ParseCertificate(block.Bytes)
 		case PEMKey:
-			prv, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+			prv, err = gost3410.ParsePKCS8PrivateKey(block.Bytes)
 		}
 		if err != nil {
 			return
@@ -67,7 +69,7 @@ func main() {
 	cn := flag.String("cn", "", "Subject's CommonName")
 	country := flag.String("country", "", "Subject's Country")
 	serial := flag.Int64("serial", -1, "Serial number")
-	ai := flag.String("ai", "", "Signing algorithm: {256[ABCD],512[ABC]}")
+	curveName := flag.String("curve", "id-tc26-gost-3410-2012-256-paramSetA", "Curve name")
 	issueWith := flag.String("issue-with", "", "Path to PEM with CA to issue the child")
 	reuseKey := flag.String("reuse-key", "", "Path to PEM with the key to reuse")
 	outKey := flag.String("out-key", "", "Path to PEM with the resulting key")
@@ -79,46 +81,22 @@ func main() {
 	if *cn == "" {
 		log.Fatal("no CommonName is set")
 	}
-	var curve *gost3410.Curve
-	var sigAlg x509.SignatureAlgorithm
-	switch *ai {
-	case "256A":
-		curve = gost3410.CurveIdtc26gost341012256paramSetA()
-		sigAlg = x509.GOST256
-	case "256B":
-		curve = gost3410.CurveIdtc26gost341012256paramSetB()
-		sigAlg = x509.GOST256
-	case "256C":
-		curve = gost3410.CurveIdtc26gost341012256paramSetC()
-		sigAlg = x509.GOST256
-	case "256D":
-		curve = gost3410.CurveIdtc26gost341012256paramSetD()
-		sigAlg = x509.GOST256
-	case "512A":
-		curve = gost3410.CurveIdtc26gost341012512paramSetA()
-		sigAlg = x509.GOST512
-	case "512B":
-		curve = gost3410.CurveIdtc26gost341012512paramSetB()
-		sigAlg = x509.GOST512
-	case "512C":
-		curve = gost3410.CurveIdtc26gost341012512paramSetC()
-		sigAlg = x509.GOST512
-	default:
-		log.Fatal("unknown curve name")
+	curve := gost3410.CurveByName(*curveName)
+	if curve == nil {
+		log.Fatalf("unknown curve name, choose one of:\n%s", strings.Join(gost3410.CurveNames(), "\n"))
 	}
 
 	var err error
 	var caCer *x509.Certificate
-	var caPrv any
+	var caPrv *gost3410.PrivateKey
 	if *issueWith != "" {
 		caCer, caPrv, err = loadKeypair(*issueWith)
 		if err != nil {
 			log.Fatal(err)
 		}
-		sigAlg = caCer.SignatureAlgorithm
 	}
 
-	var prv any
+	var prv *gost3410.PrivateKey
 	if *reuseKey == "" {
 		prvRaw := make([]byte, curve.PointSize())
 		if _, err := io.ReadFull(rand.Reader, prvRaw); err != nil {
@@ -128,7 +106,7 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		data, err := x509.MarshalPKCS8PrivateKey(prv)
+		data, err := gost3410.MarshalPKCS8PrivateKey(prv)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -179,7 +157,7 @@ func main() {
 		subj.Country = []string{*country}
 	}
 
-	pub, err := prv.(*gost3410.PrivateKey).PublicKey()
+	pub, err := prv.PublicKey()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -191,12 +169,11 @@ func main() {
 	spki = spki[:20]
 
 	cerTmpl := x509.Certificate{
-		NotBefore:          notBefore,
-		NotAfter:           notAfter,
-		SerialNumber:       sn,
-		SignatureAlgorithm: sigAlg,
-		Subject:            subj,
-		SubjectKeyId:       spki,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		SerialNumber: sn,
+		Subject:      subj,
+		SubjectKeyId: spki,
 	}
 	if *ca {
 		cerTmpl.BasicConstraintsValid = true
@@ -211,11 +188,7 @@ func main() {
 		caCer = &cerTmpl
 		caPrv = prv
 	}
-	data, err := x509.CreateCertificate(
-		rand.Reader,
-		&cerTmpl, caCer, pub,
-		&gost3410.PrivateKeyReverseDigest{Prv: caPrv.(*gost3410.PrivateKey)},
-	)
+	data, err := x509gost.CreateCertificate(rand.Reader, &cerTmpl, caCer, pub, caPrv)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,206 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+)
+
+func cmdGenKey(args []string) error {
+	fs := flag.NewFlagSet("genkey", flag.ExitOnError)
+	curveName := fs.String("curve", "id-tc26-gost-3410-2012-256-paramSetA", "Curve name, see \"curves\" command")
+	out := fs.String("out", "", "Output PEM file")
+	fs.Parse(args)
+	curve := gost3410.CurveByName(*curveName)
+	if curve == nil {
+		return fmt.Errorf("unknown curve: %s", *curveName)
+	}
+	prv, err := gost3410.GenPrivateKey(curve, rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := gost3410.MarshalPKCS8PrivateKey(prv)
+	if err != nil {
+		return err
+	}
+	return writePEM(*out, PEMKey, der, 0o600)
+}
+
+func cmdPubKey(args []string) error {
+	fs := flag.NewFlagSet("pubkey", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Private key PEM file")
+	out := fs.String("out", "", "Output PEM file")
+	fs.Parse(args)
+	prv, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	pub, err := prv.PublicKey()
+	if err != nil {
+		return err
+	}
+	der, err := gost3410.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return err
+	}
+	return writePEM(*out, PEMPub, der, 0o666)
+}
+
+// Streebog digest of the input for the curve's key size.
+func digest(curve *gost3410.Curve, path string) ([]byte, error) {
+	var h hash.Hash
+	if curve.PointSize() == 64 {
+		h = gost34112012512.New()
+	} else {
+		h = gost34112012256.New()
+	}
+	r, err := openIn(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if _, err = io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+const signHelp = "Signature is s||r over the reversed Streebog-256/512 digest, as in X.509 and CMS, hex encoded"
+
+func cmdSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Private key PEM file")
+	in := fs.String("in", "", "Input file")
+	out := fs.String("out", "", "Output signature file")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, signHelp)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	prv, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	dgst, err := digest(prv.C, *in)
+	if err != nil {
+		return err
+	}
+	sig, err := (&gost3410.PrivateKeyReverseDigest{Prv: prv}).Sign(rand.Reader, dgst, nil)
+	if err != nil {
+		return err
+	}
+	w, err := openOut(*out, 0o666)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintln(w, hex.EncodeToString(sig)); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func cmdVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	pubPath := fs.String("pub", "", "Public key or certificate PEM file")
+	in := fs.String("in", "", "Input file")
+	sigHex := fs.String("sig", "", "Hexadecimal signature")
+	sigPath := fs.String("sig-file", "", "File with hexadecimal signature")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, signHelp)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	pub, err := loadPublicKey(*pubPath)
+	if err != nil {
+		return err
+	}
+	if *sigPath != "" {
+		data, err := os.ReadFile(*sigPath)
+		if err != nil {
+			return err
+		}
+		*sigHex = string(bytes.TrimSpace(data))
+	}
+	sig, err := hex.DecodeString(*sigHex)
+	if err != nil {
+		return err
+	}
+	if len(sig) != 2*pub.C.PointSize() {
+		return fmt.Errorf("%w: signature must be %d bytes long", errInvalid, 2*pub.C.PointSize())
+	}
+	dgst, err := digest(pub.C, *in)
+	if err != nil {
+		return err
+	}
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(dgst, sig)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return fmt.Errorf("%w: invalid signature", errInvalid)
+	}
+	return nil
+}
+
+func cmdVKO(args []string) error {
+	fs := flag.NewFlagSet("vko", flag.ExitOnError)
+	keyPath := fs.String("key", "", "Our private key PEM file")
+	peerPath := fs.String("peer", "", "Remote side's public key or certificate PEM file")
+	ukmHex := fs.String("ukm", "01", "Hexadecimal little-endian UKM")
+	size := fs.Int("size", 256, "VKO GOST R 34.10-2012 output size: 256 or 512 bits")
+	fs.Parse(args)
+	prv, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	pub, err := loadPublicKey(*peerPath)
+	if err != nil {
+		return err
+	}
+	if !pub.C.Equal(prv.C) {
+		return fmt.Errorf("keys are on different curves")
+	}
+	ukm, err := hex.DecodeString(*ukmHex)
+	if err != nil {
+		return err
+	}
+	var kek []byte
+	switch *size {
+	case 256:
+		kek, err = prv.KEK2012256(pub, gost3410.NewUKM(ukm))
+	case 512:
+		kek, err = prv.KEK2012512(pub, gost3410.NewUKM(ukm))
+	default:
+		return fmt.Errorf("invalid size: %d", *size)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Println(hex.EncodeToString(kek))
+	return nil
+}
@@ -0,0 +1,220 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command-line GOST cryptography tool: keys generation, signing,
+// verification, key agreement, hashing, MACs and encryption.
+package main
+
+import (
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pedroalbanese/gogost"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+const (
+	PEMKey = "PRIVATE KEY"
+	PEMPub = "PUBLIC KEY"
+	PEMCer = "CERTIFICATE"
+
+	// Exit codes.
+	exitOK      = 0
+	exitInvalid = 1
+	exitError   = 2
+)
+
+// Invalid signature, MAC or ciphertext authentication failure.
+var errInvalid = errors.New("verification failed")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s COMMAND [options]
+
+Commands:
+  genkey  generate PKCS#8 private key
+  pubkey  print public key of the private one
+  sign    sign data
+  verify  verify data signature
+  vko     VKO shared key agreement
+  hash    hash data
+  mac     authenticate data
+  enc     encrypt data with the MGM-based stream format
+  dec     decrypt data
+  curves  print available curve names
+  version print version
+
+Keys are PEM files: PKCS#8 private keys, SubjectPublicKeyInfo public
+keys, certificates are also accepted as public keys. Data is read from
+stdin and written to stdout by default.
+
+Exit codes: 0 on success, 1 on verification or authentication failure,
+2 on any other error.
+
+Run "%s COMMAND -h" for command's options.
+`, os.Args[0], os.Args[0])
+	os.Exit(exitError)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+	var err error
+	switch cmd {
+	case "genkey":
+		err = cmdGenKey(args)
+	case "pubkey":
+		err = cmdPubKey(args)
+	case "sign":
+		err = cmdSign(args)
+	case "verify":
+		err = cmdVerify(args)
+	case "vko":
+		err = cmdVKO(args)
+	case "hash":
+		err = cmdHash(args)
+	case "mac":
+		err = cmdMAC(args)
+	case "enc":
+		err = cmdEnc(args)
+	case "dec":
+		err = cmdDec(args)
+	case "curves":
+		for _, name := range gost3410.CurveNames() {
+			fmt.Println(name)
+		}
+	case "version":
+		fmt.Println(gogost.Version)
+	default:
+		usage()
+	}
+	if err == nil {
+		os.Exit(exitOK)
+	}
+	fmt.Fprintln(os.Stderr, cmd+":", err)
+	if errors.Is(err, errInvalid) {
+		os.Exit(exitInvalid)
+	}
+	os.Exit(exitError)
+}
+
+// Open the file for reading, stdin if path is empty or "-".
+func openIn(path string) (io.ReadCloser, error) {
+	if path == "" || path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// Create the file for writing, stdout if path is empty or "-".
+func openOut(path string, perm os.FileMode) (io.WriteCloser, error) {
+	if path == "" || path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+}
+
+func writePEM(path, typ string, der []byte, perm os.FileMode) error {
+	w, err := openOut(path, perm)
+	if err != nil {
+		return err
+	}
+	if err = pem.Encode(w, &pem.Block{Type: typ, Bytes: der}); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Read the first PEM block of any of the types.
+func readPEM(path string, types ...string) (*pem.Block, error) {
+	if path == "" {
+		return nil, errors.New("no key file specified")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var block *pem.Block
+	for len(data) > 0 {
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		for _, typ := range types {
+			if block.Type == typ {
+				return block, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s: no %v found", path, types)
+}
+
+func loadPrivateKey(path string) (*gost3410.PrivateKey, error) {
+	block, err := readPEM(path, PEMKey)
+	if err != nil {
+		return nil, err
+	}
+	return gost3410.ParsePKCS8PrivateKey(block.Bytes)
+}
+
+func loadPublicKey(path string) (*gost3410.PublicKey, error) {
+	block, err := readPEM(path, PEMPub, PEMCer)
+	if err != nil {
+		return nil, err
+	}
+	if block.Type == PEMPub {
+		return gost3410.ParsePKIXPublicKey(block.Bytes)
+	}
+	cer, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	pub, _, err := x509gost.PublicKey(cer)
+	return pub, err
+}
+
+// Symmetric key either from hexadecimal string, or from the file with
+// raw bytes. Zero size means any.
+func loadSymKey(keyHex, keyPath string, size int) (key []byte, err error) {
+	switch {
+	case keyHex != "" && keyPath != "":
+		return nil, errors.New("both -key and -key-file are specified")
+	case keyHex != "":
+		key, err = hex.DecodeString(keyHex)
+	case keyPath != "":
+		key, err = os.ReadFile(keyPath)
+	default:
+		return nil, errors.New("no key specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if size != 0 && len(key) != size {
+		return nil, fmt.Errorf("key must be %d bytes long", size)
+	}
+	return key, nil
+}
@@ -0,0 +1,252 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/gost341194"
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+	"github.com/pedroalbanese/gogost/gost3413"
+	"github.com/pedroalbanese/gogost/mgm"
+	"github.com/pedroalbanese/gogost/mgmstream"
+)
+
+var hashes = map[string]func() hash.Hash{
+	"streebog256": gost34112012256.New,
+	"streebog512": gost34112012512.New,
+	"gost94": func() hash.Hash {
+		return gost341194.New(&gost28147.SboxIdGostR341194CryptoProParamSet)
+	},
+}
+
+func names[V any](m map[string]V) string {
+	var ns []string
+	for n := range m {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return strings.Join(ns, ", ")
+}
+
+func hashFile(h hash.Hash, path string) ([]byte, error) {
+	r, err := openIn(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	if _, err = io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func cmdHash(args []string) error {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	algo := fs.String("algo", "streebog256", "Hash algorithm: "+names(hashes))
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: hash [options] [FILE ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	newHash := hashes[*algo]
+	if newHash == nil {
+		return fmt.Errorf("unknown algorithm: %s", *algo)
+	}
+	if fs.NArg() == 0 {
+		sum, err := hashFile(newHash(), "")
+		if err != nil {
+			return err
+		}
+		fmt.Println(hex.EncodeToString(sum))
+		return nil
+	}
+	for _, path := range fs.Args() {
+		sum, err := hashFile(newHash(), path)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s  %s\n", hex.EncodeToString(sum), path)
+	}
+	return nil
+}
+
+// MAC constructors of the key.
+var macs = map[string]func(key []byte) (hash.Hash, error){
+	"hmac-streebog256": func(key []byte) (hash.Hash, error) {
+		return hmac.New(gost34112012256.New, key), nil
+	},
+	"hmac-streebog512": func(key []byte) (hash.Hash, error) {
+		return hmac.New(gost34112012512.New, key), nil
+	},
+	"cmac-kuznechik": func(key []byte) (hash.Hash, error) {
+		if len(key) != gost3412128.KeySize {
+			return nil, fmt.Errorf("key must be %d bytes long", gost3412128.KeySize)
+		}
+		return gost3413.NewMAC(gost3412128.NewCipher(key), gost3412128.BlockSize)
+	},
+	"cmac-magma": func(key []byte) (hash.Hash, error) {
+		if len(key) != gost341264.KeySize {
+			return nil, fmt.Errorf("key must be %d bytes long", gost341264.KeySize)
+		}
+		return gost3413.NewMAC(gost341264.NewCipher(key), gost341264.BlockSize)
+	},
+	"gost28147": func(key []byte) (hash.Hash, error) {
+		if len(key) != gost28147.KeySize {
+			return nil, fmt.Errorf("key must be %d bytes long", gost28147.KeySize)
+		}
+		return gost28147.NewCipher(key, gost28147.SboxDefault).NewMAC(8, make([]byte, gost28147.BlockSize))
+	},
+}
+
+func cmdMAC(args []string) error {
+	fs := flag.NewFlagSet("mac", flag.ExitOnError)
+	algo := fs.String("algo", "hmac-streebog256", "MAC algorithm: "+names(macs))
+	keyHex := fs.String("key", "", "Hexadecimal key")
+	keyPath := fs.String("key-file", "", "File with raw key")
+	in := fs.String("in", "", "Input file")
+	expected := fs.String("check", "", "Compare with that hexadecimal MAC instead of printing")
+	fs.Parse(args)
+	newMAC := macs[*algo]
+	if newMAC == nil {
+		return fmt.Errorf("unknown algorithm: %s", *algo)
+	}
+	key, err := loadSymKey(*keyHex, *keyPath, 0)
+	if err != nil {
+		return err
+	}
+	m, err := newMAC(key)
+	if err != nil {
+		return err
+	}
+	sum, err := hashFile(m, *in)
+	if err != nil {
+		return err
+	}
+	if *expected == "" {
+		fmt.Println(hex.EncodeToString(sum))
+		return nil
+	}
+	tag, err := hex.DecodeString(*expected)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(tag, sum) {
+		return fmt.Errorf("%w: MAC mismatch", errInvalid)
+	}
+	return nil
+}
+
+var ciphers = map[string]mgmstream.Algo{
+	"kuznechik": mgmstream.AlgoKuznechik,
+	"magma":     mgmstream.AlgoMagma,
+}
+
+func cmdEnc(args []string) error {
+	fs := flag.NewFlagSet("enc", flag.ExitOnError)
+	cipherName := fs.String("cipher", "kuznechik", "Cipher: "+names(ciphers))
+	keyHex := fs.String("key", "", "Hexadecimal key, random one is generated and printed to stderr if none is specified")
+	keyPath := fs.String("key-file", "", "File with raw key")
+	in := fs.String("in", "", "Input file")
+	out := fs.String("out", "", "Output file")
+	fs.Parse(args)
+	algo, ok := ciphers[*cipherName]
+	if !ok {
+		return fmt.Errorf("unknown cipher: %s", *cipherName)
+	}
+	var key []byte
+	var err error
+	if *keyHex == "" && *keyPath == "" {
+		key = make([]byte, gost3412128.KeySize)
+		if _, err = io.ReadFull(rand.Reader, key); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Key:", hex.EncodeToString(key))
+	} else if key, err = loadSymKey(*keyHex, *keyPath, gost3412128.KeySize); err != nil {
+		return err
+	}
+	r, err := openIn(*in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	o, err := openOut(*out, 0o666)
+	if err != nil {
+		return err
+	}
+	w, err := mgmstream.NewWriter(o, key, algo, 0)
+	if err != nil {
+		o.Close()
+		return err
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		o.Close()
+		return err
+	}
+	if err = w.Close(); err != nil {
+		o.Close()
+		return err
+	}
+	return o.Close()
+}
+
+func cmdDec(args []string) error {
+	fs := flag.NewFlagSet("dec", flag.ExitOnError)
+	keyHex := fs.String("key", "", "Hexadecimal key")
+	keyPath := fs.String("key-file", "", "File with raw key")
+	in := fs.String("in", "", "Input file")
+	out := fs.String("out", "", "Output file")
+	fs.Parse(args)
+	key, err := loadSymKey(*keyHex, *keyPath, gost3412128.KeySize)
+	if err != nil {
+		return err
+	}
+	i, err := openIn(*in)
+	if err != nil {
+		return err
+	}
+	defer i.Close()
+	r, err := mgmstream.NewReader(i, key)
+	if err != nil {
+		return err
+	}
+	w, err := openOut(*out, 0o666)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(w, r); err != nil {
+		w.Close()
+		if errors.Is(err, mgm.InvalidTag) || errors.Is(err, mgmstream.ErrTruncated) {
+			return fmt.Errorf("%w: %v", errInvalid, err)
+		}
+		return err
+	}
+	return w.Close()
+}
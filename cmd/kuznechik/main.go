@@ -15,10 +15,10 @@
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
 // Command-line 34.12-2015 128-bit Block cipher Kuznyechik crypter.
+// Data is encrypted and authenticated with mgmstream format.
 package main
 
 import (
-	"crypto/cipher"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
@@ -28,14 +28,19 @@ import (
 	"os"
 
 	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/mgmstream"
 )
 
 func main() {
 	keyHex := flag.String("key", "", "Key")
+	decrypt := flag.Bool("d", false, "Decrypt")
 	flag.Parse()
 	var key []byte
 	var err error
 	if *keyHex == "" {
+		if *decrypt {
+			panic(errors.New("key is required for decryption"))
+		}
 		key = make([]byte, gost3412128.KeySize)
 		_, err = io.ReadFull(rand.Reader, key)
 		if err != nil {
@@ -51,22 +56,24 @@ func main() {
 			panic(errors.New("provided key has wrong length"))
 		}
 	}
-	ciph := gost3412128.NewCipher(key)
-	iv := make([]byte, gost3412128.BlockSize)
-	stream := cipher.NewCTR(ciph, iv)
-	buf := make([]byte, 128*1<<10)
-	var n int
-	for {
-		n, err = os.Stdin.Read(buf)
-		if err != nil && err != io.EOF {
+	if *decrypt {
+		r, err := mgmstream.NewReader(os.Stdin, key)
+		if err != nil {
 			panic(err)
 		}
-		stream.XORKeyStream(buf[:n], buf[:n])
-		if _, err := os.Stdout.Write(buf[:n]); err != nil {
+		if _, err = io.Copy(os.Stdout, r); err != nil {
 			panic(err)
 		}
-		if err == io.EOF {
-			break
-		}
+		return
+	}
+	w, err := mgmstream.NewWriter(os.Stdout, key, mgmstream.AlgoKuznechik, 0)
+	if err != nil {
+		panic(err)
+	}
+	if _, err = io.Copy(w, os.Stdin); err != nil {
+		panic(err)
+	}
+	if err = w.Close(); err != nil {
+		panic(err)
 	}
 }
@@ -0,0 +1,119 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// OCSP responder over the cmd/ca directory with GOST signatures.
+package main
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/caindex"
+	"github.com/pedroalbanese/gogost/ocsp"
+)
+
+const (
+	PEMKey = "PRIVATE KEY"
+	PEMCer = "CERTIFICATE"
+)
+
+// Read the first certificate and PKCS#8 private key found in PEM file,
+// any of them may be missing.
+func readPEM(path string) (cer *x509.Certificate, prv *gost3410.PrivateKey, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var block *pem.Block
+	for len(data) > 0 {
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch {
+		case block.Type == PEMCer && cer == nil:
+			cer, err = x509.ParseCertificate(block.Bytes)
+		case block.Type == PEMKey && prv == nil:
+			prv, err = gost3410.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func main() {
+	dir := flag.String("dir", ".", "CA directory, as created by cmd/ca")
+	keypair := flag.String("keypair", "", "Path to PEM with delegated responder's certificate and PKCS#8 private key, CA's key by default")
+	validity := flag.Duration("validity", time.Hour, "Responses validity period, 0 to omit nextUpdate")
+	bind := flag.String("bind", "[::1]:2560", "Address to listen on")
+	flag.Parse()
+	log.SetFlags(log.Lshortfile)
+
+	issuer, _, err := readPEM(filepath.Join(*dir, "ca.pem"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if issuer == nil {
+		log.Fatal("no CA's certificate found")
+	}
+	r := ocsp.Responder{Issuer: issuer, Validity: *validity}
+	if *keypair == "" {
+		if _, r.Key, err = readPEM(filepath.Join(*dir, "ca.key")); err != nil {
+			log.Fatal(err)
+		}
+		if r.Key == nil {
+			log.Fatal("no CA's private key found")
+		}
+	} else {
+		if r.Certificate, r.Key, err = readPEM(*keypair); err != nil {
+			log.Fatal(err)
+		}
+		if r.Certificate == nil || r.Key == nil {
+			log.Fatal("no responder's certificate or private key found")
+		}
+	}
+	index := filepath.Join(*dir, "index.txt")
+	r.Lookup = func(serial *big.Int) (ocsp.Response, error) {
+		entries, err := caindex.Read(index)
+		if err != nil {
+			log.Println(err)
+			return ocsp.Response{}, err
+		}
+		e := caindex.Lookup(entries, serial)
+		switch {
+		case e == nil:
+			return ocsp.Response{Status: ocsp.Unknown}, nil
+		case e.Revoked:
+			return ocsp.Response{
+				Status:           ocsp.Revoked,
+				RevokedAt:        e.RevokedAt,
+				RevocationReason: e.Reason,
+			}, nil
+		}
+		return ocsp.Response{Status: ocsp.Good}, nil
+	}
+	log.Println("listening on", *bind)
+	log.Fatal(http.ListenAndServe(*bind, &r))
+}
@@ -18,10 +18,8 @@
 package main
 
 import (
-	"bufio"
 	"crypto/rand"
 	"encoding/hex"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -29,7 +27,6 @@ import (
 	"os"
 
 	"github.com/pedroalbanese/gogost/gost3410"
-	"github.com/pedroalbanese/gogost/gost34112012256"
 )
 
 func main() {
@@ -37,128 +34,110 @@ func main() {
 	keygen := flag.Bool("gen", false, "Generate keypair")
 	sign := flag.Bool("sign", false, "Sign with private key")
 	verify := flag.Bool("verify", false, "Verify with public key")
-	sig := flag.String("sig", "", "Input signature.")
+	sig := flag.String("sig", "", "Input hexadecimal signature")
+	sigPath := flag.String("sig-file", "", "Detached signature file: written with -sign, read with -verify")
+	in := flag.String("in", "", "Input file to sign or verify, stdin by default")
 	key := flag.String("key", "", "Private/Public key, depending on operation.")
+	list := flag.Bool("curves", false, "Print available curve names")
 	flag.Parse()
+	log.SetFlags(0)
 
-	var curve *gost3410.Curve
-	switch *curveName {
-	case "id-tc26-gost-3410-2012-256-paramSetA":
-		curve = gost3410.CurveIdtc26gost34102012256paramSetA()
-	case "id-tc26-gost-3410-2012-256-paramSetB":
-		curve = gost3410.CurveIdtc26gost34102012256paramSetD()
-	case "id-tc26-gost-3410-2012-256-paramSetC":
-		curve = gost3410.CurveIdtc26gost34102012256paramSetC()
-	case "id-tc26-gost-3410-2012-256-paramSetD":
-		curve = gost3410.CurveIdtc26gost34102012256paramSetD()
-	default:
-		panic(errors.New("unknown curve specified"))
+	if *list {
+		for _, name := range gost3410.CurveNames() {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	curve := gost3410.CurveByName(*curveName)
+	if curve == nil {
+		log.Fatalln("unknown curve specified:", *curveName)
 	}
 
 	if *keygen {
-		var err error
-		var prvRaw []byte
-		var pubRaw []byte
-		var prv *gost3410.PrivateKey
-		var pub *gost3410.PublicKey
-		prvRaw = make([]byte, 256/8)
-		_, err = io.ReadFull(rand.Reader, prvRaw)
+		prv, err := gost3410.GenPrivateKey(curve, rand.Reader)
 		if err != nil {
-			panic(err)
+			log.Fatal(err)
 		}
-		fmt.Fprintln(os.Stderr, "Private:", hex.EncodeToString(prvRaw))
-		prv, err = gost3410.NewPrivateKey(curve, prvRaw)
+		pub, err := prv.PublicKey()
 		if err != nil {
-			panic(err)
+			log.Fatal(err)
 		}
+		fmt.Fprintln(os.Stderr, "Private:", hex.EncodeToString(prv.Raw()))
+		fmt.Fprintln(os.Stderr, "Public:", hex.EncodeToString(pub.Raw()))
+		return
+	}
+
+	if !*sign && !*verify {
+		flag.Usage()
+		os.Exit(1)
+	}
 
-		pub, err = prv.PublicKey()
+	var ds *DetachedSignature
+	if *verify && *sigPath != "" {
+		data, err := os.ReadFile(*sigPath)
 		if err != nil {
-			panic(err)
+			log.Fatal(err)
 		}
-		pubRaw = pub.Raw()
-		fmt.Fprintln(os.Stderr, "Public:", hex.EncodeToString(pubRaw))
-		os.Exit(0)
+		if ds, err = ParseDetachedSignature(data); err != nil {
+			log.Fatal(err)
+		}
+		curve = ds.Curve
 	}
 
-	if *sign == true || *verify == true {
+	keyRaw, err := hex.DecodeString(*key)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		scannerWrite := bufio.NewScanner(os.Stdin)
-		if !scannerWrite.Scan() {
-			log.Printf("Failed to read: %v", scannerWrite.Err())
-			return
+	var r io.Reader = os.Stdin
+	if *in != "" {
+		fd, err := os.Open(*in)
+		if err != nil {
+			log.Fatal(err)
 		}
+		defer fd.Close()
+		r = fd
+	}
 
-		var err error
-		var prvRaw []byte
-		var pubRaw []byte
-		var prv *gost3410.PrivateKey
-		var pub *gost3410.PublicKey
-
-		var inputsig []byte
-		inputsig, err = hex.DecodeString(*sig)
+	if *sign {
+		if len(keyRaw) != curve.PointSize() {
+			log.Fatalf("private key must be %d bytes long", curve.PointSize())
+		}
+		prv, err := gost3410.NewPrivateKey(curve, keyRaw)
 		if err != nil {
-			panic(err)
+			log.Fatal(err)
 		}
-
-		if *sign == true {
-			hash := scannerWrite.Bytes()
-			data := []byte(hash)
-			hasher := gost34112012256.New()
-			_, err := hasher.Write(data)
-			if err != nil {
-				log.Fatal(err)
-			}
-			dgst := hasher.Sum(nil)
-			prvRaw, err = hex.DecodeString(*key)
-			if err != nil {
-				panic(err)
-			}
-			if len(prvRaw) != 256/8 {
-				log.Fatal(err, "private key has wrong length")
-			}
-			prv, err = gost3410.NewPrivateKey(curve, prvRaw)
-			if err != nil {
-				panic(err)
-			}
-
-			signature, err := prv.Sign(rand.Reader, dgst, nil)
-			if err != nil {
-				log.Fatal(err)
-			}
-			fmt.Println(hex.EncodeToString(signature))
-			os.Exit(0)
+		ds, err := Sign(rand.Reader, prv, r)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *sigPath == "" {
+			fmt.Println(hex.EncodeToString(ds.Signature))
+			return
 		}
+		if err = os.WriteFile(*sigPath, ds.Marshal(), 0o666); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-		if *verify == true {
-			hash := scannerWrite.Bytes()
-			data := []byte(hash)
-			hasher := gost34112012256.New()
-			_, err := hasher.Write(data)
-			if err != nil {
-				panic(err)
-			}
-			dgst := hasher.Sum(nil)
-			pubRaw, err = hex.DecodeString(*key)
-			if err != nil {
-				panic(err)
-			}
-			if len(pubRaw) != 2*256/8 {
-				log.Fatal(err, "public key has wrong length")
-			}
-			pub, err = gost3410.NewPublicKey(curve, pubRaw)
-			if err != nil {
-				panic(err)
-			}
-			isValid, err := pub.VerifyDigest(dgst, inputsig)
-			if err != nil {
-				panic(err)
-			}
-			if !isValid {
-				panic(errors.New("signature is invalid"))
-			}
-			fmt.Println("Verify correct.")
-			os.Exit(0)
+	if len(keyRaw) != 2*curve.PointSize() {
+		log.Fatalf("public key must be %d bytes long", 2*curve.PointSize())
+	}
+	pub, err := gost3410.NewPublicKey(curve, keyRaw)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ds == nil {
+		sigRaw, err := hex.DecodeString(*sig)
+		if err != nil {
+			log.Fatal(err)
 		}
+		ds = &DetachedSignature{Curve: curve, Signature: sigRaw}
+	}
+	if err = ds.Verify(pub, r); err != nil {
+		log.Fatal(err)
 	}
+	fmt.Println("Verify correct.")
 }
@@ -0,0 +1,182 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2020 Sergey Matveev <stargrave@stargrave.org>
+// Copyright (C) 2020-2021 Pedro Albanese <pedroalbanese@hotmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+)
+
+// PEM type of the detached signature file. Its headers carry the
+// signature algorithm and curve identifiers and the key fingerprint.
+const PEMSignature = "GOST SIGNATURE"
+
+var (
+	ErrFingerprint = errors.New("signature made by another key")
+	ErrInvalid     = errors.New("signature is invalid")
+)
+
+// Detached signature of the whole file. Signature is s||r over the
+// reversed Streebog digest, just like in X.509 and CMS.
+type DetachedSignature struct {
+	Algorithm   asn1.ObjectIdentifier
+	Curve       *gost3410.Curve
+	Fingerprint []byte
+	Signature   []byte
+}
+
+// Streebog-256 of the raw little-endian public key.
+func Fingerprint(pub *gost3410.PublicKey) []byte {
+	h := gost34112012256.New()
+	h.Write(pub.Raw())
+	return h.Sum(nil)
+}
+
+func algorithm(c *gost3410.Curve) asn1.ObjectIdentifier {
+	if c.PointSize() == 64 {
+		return gost3410.OIDTc26SignWithDigestGost34102012512
+	}
+	return gost3410.OIDTc26SignWithDigestGost34102012256
+}
+
+func newHash(c *gost3410.Curve) hash.Hash {
+	if c.PointSize() == 64 {
+		return gost34112012512.New()
+	}
+	return gost34112012256.New()
+}
+
+func digest(c *gost3410.Curve, r io.Reader) ([]byte, error) {
+	h := newHash(c)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+func Sign(rand io.Reader, prv *gost3410.PrivateKey, r io.Reader) (*DetachedSignature, error) {
+	if prv.C.OID() == nil {
+		return nil, errors.New("curve has no identifier")
+	}
+	pub, err := prv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	dgst, err := digest(prv.C, r)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := (&gost3410.PrivateKeyReverseDigest{Prv: prv}).Sign(rand, dgst, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &DetachedSignature{
+		Algorithm:   algorithm(prv.C),
+		Curve:       prv.C,
+		Fingerprint: Fingerprint(pub),
+		Signature:   sig,
+	}, nil
+}
+
+// Verify the signature of the data read from r. Fingerprint, if
+// present, must match the key.
+func (ds *DetachedSignature) Verify(pub *gost3410.PublicKey, r io.Reader) error {
+	if !pub.C.Equal(ds.Curve) {
+		return errors.New("public key curve differs from the signature one")
+	}
+	if ds.Fingerprint != nil && !bytes.Equal(ds.Fingerprint, Fingerprint(pub)) {
+		return ErrFingerprint
+	}
+	if len(ds.Signature) != 2*pub.C.PointSize() {
+		return fmt.Errorf("signature must be %d bytes long", 2*pub.C.PointSize())
+	}
+	dgst, err := digest(pub.C, r)
+	if err != nil {
+		return err
+	}
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(dgst, ds.Signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrInvalid
+	}
+	return nil
+}
+
+func (ds *DetachedSignature) Marshal() []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type: PEMSignature,
+		Headers: map[string]string{
+			"Algorithm":   ds.Algorithm.String(),
+			"Curve":       ds.Curve.OID().String(),
+			"Fingerprint": hex.EncodeToString(ds.Fingerprint),
+		},
+		Bytes: ds.Signature,
+	})
+}
+
+func parseOID(s string) (oid asn1.ObjectIdentifier, err error) {
+	for _, arc := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(arc)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid OID: %q", s)
+		}
+		oid = append(oid, n)
+	}
+	return oid, nil
+}
+
+func ParseDetachedSignature(data []byte) (*DetachedSignature, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != PEMSignature {
+		return nil, errors.New("no " + PEMSignature + " PEM block found")
+	}
+	ds := DetachedSignature{Signature: block.Bytes}
+	oid, err := parseOID(block.Headers["Curve"])
+	if err != nil {
+		return nil, err
+	}
+	if ds.Curve = gost3410.CurveByOID(oid); ds.Curve == nil {
+		return nil, fmt.Errorf("unknown curve: %s", oid)
+	}
+	if ds.Algorithm, err = parseOID(block.Headers["Algorithm"]); err != nil {
+		return nil, err
+	}
+	if !ds.Algorithm.Equal(algorithm(ds.Curve)) {
+		return nil, fmt.Errorf("unsupported algorithm %s for the curve", ds.Algorithm)
+	}
+	if ds.Fingerprint, err = hex.DecodeString(block.Headers["Fingerprint"]); err != nil {
+		return nil, err
+	}
+	if len(ds.Fingerprint) == 0 {
+		ds.Fingerprint = nil
+	}
+	return &ds, nil
+}
@@ -0,0 +1,120 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func signParsed(t *testing.T, prv *gost3410.PrivateKey, data []byte) *DetachedSignature {
+	ds, err := Sign(rand.Reader, prv, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds, err = ParseDetachedSignature(ds.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestSigFile(t *testing.T) {
+	data := []byte("whole file\nwith several lines\n")
+	for _, c := range []*gost3410.Curve{
+		gost3410.CurveIdtc26gost34102012256paramSetA(),
+		gost3410.CurveIdtc26gost34102012256paramSetB(),
+		gost3410.CurveIdtc26gost341012512paramSetA(),
+		gost3410.CurveIdtc26gost341012512paramSetC(),
+	} {
+		prv, err := gost3410.GenPrivateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		ds := signParsed(t, prv, data)
+		if !ds.Algorithm.Equal(algorithm(c)) || !ds.Curve.Equal(c) {
+			t.Fatal("identifiers differ")
+		}
+		if err = ds.Verify(pub, bytes.NewReader(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err = ds.Verify(pub, bytes.NewReader(data[1:])); err != ErrInvalid {
+			t.Fatal("modified data accepted", err)
+		}
+	}
+}
+
+func TestSigFileMismatch(t *testing.T) {
+	data := []byte("data")
+	prv, err := gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012256paramSetA(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := signParsed(t, prv, data)
+
+	other, err := gost3410.GenPrivateKey(prv.C, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, err := other.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ds.Verify(otherPub, bytes.NewReader(data)); err != ErrFingerprint {
+		t.Fatal("another key's fingerprint accepted", err)
+	}
+
+	other, err = gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012256paramSetB(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherPub, err = other.PublicKey(); err != nil {
+		t.Fatal(err)
+	}
+	if err = ds.Verify(otherPub, bytes.NewReader(data)); err == nil {
+		t.Fatal("another curve accepted")
+	}
+
+	pem := string(ds.Marshal())
+	for _, tc := range []struct{ from, to string }{
+		{
+			gost3410.OIDTc26SignWithDigestGost34102012256.String(),
+			gost3410.OIDTc26SignWithDigestGost34102012512.String(),
+		},
+		{
+			gost3410.CurveIdtc26gost34102012256paramSetA().OID().String(),
+			gost3410.CurveIdtc26gost341012512paramSetA().OID().String(),
+		},
+		{
+			gost3410.CurveIdtc26gost34102012256paramSetA().OID().String(),
+			"1.2.3.4",
+		},
+	} {
+		if !strings.Contains(pem, tc.from) {
+			t.Fatal("no header", tc.from)
+		}
+		if _, err = ParseDetachedSignature([]byte(strings.Replace(pem, tc.from, tc.to, 1))); err == nil {
+			t.Fatal("modified header accepted", tc.to)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// RFC 3161 time-stamping authority HTTP server with GOST signatures.
+package main
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/tsp"
+)
+
+const (
+	PEMKey = "PRIVATE KEY"
+	PEMCer = "CERTIFICATE"
+)
+
+func parseOID(s string) (oid asn1.ObjectIdentifier, err error) {
+	for _, arc := range strings.Split(s, ".") {
+		var n int
+		if n, err = strconv.Atoi(arc); err != nil {
+			return
+		}
+		oid = append(oid, n)
+	}
+	return
+}
+
+func main() {
+	keypair := flag.String("keypair", "", "Path to PEM with TSA's certificate and PKCS#8 private key")
+	chain := flag.String("chain", "", "Path to PEM with additional certificates to include")
+	policy := flag.String("policy", "", "TSA policy OID")
+	accuracy := flag.Duration("accuracy", time.Second, "Declared time accuracy, 0 to omit")
+	bind := flag.String("bind", "[::1]:3161", "Address to listen on")
+	flag.Parse()
+	log.SetFlags(log.Lshortfile)
+
+	if *policy == "" {
+		log.Fatal("no policy is set")
+	}
+	policyOID, err := parseOID(*policy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	srv := tsp.Server{Policy: policyOID, Accuracy: *accuracy}
+	data, err := os.ReadFile(*keypair)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var block *pem.Block
+	for len(data) > 0 {
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case PEMCer:
+			srv.Certificate, err = x509.ParseCertificate(block.Bytes)
+		case PEMKey:
+			srv.Key, err = gost3410.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if srv.Certificate == nil || srv.Key == nil {
+		log.Fatal("no certificate or private key found")
+	}
+	if *chain != "" {
+		data, err = os.ReadFile(*chain)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for len(data) > 0 {
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type == PEMCer {
+				srv.Certificates = append(srv.Certificates, block.Bytes)
+			}
+		}
+	}
+	log.Println("listening on", *bind)
+	log.Fatal(http.ListenAndServe(*bind, &srv))
+}
@@ -0,0 +1,189 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Cryptographic Message Syntax (RFC 5652) with GOST algorithms
+// (RFC 4490, RFC 9337 and TC26 recommendations).
+//
+// GOST signatures follow the same conventions as X.509 ones: the
+// digest is reversed before signing (gost3410.PrivateKeyReverseDigest)
+// and signature is s||r, as gost3410.PrivateKey.SignDigest outputs.
+package cms
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/ber"
+)
+
+var (
+	OIDData              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	OIDSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	OIDEnvelopedData     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	OIDAuthEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 23}
+
+	OIDAttrContentType          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	OIDAttrMessageDigest        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	OIDAttrSigningTime          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	OIDAttrSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+)
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type IssuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
+// Explicitly [n] tagged value. encoding/asn1 ignores tagging
+// parameters of RawValue during marshaling, and leaves the tag in it
+// during unmarshaling, so its Bytes hold the tagged element.
+func explicit(tag int, der []byte) asn1.RawValue {
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        tag,
+		IsCompound: true,
+		Bytes:      der,
+	}
+}
+
+func unmarshal(der []byte, v interface{}, what string) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("gogost/cms: invalid %s: %w", what, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/cms: trailing data after %s", what)
+	}
+	return nil
+}
+
+// Marshal ContentInfo with the DER encoded content.
+func marshalContentInfo(contentType asn1.ObjectIdentifier, content []byte) ([]byte, error) {
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content:     explicit(0, content),
+	})
+}
+
+// Parse BER or DER ContentInfo. Its type and DER encoded content are
+// returned.
+func parseContentInfoAny(data []byte) (asn1.ObjectIdentifier, []byte, error) {
+	der, err := ber.ToDER(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ci contentInfo
+	if err = unmarshal(der, &ci, "ContentInfo"); err != nil {
+		return nil, nil, err
+	}
+	return ci.ContentType, ci.Content.Bytes, nil
+}
+
+// Parse BER or DER ContentInfo, checking its type. DER encoded content
+// is returned.
+func parseContentInfo(data []byte, contentType asn1.ObjectIdentifier) ([]byte, error) {
+	typ, der, err := parseContentInfoAny(data)
+	if err != nil {
+		return nil, err
+	}
+	if !typ.Equal(contentType) {
+		return nil, fmt.Errorf("gogost/cms: unexpected content type %s", typ)
+	}
+	return der, nil
+}
+
+// Attribute with the single DER encoded value.
+func newAttribute(typ asn1.ObjectIdentifier, value []byte) Attribute {
+	return Attribute{Type: typ, Values: asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      value,
+	}}
+}
+
+// Get the single value of the attribute of the given type. Nil is
+// returned if there is no such attribute.
+func attributeValue(attrs []Attribute, typ asn1.ObjectIdentifier) ([]byte, error) {
+	var found []byte
+	for _, attr := range attrs {
+		if !attr.Type.Equal(typ) {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("gogost/cms: duplicate %s attribute", typ)
+		}
+		var value asn1.RawValue
+		rest, err := asn1.Unmarshal(attr.Values.Bytes, &value)
+		if err != nil {
+			return nil, fmt.Errorf("gogost/cms: invalid %s attribute: %w", typ, err)
+		}
+		if len(rest) > 0 {
+			return nil, fmt.Errorf("gogost/cms: multiple %s attribute values", typ)
+		}
+		found = value.FullBytes
+	}
+	return found, nil
+}
+
+// Get the GOST public key and its algorithm from the certificate.
+func certPublicKey(cert *x509.Certificate) (*gost3410.PublicKey, asn1.ObjectIdentifier, error) {
+	var spki struct {
+		Algo      pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if err := unmarshal(cert.RawSubjectPublicKeyInfo, &spki, "SubjectPublicKeyInfo"); err != nil {
+		return nil, nil, err
+	}
+	pub, err := gost3410.ParsePublicKey(spki.Algo, spki.PublicKey.RightAlign())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, spki.Algo.Algorithm, nil
+}
+
+func issuerAndSerial(cert *x509.Certificate) IssuerAndSerialNumber {
+	return IssuerAndSerialNumber{
+		Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+		SerialNumber: cert.SerialNumber,
+	}
+}
+
+// Find the certificate by issuer and serial number.
+func findCert(certs []*x509.Certificate, ias IssuerAndSerialNumber) *x509.Certificate {
+	for _, cert := range certs {
+		if cert.SerialNumber.Cmp(ias.SerialNumber) == 0 &&
+			string(cert.RawIssuer) == string(ias.Issuer.FullBytes) {
+			return cert
+		}
+	}
+	return nil
+}
+
+var errNoCert = errors.New("gogost/cms: signer's certificate not found")
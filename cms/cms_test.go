@@ -0,0 +1,41 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cms
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/testpki"
+)
+
+// Create self-signed certificate with the new key on the curve.
+func newTestCert(t *testing.T, c *gost3410.Curve, legacy bool) (*x509.Certificate, *gost3410.PrivateKey) {
+	prv := testpki.Key(t, c)
+	tmpl := x509.Certificate{Subject: pkix.Name{CommonName: c.Name}}
+	if legacy {
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tmpl.RawSubjectPublicKeyInfo, err = gost3410.MarshalPKIXPublicKey2001(pub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return testpki.Issue(t, &tmpl, prv, nil, nil), prv
+}
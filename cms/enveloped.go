@@ -0,0 +1,634 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cms
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pedroalbanese/gogost/acpkm"
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+	"github.com/pedroalbanese/gogost/internal/ber"
+	"github.com/pedroalbanese/gogost/mgm"
+)
+
+var (
+	OIDMagmaCTRACPKM         = acpkm.OIDMagmaCTRACPKM
+	OIDMagmaCTRACPKMOMAC     = acpkm.OIDMagmaCTRACPKMOMAC
+	OIDMagmaMGM              = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 1, 3}
+	OIDKuznechikCTRACPKM     = acpkm.OIDKuznechikCTRACPKM
+	OIDKuznechikCTRACPKMOMAC = acpkm.OIDKuznechikCTRACPKMOMAC
+	OIDKuznechikMGM          = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 5, 2, 3}
+
+	OIDMagmaKExp15     = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 7, 1, 1}
+	OIDKuznechikKExp15 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 7, 2, 1}
+
+	// Unprotected attribute with CTR-ACPKM-OMAC content MAC.
+	OIDAttrCMSMAC = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 0, 6, 1, 1}
+
+	ErrNoRecipient = errors.New("gogost/cms: recipient not found")
+	ErrMAC         = errors.New("gogost/cms: content MAC verification failed")
+)
+
+const (
+	cekSize     = 32
+	transUKMLen = 8
+	kegUKMLen   = 32
+)
+
+// Content cipher: CTR-ACPKM(-OMAC) scheme, or MGM with the same block
+// cipher, and KExp15 used with it.
+type contentCipher struct {
+	acpkm.Scheme
+	mgm    bool
+	kexp15 asn1.ObjectIdentifier
+}
+
+func acpkmScheme(oid asn1.ObjectIdentifier) acpkm.Scheme {
+	return *acpkm.SchemeByOID(oid)
+}
+
+var contentCiphers = []contentCipher{
+	{acpkmScheme(OIDKuznechikCTRACPKM), false, OIDKuznechikKExp15},
+	{acpkmScheme(OIDKuznechikCTRACPKMOMAC), false, OIDKuznechikKExp15},
+	{acpkm.Scheme{
+		OID:       OIDKuznechikMGM,
+		NewCipher: acpkm.NewKuznechik,
+		BlockSize: gost3412128.BlockSize,
+	}, true, OIDKuznechikKExp15},
+	{acpkmScheme(OIDMagmaCTRACPKM), false, OIDMagmaKExp15},
+	{acpkmScheme(OIDMagmaCTRACPKMOMAC), false, OIDMagmaKExp15},
+	{acpkm.Scheme{
+		OID:       OIDMagmaMGM,
+		NewCipher: acpkm.NewMagma,
+		BlockSize: gost341264.BlockSize,
+	}, true, OIDMagmaKExp15},
+}
+
+func findContentCipher(oid asn1.ObjectIdentifier) *contentCipher {
+	for i := range contentCiphers {
+		if contentCiphers[i].OID.Equal(oid) {
+			return &contentCiphers[i]
+		}
+	}
+	return nil
+}
+
+func findKExp15Cipher(oid asn1.ObjectIdentifier) *contentCipher {
+	for i := range contentCiphers {
+		if contentCiphers[i].kexp15.Equal(oid) {
+			return &contentCiphers[i]
+		}
+	}
+	return nil
+}
+
+type envelopedData struct {
+	Version              int
+	OriginatorInfo       asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos       []asn1.RawValue `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+	UnprotectedAttrs     []Attribute `asn1:"optional,tag:1,set"`
+}
+
+type authEnvelopedData struct {
+	Version                  int
+	OriginatorInfo           asn1.RawValue   `asn1:"optional,tag:0"`
+	RecipientInfos           []asn1.RawValue `asn1:"set"`
+	AuthEncryptedContentInfo encryptedContentInfo
+	AuthAttrs                []Attribute `asn1:"optional,tag:1,set"`
+	MAC                      []byte
+	UnauthAttrs              []Attribute `asn1:"optional,tag:2,set"`
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    IssuerAndSerialNumber
+	KeyEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedKey           []byte
+}
+
+// GostR3410-KeyTransport (RFC 4490).
+type keyTransport struct {
+	SessionEncryptedKey encryptedKey
+	TransportParameters transportParameters `asn1:"optional,tag:0"`
+}
+
+// Gost28147-89-EncryptedKey.
+type encryptedKey struct {
+	EncryptedKey []byte
+	MaskKey      []byte `asn1:"optional,tag:0"`
+	MacKey       []byte
+}
+
+// GostR3410-TransportParameters.
+type transportParameters struct {
+	EncryptionParamSet asn1.ObjectIdentifier
+	EphemeralPublicKey asn1.RawValue `asn1:"optional,tag:0"`
+	UKM                []byte
+}
+
+// Gost28147-89-Parameters.
+type gost28147Params struct {
+	IV                 []byte
+	EncryptionParamSet asn1.ObjectIdentifier
+}
+
+type mgmParams struct {
+	Nonce  []byte
+	ICVLen int
+}
+
+type EncryptOpts struct {
+	// Content encryption algorithm: one of Kuznechik/Magma CTR-ACPKM,
+	// CTR-ACPKM-OMAC, MGM, or gost28147.OIDGost2814789.
+	// OIDKuznechikCTRACPKMOMAC by default. MGM produces
+	// AuthEnvelopedData, others EnvelopedData.
+	Cipher asn1.ObjectIdentifier
+
+	// Sbox for GOST 28147-89 CFB. gost28147.SboxDefault by default.
+	Sbox *gost28147.Sbox
+}
+
+func marshalParams(oid asn1.ObjectIdentifier, params interface{}) (pkix.AlgorithmIdentifier, error) {
+	der, err := asn1.Marshal(params)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.RawValue{FullBytes: der}}, nil
+}
+
+func unmarshalParams(ai pkix.AlgorithmIdentifier, params interface{}) error {
+	return unmarshal(ai.Parameters.FullBytes, params, ai.Algorithm.String()+" parameters")
+}
+
+// Encrypt the content with the random content encryption key.
+// Algorithm identifier, ciphertext and MAC (if any) are returned.
+func encryptContent(
+	rand io.Reader,
+	cek, content []byte,
+	opts *EncryptOpts,
+) (ai pkix.AlgorithmIdentifier, ct, mac []byte, err error) {
+	if opts.Cipher.Equal(gost28147.OIDGost2814789) {
+		sbox := opts.Sbox
+		if sbox == nil {
+			sbox = gost28147.SboxDefault
+		}
+		paramSet := sbox.OID()
+		if paramSet == nil {
+			err = errors.New("gogost/cms: Sbox has no identifier")
+			return
+		}
+		iv := make([]byte, gost28147.BlockSize)
+		if _, err = io.ReadFull(rand, iv); err != nil {
+			return
+		}
+		if ai, err = marshalParams(opts.Cipher, gost28147Params{iv, paramSet}); err != nil {
+			return
+		}
+		ct = make([]byte, len(content))
+		crypt28147(cek, sbox, iv, ct, content, true)
+		return
+	}
+	cc := findContentCipher(opts.Cipher)
+	if cc == nil {
+		err = fmt.Errorf("gogost/cms: unsupported cipher %s", opts.Cipher)
+		return
+	}
+	if cc.mgm {
+		if len(content) == 0 {
+			err = errors.New("gogost/cms: empty content can not be encrypted with MGM")
+			return
+		}
+		nonce := make([]byte, cc.BlockSize)
+		if _, err = io.ReadFull(rand, nonce); err != nil {
+			return
+		}
+		nonce[0] &= 0x7F
+		if ai, err = marshalParams(cc.OID, mgmParams{nonce, cc.BlockSize}); err != nil {
+			return
+		}
+		var aead cipher.AEAD
+		if aead, err = mgm.NewMGM(cc.NewCipher(cek), cc.BlockSize); err != nil {
+			return
+		}
+		sealed := aead.Seal(nil, nonce, content, nil)
+		ct, mac = sealed[:len(content)], sealed[len(content):]
+		return
+	}
+	ukm := make([]byte, cc.UKMSize())
+	if _, err = io.ReadFull(rand, ukm); err != nil {
+		return
+	}
+	if ai, err = marshalParams(cc.OID, acpkm.Params{UKM: ukm}); err != nil {
+		return
+	}
+	kEnc := cek
+	if cc.OMAC {
+		var kMac []byte
+		kEnc, kMac = acpkm.OMACKeys(cek, ukm[cc.BlockSize/2:])
+		mac = cc.MAC(kMac, content)
+	}
+	ct = make([]byte, len(content))
+	cc.Crypt(kEnc, ukm[:cc.BlockSize/2], ct, content)
+	return
+}
+
+func crypt28147(key []byte, sbox *gost28147.Sbox, iv, dst, src []byte, encrypt bool) {
+	_, meshing := gost28147.SboxByOID(sbox.OID())
+	c := gost28147.NewCipher(key, sbox)
+	var s cipher.Stream
+	switch {
+	case encrypt && meshing:
+		s = c.NewCFBEncrypterMeshing(iv)
+	case encrypt:
+		s = c.NewCFBEncrypter(iv)
+	case meshing:
+		s = c.NewCFBDecrypterMeshing(iv)
+	default:
+		s = c.NewCFBDecrypter(iv)
+	}
+	s.XORKeyStream(dst, src)
+}
+
+func decryptContent(cek []byte, ai pkix.AlgorithmIdentifier, ct, mac []byte) ([]byte, error) {
+	if ai.Algorithm.Equal(gost28147.OIDGost2814789) {
+		var params gost28147Params
+		if err := unmarshalParams(ai, &params); err != nil {
+			return nil, err
+		}
+		sbox, _ := gost28147.SboxByOID(params.EncryptionParamSet)
+		if sbox == nil {
+			return nil, fmt.Errorf("gogost/cms: unknown parameter set %s", params.EncryptionParamSet)
+		}
+		if len(params.IV) != gost28147.BlockSize {
+			return nil, errors.New("gogost/cms: invalid IV length")
+		}
+		pt := make([]byte, len(ct))
+		crypt28147(cek, sbox, params.IV, pt, ct, false)
+		return pt, nil
+	}
+	cc := findContentCipher(ai.Algorithm)
+	if cc == nil {
+		return nil, fmt.Errorf("gogost/cms: unsupported cipher %s", ai.Algorithm)
+	}
+	if cc.mgm {
+		var params mgmParams
+		if err := unmarshalParams(ai, &params); err != nil {
+			return nil, err
+		}
+		if len(params.Nonce) != cc.BlockSize || params.Nonce[0]&0x80 > 0 {
+			return nil, errors.New("gogost/cms: invalid MGM nonce")
+		}
+		if params.ICVLen != len(mac) {
+			return nil, errors.New("gogost/cms: invalid MGM tag length")
+		}
+		aead, err := mgm.NewMGM(cc.NewCipher(cek), len(mac))
+		if err != nil {
+			return nil, err
+		}
+		pt, err := aead.Open(nil, params.Nonce, append(append([]byte{}, ct...), mac...), nil)
+		if err != nil {
+			return nil, ErrMAC
+		}
+		return pt, nil
+	}
+	var params acpkm.Params
+	if err := unmarshalParams(ai, &params); err != nil {
+		return nil, err
+	}
+	if len(params.UKM) != cc.UKMSize() {
+		return nil, errors.New("gogost/cms: invalid UKM length")
+	}
+	kEnc := cek
+	var kMac []byte
+	if cc.OMAC {
+		kEnc, kMac = acpkm.OMACKeys(cek, params.UKM[cc.BlockSize/2:])
+	}
+	pt := make([]byte, len(ct))
+	cc.Crypt(kEnc, params.UKM[:cc.BlockSize/2], pt, ct)
+	if cc.OMAC && !hmac.Equal(cc.MAC(kMac, pt), mac) {
+		return nil, ErrMAC
+	}
+	return pt, nil
+}
+
+// Encode SubjectPublicKeyInfo as implicitly [0] tagged value.
+func implicitSPKI(spki []byte) (asn1.RawValue, error) {
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(spki, &raw); err != nil {
+		return raw, err
+	}
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        0,
+		IsCompound: true,
+		Bytes:      raw.Bytes,
+	}, nil
+}
+
+// Create key transport recipient info for the certificate. KEG with
+// KExp15 is used for 34.10-2012 keys if content cipher has it, VKO with
+// CryptoPro key wrap otherwise.
+func newRecipientInfo(
+	rand io.Reader,
+	cert *x509.Certificate,
+	cek []byte,
+	kexp15 *contentCipher,
+) ([]byte, error) {
+	pub, keyAlgo, err := certPublicKey(cert)
+	if err != nil {
+		return nil, err
+	}
+	legacy := keyAlgo.Equal(gost3410.OIDGostR34102001)
+	eph, err := gost3410.GenPrivateKey(pub.C, rand)
+	if err != nil {
+		return nil, err
+	}
+	ephPub, err := eph.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	var spki []byte
+	if legacy {
+		spki, err = gost3410.MarshalPKIXPublicKey2001(ephPub)
+	} else {
+		spki, err = gost3410.MarshalPKIXPublicKey(ephPub)
+	}
+	if err != nil {
+		return nil, err
+	}
+	params := transportParameters{}
+	if params.EphemeralPublicKey, err = implicitSPKI(spki); err != nil {
+		return nil, err
+	}
+	var ek encryptedKey
+	if !legacy && kexp15 != nil {
+		params.EncryptionParamSet = kexp15.kexp15
+		params.UKM = make([]byte, kegUKMLen)
+		if _, err = io.ReadFull(rand, params.UKM); err != nil {
+			return nil, err
+		}
+		keys, err := eph.KEG(pub, params.UKM)
+		if err != nil {
+			return nil, err
+		}
+		iv := params.UKM[gost3410.KEGUKMSize : gost3410.KEGUKMSize+kexp15.BlockSize/2]
+		exported := acpkm.KExp15(kexp15.NewCipher, keys[:cekSize], keys[cekSize:], iv, cek)
+		ek.EncryptedKey, ek.MacKey = exported[:cekSize], exported[cekSize:]
+	} else {
+		params.EncryptionParamSet = gost28147.SboxIdGost2814789CryptoProAParamSet.OID()
+		params.UKM = make([]byte, transUKMLen)
+		if _, err = io.ReadFull(rand, params.UKM); err != nil {
+			return nil, err
+		}
+		kek, err := transportKEK(eph, pub, params.UKM, legacy)
+		if err != nil {
+			return nil, err
+		}
+		wrapped := gost28147.WrapGost(params.UKM, gost28147.DiversifyCryptoPro(kek, params.UKM), cek)
+		ek.EncryptedKey = wrapped[transUKMLen : transUKMLen+cekSize]
+		ek.MacKey = wrapped[transUKMLen+cekSize:]
+	}
+	encrypted, err := asn1.Marshal(keyTransport{ek, params})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(keyTransRecipientInfo{
+		RID:                    issuerAndSerial(cert),
+		KeyEncryptionAlgorithm: pkix.AlgorithmIdentifier{Algorithm: keyAlgo},
+		EncryptedKey:           encrypted,
+	})
+}
+
+func transportKEK(prv *gost3410.PrivateKey, pub *gost3410.PublicKey, ukm []byte, legacy bool) ([]byte, error) {
+	if legacy {
+		return prv.KEK2001(pub, gost3410.NewUKM(ukm))
+	}
+	return prv.KEK2012256(pub, gost3410.NewUKM(ukm))
+}
+
+// Encrypt the content to the recipients' certificates, producing
+// EnvelopedData or AuthEnvelopedData ContentInfo.
+func Encrypt(
+	rand io.Reader,
+	content []byte,
+	recipients []*x509.Certificate,
+	opts *EncryptOpts,
+) ([]byte, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("gogost/cms: no recipients")
+	}
+	o := EncryptOpts{Cipher: OIDKuznechikCTRACPKMOMAC}
+	if opts != nil {
+		o = *opts
+		if o.Cipher == nil {
+			o.Cipher = OIDKuznechikCTRACPKMOMAC
+		}
+	}
+	cek := make([]byte, cekSize)
+	if _, err := io.ReadFull(rand, cek); err != nil {
+		return nil, err
+	}
+	ai, ct, mac, err := encryptContent(rand, cek, content, &o)
+	if err != nil {
+		return nil, err
+	}
+	cc := findContentCipher(o.Cipher)
+	ris := make([]asn1.RawValue, 0, len(recipients))
+	for _, cert := range recipients {
+		ri, err := newRecipientInfo(rand, cert, cek, cc)
+		if err != nil {
+			return nil, err
+		}
+		ris = append(ris, asn1.RawValue{FullBytes: ri})
+	}
+	eci := encryptedContentInfo{
+		ContentType:                OIDData,
+		ContentEncryptionAlgorithm: ai,
+		EncryptedContent: asn1.RawValue{
+			Class: asn1.ClassContextSpecific,
+			Tag:   0,
+			Bytes: ct,
+		},
+	}
+	var der []byte
+	if cc != nil && cc.mgm {
+		if der, err = asn1.Marshal(authEnvelopedData{
+			RecipientInfos:           ris,
+			AuthEncryptedContentInfo: eci,
+			MAC:                      mac,
+		}); err != nil {
+			return nil, err
+		}
+		return marshalContentInfo(OIDAuthEnvelopedData, der)
+	}
+	ed := envelopedData{RecipientInfos: ris, EncryptedContentInfo: eci}
+	if mac != nil {
+		value, err := asn1.Marshal(mac)
+		if err != nil {
+			return nil, err
+		}
+		ed.Version = 2
+		ed.UnprotectedAttrs = []Attribute{newAttribute(OIDAttrCMSMAC, value)}
+	}
+	if der, err = asn1.Marshal(ed); err != nil {
+		return nil, err
+	}
+	return marshalContentInfo(OIDEnvelopedData, der)
+}
+
+// Find recipient info for the certificate and decrypt the content
+// encryption key with the private key.
+func decryptCEK(ris []asn1.RawValue, cert *x509.Certificate, prv *gost3410.PrivateKey) ([]byte, error) {
+	var ktri keyTransRecipientInfo
+	found := false
+	for _, ri := range ris {
+		if ri.Class != asn1.ClassUniversal || ri.Tag != asn1.TagSequence {
+			continue // other recipient info types
+		}
+		if err := unmarshal(ri.FullBytes, &ktri, "KeyTransRecipientInfo"); err != nil {
+			return nil, err
+		}
+		if findCert([]*x509.Certificate{cert}, ktri.RID) != nil {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrNoRecipient
+	}
+	var kt keyTransport
+	if err := unmarshal(ktri.EncryptedKey, &kt, "GostR3410-KeyTransport"); err != nil {
+		return nil, err
+	}
+	params := kt.TransportParameters
+	if len(params.EphemeralPublicKey.Bytes) == 0 {
+		return nil, errors.New("gogost/cms: no ephemeral public key")
+	}
+	spki, err := asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSequence,
+		IsCompound: true,
+		Bytes:      params.EphemeralPublicKey.Bytes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	eph, err := gost3410.ParsePKIXPublicKey(spki)
+	if err != nil {
+		return nil, err
+	}
+	if !eph.C.Equal(prv.C) {
+		return nil, errors.New("gogost/cms: ephemeral key curve differs")
+	}
+	ek := kt.SessionEncryptedKey
+	if kexp15 := findKExp15Cipher(params.EncryptionParamSet); kexp15 != nil {
+		if len(params.UKM) != kegUKMLen {
+			return nil, errors.New("gogost/cms: invalid UKM length")
+		}
+		keys, err := prv.KEG(eph, params.UKM)
+		if err != nil {
+			return nil, err
+		}
+		iv := params.UKM[gost3410.KEGUKMSize : gost3410.KEGUKMSize+kexp15.BlockSize/2]
+		return acpkm.KImp15(
+			kexp15.NewCipher, keys[:cekSize], keys[cekSize:], iv,
+			append(append([]byte{}, ek.EncryptedKey...), ek.MacKey...),
+		)
+	}
+	if len(params.UKM) != transUKMLen || len(ek.EncryptedKey) != cekSize || len(ek.MacKey) != 4 {
+		return nil, errors.New("gogost/cms: invalid key transport sizes")
+	}
+	legacy := ktri.KeyEncryptionAlgorithm.Algorithm.Equal(gost3410.OIDGostR34102001)
+	kek, err := transportKEK(prv, eph, params.UKM, legacy)
+	if err != nil {
+		return nil, err
+	}
+	cek := gost28147.UnwrapCryptoPro(kek, append(append(
+		append([]byte{}, params.UKM...), ek.EncryptedKey...), ek.MacKey...,
+	))
+	if cek == nil {
+		return nil, errors.New("gogost/cms: key unwrap failed")
+	}
+	return cek, nil
+}
+
+// Decrypt EnvelopedData or AuthEnvelopedData ContentInfo with the
+// recipient's certificate and private key.
+func Decrypt(data []byte, cert *x509.Certificate, prv *gost3410.PrivateKey) ([]byte, error) {
+	typ, der, err := parseContentInfoAny(data)
+	if err != nil {
+		return nil, err
+	}
+	var ris []asn1.RawValue
+	var eci encryptedContentInfo
+	var mac []byte
+	switch {
+	case typ.Equal(OIDEnvelopedData):
+		var ed envelopedData
+		if err = unmarshal(der, &ed, "EnvelopedData"); err != nil {
+			return nil, err
+		}
+		ris, eci = ed.RecipientInfos, ed.EncryptedContentInfo
+		if mac, err = attributeValue(ed.UnprotectedAttrs, OIDAttrCMSMAC); err != nil {
+			return nil, err
+		}
+		if mac != nil {
+			if err = unmarshal(mac, &mac, "MAC attribute"); err != nil {
+				return nil, err
+			}
+		}
+	case typ.Equal(OIDAuthEnvelopedData):
+		var aed authEnvelopedData
+		if err = unmarshal(der, &aed, "AuthEnvelopedData"); err != nil {
+			return nil, err
+		}
+		if len(aed.AuthAttrs) > 0 {
+			return nil, errors.New("gogost/cms: authenticated attributes are not supported")
+		}
+		ris, eci, mac = aed.RecipientInfos, aed.AuthEncryptedContentInfo, aed.MAC
+	default:
+		return nil, fmt.Errorf("gogost/cms: unexpected content type %s", typ)
+	}
+	cek, err := decryptCEK(ris, cert, prv)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := ber.Octets(eci.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+	if cc := findContentCipher(eci.ContentEncryptionAlgorithm.Algorithm); cc != nil && (cc.OMAC || cc.mgm) && mac == nil {
+		return nil, errors.New("gogost/cms: content MAC is missing")
+	}
+	return decryptContent(cek, eci.ContentEncryptionAlgorithm, ct, mac)
+}
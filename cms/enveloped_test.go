@@ -0,0 +1,89 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cms
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	content := make([]byte, 5000)
+	if _, err := rand.Read(content); err != nil {
+		t.Fatal(err)
+	}
+	var certs []*x509.Certificate
+	var prvs []*gost3410.PrivateKey
+	for _, tc := range testCurves {
+		cert, prv := newTestCert(t, tc.c(), tc.legacy)
+		certs = append(certs, cert)
+		prvs = append(prvs, prv)
+	}
+	for _, cipher := range []asn1.ObjectIdentifier{
+		gost28147.OIDGost2814789,
+		OIDKuznechikCTRACPKM,
+		OIDKuznechikCTRACPKMOMAC,
+		OIDKuznechikMGM,
+		OIDMagmaCTRACPKM,
+		OIDMagmaCTRACPKMOMAC,
+		OIDMagmaMGM,
+	} {
+		data, err := Encrypt(rand.Reader, content, certs, &EncryptOpts{Cipher: cipher})
+		if err != nil {
+			t.Fatal(cipher, err)
+		}
+		for i, cert := range certs {
+			got, err := Decrypt(data, cert, prvs[i])
+			if err != nil {
+				t.Fatal(cipher, err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Fatal(cipher, "content differs")
+			}
+		}
+	}
+}
+
+func TestDecryptForeign(t *testing.T) {
+	cert, _ := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	other, otherPrv := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	data, err := Encrypt(rand.Reader, []byte("content"), []*x509.Certificate{cert}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Decrypt(data, other, otherPrv); err != ErrNoRecipient {
+		t.Fatal("foreign recipient accepted")
+	}
+	if _, err = Decrypt(data, cert, otherPrv); err == nil {
+		t.Fatal("wrong private key accepted")
+	}
+}
+
+func TestEncryptMGMEmpty(t *testing.T) {
+	cert, _ := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	if _, err := Encrypt(
+		rand.Reader, nil, []*x509.Certificate{cert},
+		&EncryptOpts{Cipher: OIDMagmaMGM},
+	); err == nil {
+		t.Fatal("empty MGM content accepted")
+	}
+}
@@ -0,0 +1,461 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cms
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+var oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                IssuerAndSerialNumber
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  issuerSerial `asn1:"optional"`
+}
+
+type issuerSerial struct {
+	Issuer       []asn1.RawValue
+	SerialNumber asn1.RawValue
+}
+
+type signingCertificateV2 struct {
+	Certs []essCertIDv2
+}
+
+type SignOpts struct {
+	// Detached signature does not include the content.
+	Detached bool
+
+	// Encapsulated content type, OIDData by default.
+	ContentType asn1.ObjectIdentifier
+
+	// Signing time attribute value, current time by default.
+	SigningTime time.Time
+
+	// Additional DER encoded certificates to include, like the
+	// intermediate CA ones.
+	Certificates [][]byte
+
+	// Do not include any certificates at all.
+	NoCertificates bool
+}
+
+func newHash(digest asn1.ObjectIdentifier) (hash.Hash, error) {
+	if digest.Equal(oidSHA256) {
+		return sha256.New(), nil
+	}
+	if h := gost3410.NewHash(digest); h != nil {
+		return h, nil
+	}
+	return nil, fmt.Errorf("gogost/cms: unsupported digest algorithm %s", digest)
+}
+
+func digest(digestAlgo asn1.ObjectIdentifier, data []byte) ([]byte, error) {
+	h, err := newHash(digestAlgo)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// Encode attributes as DER SET OF, sorting them.
+func marshalAttributes(attrs []Attribute) ([]byte, error) {
+	encoded := make([][]byte, 0, len(attrs))
+	for _, attr := range attrs {
+		der, err := asn1.Marshal(attr)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, der)
+	}
+	sort.Slice(encoded, func(i, j int) bool {
+		return bytes.Compare(encoded[i], encoded[j]) < 0
+	})
+	return bytes.Join(encoded, nil), nil
+}
+
+// Universal SET tagged encoding of signed attributes, that is signed.
+func signedAttrsSet(raw asn1.RawValue) ([]byte, error) {
+	return asn1.Marshal(asn1.RawValue{
+		Class:      asn1.ClassUniversal,
+		Tag:        asn1.TagSet,
+		IsCompound: true,
+		Bytes:      raw.Bytes,
+	})
+}
+
+// Sign the content with the private key of the certificate, producing
+// SignedData ContentInfo. Signed attributes include contentType,
+// messageDigest, signingTime and signingCertificateV2.
+func Sign(
+	rand io.Reader,
+	content []byte,
+	cert *x509.Certificate,
+	prv *gost3410.PrivateKey,
+	opts *SignOpts,
+) ([]byte, error) {
+	if opts == nil {
+		opts = &SignOpts{}
+	}
+	_, keyAlgo, err := certPublicKey(cert)
+	if err != nil {
+		return nil, err
+	}
+	digestOID, _ := gost3410.SignatureAlgorithms(keyAlgo)
+	if digestOID == nil {
+		return nil, fmt.Errorf("gogost/cms: unsupported key algorithm %s", keyAlgo)
+	}
+	digestAlgo := pkix.AlgorithmIdentifier{Algorithm: digestOID}
+	contentType := opts.ContentType
+	if contentType == nil {
+		contentType = OIDData
+	}
+	signingTime := opts.SigningTime
+	if signingTime.IsZero() {
+		signingTime = time.Now()
+	}
+
+	contentDigest, err := digest(digestOID, content)
+	if err != nil {
+		return nil, err
+	}
+	certDigest, err := digest(digestOID, cert.Raw)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := asn1.Marshal(cert.SerialNumber)
+	if err != nil {
+		return nil, err
+	}
+	var attrs []Attribute
+	for _, a := range []struct {
+		typ   asn1.ObjectIdentifier
+		value interface{}
+	}{
+		{OIDAttrContentType, contentType},
+		{OIDAttrMessageDigest, contentDigest},
+		{OIDAttrSigningTime, signingTime.UTC()},
+		{OIDAttrSigningCertificateV2, signingCertificateV2{Certs: []essCertIDv2{{
+			HashAlgorithm: digestAlgo,
+			CertHash:      certDigest,
+			IssuerSerial: issuerSerial{
+				Issuer:       []asn1.RawValue{explicit(4, cert.RawIssuer)},
+				SerialNumber: asn1.RawValue{FullBytes: serial},
+			},
+		}}}},
+	} {
+		der, err := asn1.Marshal(a.value)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, newAttribute(a.typ, der))
+	}
+	attrsDER, err := marshalAttributes(attrs)
+	if err != nil {
+		return nil, err
+	}
+	si := signerInfo{
+		Version:         1,
+		SID:             issuerAndSerial(cert),
+		DigestAlgorithm: digestAlgo,
+		SignedAttrs: asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      attrsDER,
+		},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: keyAlgo},
+	}
+	toBeSigned, err := signedAttrsSet(si.SignedAttrs)
+	if err != nil {
+		return nil, err
+	}
+	dgst, err := digest(digestOID, toBeSigned)
+	if err != nil {
+		return nil, err
+	}
+	si.Signature, err = (&gost3410.PrivateKeyReverseDigest{Prv: prv}).Sign(rand, dgst, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{digestAlgo},
+		EncapContentInfo: encapContentInfo{EContentType: contentType},
+		SignerInfos:      []signerInfo{si},
+	}
+	if !opts.NoCertificates {
+		sd.Certificates = asn1.RawValue{
+			Class:      asn1.ClassContextSpecific,
+			Tag:        0,
+			IsCompound: true,
+			Bytes:      bytes.Join(append([][]byte{cert.Raw}, opts.Certificates...), nil),
+		}
+	}
+	if !opts.Detached {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		sd.EncapContentInfo.EContent = explicit(0, octets)
+	}
+	if !contentType.Equal(OIDData) {
+		sd.Version = 3
+	}
+	der, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return marshalContentInfo(OIDSignedData, der)
+}
+
+// Parsed and verified SignedData.
+type Signed struct {
+	ContentType asn1.ObjectIdentifier
+	Content     []byte
+
+	// All included certificates.
+	Certificates []*x509.Certificate
+
+	// Certificates of the signers, in SignerInfos order.
+	Signers []*x509.Certificate
+
+	// Signing times of the signers, zero if absent.
+	SigningTimes []time.Time
+}
+
+// Verify SignedData ContentInfo signatures. Detached content must be
+// provided if it is not encapsulated. Signers' certificates are looked
+// up among included and additionally provided ones. Certificates
+// themselves are not verified.
+func Verify(data, detached []byte, certs []*x509.Certificate) (*Signed, error) {
+	der, err := parseContentInfo(data, OIDSignedData)
+	if err != nil {
+		return nil, err
+	}
+	var sd signedData
+	if err = unmarshal(der, &sd, "SignedData"); err != nil {
+		return nil, err
+	}
+	signed := Signed{ContentType: sd.EncapContentInfo.EContentType}
+	if len(sd.EncapContentInfo.EContent.Bytes) > 0 {
+		if err = unmarshal(sd.EncapContentInfo.EContent.Bytes, &signed.Content, "eContent"); err != nil {
+			return nil, err
+		}
+		if detached != nil && !bytes.Equal(detached, signed.Content) {
+			return nil, errors.New("gogost/cms: detached content differs from encapsulated one")
+		}
+	} else {
+		if detached == nil {
+			return nil, errors.New("gogost/cms: no content")
+		}
+		signed.Content = detached
+	}
+	for rest := sd.Certificates.Bytes; len(rest) > 0; {
+		var raw asn1.RawValue
+		if rest, err = asn1.Unmarshal(rest, &raw); err != nil {
+			return nil, fmt.Errorf("gogost/cms: invalid certificates: %w", err)
+		}
+		if raw.Class != asn1.ClassUniversal {
+			continue // other certificate formats
+		}
+		cert, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		signed.Certificates = append(signed.Certificates, cert)
+	}
+	if len(sd.SignerInfos) == 0 {
+		return nil, errors.New("gogost/cms: no signers")
+	}
+	certs = append(append([]*x509.Certificate{}, signed.Certificates...), certs...)
+	for _, si := range sd.SignerInfos {
+		cert := findCert(certs, si.SID)
+		if cert == nil {
+			return nil, errNoCert
+		}
+		signingTime, err := verifySigner(&si, cert, signed.ContentType, signed.Content)
+		if err != nil {
+			return nil, err
+		}
+		signed.Signers = append(signed.Signers, cert)
+		signed.SigningTimes = append(signed.SigningTimes, signingTime)
+	}
+	return &signed, nil
+}
+
+func verifySigner(
+	si *signerInfo,
+	cert *x509.Certificate,
+	contentType asn1.ObjectIdentifier,
+	content []byte,
+) (signingTime time.Time, err error) {
+	pub, keyAlgo, err := certPublicKey(cert)
+	if err != nil {
+		return
+	}
+	digestOID := si.DigestAlgorithm.Algorithm
+	if expected, _ := gost3410.SignatureAlgorithms(keyAlgo); !digestOID.Equal(expected) {
+		err = fmt.Errorf("gogost/cms: digest algorithm %s does not match the key", digestOID)
+		return
+	}
+	if sigDigest := gost3410.DigestBySignatureAlgorithm(si.SignatureAlgorithm.Algorithm); !digestOID.Equal(sigDigest) {
+		err = fmt.Errorf("gogost/cms: unsupported signature algorithm %s", si.SignatureAlgorithm.Algorithm)
+		return
+	}
+	contentDigest, err := digest(digestOID, content)
+	if err != nil {
+		return
+	}
+	toBeSigned := content
+	if len(si.SignedAttrs.FullBytes) > 0 {
+		var attrs []Attribute
+		if toBeSigned, err = signedAttrsSet(si.SignedAttrs); err != nil {
+			return
+		}
+		var rest []byte
+		if rest, err = asn1.UnmarshalWithParams(toBeSigned, &attrs, "set"); err != nil {
+			err = fmt.Errorf("gogost/cms: invalid signed attributes: %w", err)
+			return
+		}
+		if len(rest) > 0 {
+			err = errors.New("gogost/cms: trailing data after signed attributes")
+			return
+		}
+		if signingTime, err = checkAttributes(attrs, cert, contentType, contentDigest); err != nil {
+			return
+		}
+	} else if !contentType.Equal(OIDData) {
+		err = errors.New("gogost/cms: signed attributes are missing")
+		return
+	}
+	dgst, err := digest(digestOID, toBeSigned)
+	if err != nil {
+		return
+	}
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(dgst, si.Signature)
+	if err != nil {
+		return
+	}
+	if !valid {
+		err = errors.New("gogost/cms: invalid signature")
+	}
+	return
+}
+
+func checkAttributes(
+	attrs []Attribute,
+	cert *x509.Certificate,
+	contentType asn1.ObjectIdentifier,
+	contentDigest []byte,
+) (signingTime time.Time, err error) {
+	value, err := attributeValue(attrs, OIDAttrContentType)
+	if err != nil {
+		return
+	}
+	var ct asn1.ObjectIdentifier
+	if value == nil || unmarshal(value, &ct, "contentType") != nil || !ct.Equal(contentType) {
+		err = errors.New("gogost/cms: invalid contentType attribute")
+		return
+	}
+	if value, err = attributeValue(attrs, OIDAttrMessageDigest); err != nil {
+		return
+	}
+	var md []byte
+	if value == nil || unmarshal(value, &md, "messageDigest") != nil {
+		err = errors.New("gogost/cms: invalid messageDigest attribute")
+		return
+	}
+	if !hmac.Equal(md, contentDigest) {
+		err = errors.New("gogost/cms: message digest mismatch")
+		return
+	}
+	if value, err = attributeValue(attrs, OIDAttrSigningTime); err != nil {
+		return
+	}
+	if value != nil {
+		if err = unmarshal(value, &signingTime, "signingTime"); err != nil {
+			return
+		}
+	}
+	if value, err = attributeValue(attrs, OIDAttrSigningCertificateV2); err != nil {
+		return
+	}
+	if value != nil {
+		var sc signingCertificateV2
+		if err = unmarshal(value, &sc, "signingCertificateV2"); err != nil {
+			return
+		}
+		if len(sc.Certs) == 0 {
+			err = errors.New("gogost/cms: empty signingCertificateV2")
+			return
+		}
+		id := sc.Certs[0]
+		hashAlgo := id.HashAlgorithm.Algorithm
+		if hashAlgo == nil {
+			hashAlgo = oidSHA256
+		}
+		var certDigest []byte
+		if certDigest, err = digest(hashAlgo, cert.Raw); err != nil {
+			return
+		}
+		if !hmac.Equal(certDigest, id.CertHash) {
+			err = errors.New("gogost/cms: signingCertificateV2 does not match signer's certificate")
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,135 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cms
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/asn1"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+var testCurves = []struct {
+	c      func() *gost3410.Curve
+	legacy bool
+}{
+	{gost3410.CurveIdGostR34102001CryptoProAParamSet, true},
+	{gost3410.CurveIdtc26gost34102012256paramSetA, false},
+	{gost3410.CurveIdtc26gost34102012512paramSetA, false},
+}
+
+func TestSignVerify(t *testing.T) {
+	content := []byte("some content to sign")
+	for _, tc := range testCurves {
+		cert, prv := newTestCert(t, tc.c(), tc.legacy)
+		signingTime := time.Now().UTC().Truncate(time.Second)
+		for _, detached := range []bool{false, true} {
+			data, err := Sign(rand.Reader, content, cert, prv, &SignOpts{
+				Detached:    detached,
+				SigningTime: signingTime,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var signed *Signed
+			if detached {
+				if _, err = Verify(data, nil, nil); err == nil {
+					t.Fatal("no content accepted")
+				}
+				signed, err = Verify(data, content, nil)
+			} else {
+				signed, err = Verify(data, nil, nil)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(signed.Content, content) ||
+				!signed.ContentType.Equal(OIDData) ||
+				len(signed.Signers) != 1 ||
+				!signed.Signers[0].Equal(cert) ||
+				!signed.SigningTimes[0].Equal(signingTime) {
+				t.Fatal("signed data differs")
+			}
+			if _, err = Verify(data, []byte("another content"), nil); err == nil {
+				t.Fatal("another content accepted")
+			}
+		}
+	}
+}
+
+func TestVerifyForeignCertificate(t *testing.T) {
+	cert, prv := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetB(), false)
+	other, _ := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetB(), false)
+	data, err := Sign(rand.Reader, []byte("content"), cert, prv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Substitute the included certificate with another one
+	data = bytes.Replace(data, cert.Raw, other.Raw, 1)
+	if _, err = Verify(data, nil, []*x509.Certificate{other}); err == nil {
+		t.Fatal("foreign certificate accepted")
+	}
+}
+
+func TestNoCertificates(t *testing.T) {
+	cert, prv := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	data, err := Sign(rand.Reader, []byte("content"), cert, prv, &SignOpts{NoCertificates: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, cert.Raw) {
+		t.Fatal("certificate included")
+	}
+	if _, err = Verify(data, nil, nil); err == nil {
+		t.Fatal("verified without certificate")
+	}
+	if _, err = Verify(data, nil, []*x509.Certificate{cert}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestContentType(t *testing.T) {
+	cert, prv := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	ct := asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	data, err := Sign(rand.Reader, []byte{0x30, 0x00}, cert, prv, &SignOpts{ContentType: ct})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := Verify(data, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !signed.ContentType.Equal(ct) {
+		t.FailNow()
+	}
+}
+
+func TestSignatureCorruption(t *testing.T) {
+	cert, prv := newTestCert(t, gost3410.CurveIdtc26gost34102012512paramSetB(), false)
+	data, err := Sign(rand.Reader, []byte("content"), cert, prv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signature is the last element of the structure
+	data[len(data)-1] ^= 1
+	if _, err = Verify(data, nil, nil); err == nil {
+		t.Fatal("corrupted signature accepted")
+	}
+}
@@ -0,0 +1,290 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Windows CryptoAPI (CryptoPro CSP) SIMPLEBLOB and PUBLICKEYBLOB key
+// blobs with GOST algorithms.
+//
+// Session key is exported with CryptoPro key wrap (RFC 4357): VKO
+// (34.10-2001 or 34.10-2012 256-bit) key agreement output diversified
+// with UKM, 28147-89 ECB encryption of the key and its MAC. Wrapping is
+// done under CryptoPro-A parameters, as gost28147.WrapGost does.
+package cryptoapi
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+// Blob types, version and magic values.
+const (
+	TypeSimpleBlob    = 0x01
+	TypePublicKeyBlob = 0x06
+	BlobVersion       = 0x20
+
+	G28147Magic  = 0x374A51FD
+	GR3410Magic1 = 0x3147414D // "MAG1"
+)
+
+// Algorithm identifiers (ALG_ID).
+const (
+	CalgG28147             = 0x661E
+	CalgGR3410EL           = 0x2E23
+	CalgGR341012256        = 0x2E49
+	CalgGR341012512        = 0x2E3D
+	CalgDHELSF             = 0xAA24
+	CalgDHELEphem          = 0xAA25
+	CalgDHGR341012256SF    = 0xAA46
+	CalgDHGR341012256Ephem = 0xAA47
+	CalgDHGR341012512SF    = 0xAA42
+	CalgDHGR341012512Ephem = 0xAA43
+)
+
+const (
+	blobHeaderSize = 8
+	ukmSize        = 8
+	macSize        = 4
+)
+
+var ErrMAC = errors.New("gogost/cryptoapi: invalid key MAC")
+
+func putHeader(dst []byte, typ byte, alg uint32) {
+	dst[0] = typ
+	dst[1] = BlobVersion
+	dst[2], dst[3] = 0, 0
+	binary.LittleEndian.PutUint32(dst[4:], alg)
+}
+
+func parseHeader(data []byte, typ byte) (uint32, error) {
+	if len(data) < blobHeaderSize {
+		return 0, errors.New("gogost/cryptoapi: blob is too short")
+	}
+	if data[0] != typ {
+		return 0, fmt.Errorf("gogost/cryptoapi: unexpected blob type 0x%02X", data[0])
+	}
+	if data[1] != BlobVersion {
+		return 0, fmt.Errorf("gogost/cryptoapi: unsupported blob version 0x%02X", data[1])
+	}
+	return binary.LittleEndian.Uint32(data[4:]), nil
+}
+
+// CRYPT_SIMPLEBLOB with the wrapped 28147-89 session key.
+type SimpleBlob struct {
+	KeyAlg             uint32
+	UKM                []byte
+	EncryptedKey       []byte
+	MAC                []byte
+	EncryptionParamSet asn1.ObjectIdentifier
+}
+
+func (b *SimpleBlob) Marshal() ([]byte, error) {
+	if len(b.UKM) != ukmSize ||
+		len(b.EncryptedKey) != gost28147.KeySize ||
+		len(b.MAC) != macSize {
+		return nil, errors.New("gogost/cryptoapi: invalid SIMPLEBLOB fields sizes")
+	}
+	oid, err := asn1.Marshal(b.EncryptionParamSet)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, blobHeaderSize+8, blobHeaderSize+8+ukmSize+gost28147.KeySize+macSize+len(oid))
+	putHeader(data, TypeSimpleBlob, b.KeyAlg)
+	binary.LittleEndian.PutUint32(data[blobHeaderSize:], G28147Magic)
+	binary.LittleEndian.PutUint32(data[blobHeaderSize+4:], CalgG28147)
+	data = append(data, b.UKM...)
+	data = append(data, b.EncryptedKey...)
+	data = append(data, b.MAC...)
+	return append(data, oid...), nil
+}
+
+func ParseSimpleBlob(data []byte) (*SimpleBlob, error) {
+	alg, err := parseHeader(data, TypeSimpleBlob)
+	if err != nil {
+		return nil, err
+	}
+	data = data[blobHeaderSize:]
+	if len(data) < 8+ukmSize+gost28147.KeySize+macSize {
+		return nil, errors.New("gogost/cryptoapi: SIMPLEBLOB is too short")
+	}
+	if binary.LittleEndian.Uint32(data) != G28147Magic {
+		return nil, errors.New("gogost/cryptoapi: invalid SIMPLEBLOB magic")
+	}
+	if alg := binary.LittleEndian.Uint32(data[4:]); alg != CalgG28147 {
+		return nil, fmt.Errorf("gogost/cryptoapi: unsupported key encryption algorithm 0x%04X", alg)
+	}
+	data = data[8:]
+	b := SimpleBlob{KeyAlg: alg}
+	b.UKM, data = data[:ukmSize], data[ukmSize:]
+	b.EncryptedKey, data = data[:gost28147.KeySize], data[gost28147.KeySize:]
+	b.MAC, data = data[:macSize], data[macSize:]
+	rest, err := asn1.Unmarshal(data, &b.EncryptionParamSet)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/cryptoapi: invalid encryption parameters: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost/cryptoapi: trailing data after SIMPLEBLOB")
+	}
+	return &b, nil
+}
+
+func kek(prv *gost3410.PrivateKey, pub *gost3410.PublicKey, ukm []byte, legacy bool) ([]byte, error) {
+	if legacy {
+		return prv.KEK2001(pub, gost3410.NewUKM(ukm))
+	}
+	return prv.KEK2012256(pub, gost3410.NewUKM(ukm))
+}
+
+// Export the session key to SIMPLEBLOB for the recipient's public key.
+// VKO 34.10-2001 is used if legacy is true, VKO 34.10-2012 256-bit
+// otherwise. Session key's encryption parameters are taken from sbox.
+func ExportKey(
+	rand io.Reader,
+	prv *gost3410.PrivateKey,
+	pub *gost3410.PublicKey,
+	cek []byte,
+	sbox *gost28147.Sbox,
+	legacy bool,
+) (*SimpleBlob, error) {
+	if len(cek) != gost28147.KeySize {
+		return nil, errors.New("gogost/cryptoapi: invalid session key size")
+	}
+	paramSet := sbox.OID()
+	if paramSet == nil {
+		return nil, errors.New("gogost/cryptoapi: Sbox has no identifier")
+	}
+	ukm := make([]byte, ukmSize)
+	if _, err := io.ReadFull(rand, ukm); err != nil {
+		return nil, err
+	}
+	key, err := kek(prv, pub, ukm, legacy)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := gost28147.WrapGost(ukm, gost28147.DiversifyCryptoPro(key, ukm), cek)
+	return &SimpleBlob{
+		KeyAlg:             CalgG28147,
+		UKM:                wrapped[:ukmSize],
+		EncryptedKey:       wrapped[ukmSize : ukmSize+gost28147.KeySize],
+		MAC:                wrapped[ukmSize+gost28147.KeySize:],
+		EncryptionParamSet: paramSet,
+	}, nil
+}
+
+// Import the session key from SIMPLEBLOB with the recipient's private
+// key and the sender's public one. Its Sbox is returned too.
+func (b *SimpleBlob) ImportKey(
+	prv *gost3410.PrivateKey,
+	pub *gost3410.PublicKey,
+	legacy bool,
+) ([]byte, *gost28147.Sbox, error) {
+	sbox, _ := gost28147.SboxByOID(b.EncryptionParamSet)
+	if sbox == nil {
+		return nil, nil, fmt.Errorf("gogost/cryptoapi: unknown encryption parameters %s", b.EncryptionParamSet)
+	}
+	key, err := kek(prv, pub, b.UKM, legacy)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped := make([]byte, 0, ukmSize+gost28147.KeySize+macSize)
+	wrapped = append(wrapped, b.UKM...)
+	wrapped = append(wrapped, b.EncryptedKey...)
+	wrapped = append(wrapped, b.MAC...)
+	cek := gost28147.UnwrapCryptoPro(key, wrapped)
+	if cek == nil {
+		return nil, nil, ErrMAC
+	}
+	return cek, sbox, nil
+}
+
+type keyParameters struct {
+	PublicKeyParamSet  asn1.ObjectIdentifier
+	DigestParamSet     asn1.ObjectIdentifier `asn1:"optional"`
+	EncryptionParamSet asn1.ObjectIdentifier `asn1:"optional"`
+}
+
+// PUBLICKEYBLOB with CRYPT_PUBKEYPARAM and key parameters.
+type PublicKeyBlob struct {
+	KeyAlg             uint32
+	Pub                *gost3410.PublicKey
+	DigestParamSet     asn1.ObjectIdentifier
+	EncryptionParamSet asn1.ObjectIdentifier
+}
+
+func (b *PublicKeyBlob) Marshal() ([]byte, error) {
+	paramSet := b.Pub.C.OID()
+	if paramSet == nil {
+		return nil, fmt.Errorf("gogost/cryptoapi: curve %s has no identifier", b.Pub.C.Name)
+	}
+	params, err := asn1.Marshal(keyParameters{
+		PublicKeyParamSet:  paramSet,
+		DigestParamSet:     b.DigestParamSet,
+		EncryptionParamSet: b.EncryptionParamSet,
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw := b.Pub.RawLE()
+	data := make([]byte, blobHeaderSize+8, blobHeaderSize+8+len(params)+len(raw))
+	putHeader(data, TypePublicKeyBlob, b.KeyAlg)
+	binary.LittleEndian.PutUint32(data[blobHeaderSize:], GR3410Magic1)
+	binary.LittleEndian.PutUint32(data[blobHeaderSize+4:], uint32(8*len(raw)))
+	data = append(data, params...)
+	return append(data, raw...), nil
+}
+
+func ParsePublicKeyBlob(data []byte) (*PublicKeyBlob, error) {
+	alg, err := parseHeader(data, TypePublicKeyBlob)
+	if err != nil {
+		return nil, err
+	}
+	data = data[blobHeaderSize:]
+	if len(data) < 8 {
+		return nil, errors.New("gogost/cryptoapi: PUBLICKEYBLOB is too short")
+	}
+	if binary.LittleEndian.Uint32(data) != GR3410Magic1 {
+		return nil, errors.New("gogost/cryptoapi: invalid PUBLICKEYBLOB magic")
+	}
+	bitLen := int(binary.LittleEndian.Uint32(data[4:]))
+	var params keyParameters
+	raw, err := asn1.Unmarshal(data[8:], &params)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/cryptoapi: invalid key parameters: %w", err)
+	}
+	c := gost3410.CurveByOID(params.PublicKeyParamSet)
+	if c == nil {
+		return nil, fmt.Errorf("gogost/cryptoapi: unknown curve %s", params.PublicKeyParamSet)
+	}
+	if bitLen != 8*len(raw) {
+		return nil, errors.New("gogost/cryptoapi: public key length mismatch")
+	}
+	pub, err := gost3410.NewPublicKeyLE(c, raw)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Contains(pub.X, pub.Y) {
+		return nil, errors.New("gogost/cryptoapi: public key is not on the curve")
+	}
+	return &PublicKeyBlob{
+		KeyAlg:             alg,
+		Pub:                pub,
+		DigestParamSet:     params.DigestParamSet,
+		EncryptionParamSet: params.EncryptionParamSet,
+	}, nil
+}
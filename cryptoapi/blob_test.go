@@ -0,0 +1,125 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cryptoapi
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func testExportImport(t *testing.T, c *gost3410.Curve, legacy bool) {
+	prvA, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubA, err := prvA.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prvB, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubB, err := prvB.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cek := make([]byte, gost28147.KeySize)
+	if _, err = rand.Read(cek); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := ExportKey(
+		rand.Reader, prvA, pubB, cek,
+		&gost28147.SboxIdGost2814789CryptoProAParamSet, legacy,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := blob.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data[:16], []byte{
+		0x01, 0x20, 0x00, 0x00, 0x1E, 0x66, 0x00, 0x00,
+		0xFD, 0x51, 0x4A, 0x37, 0x1E, 0x66, 0x00, 0x00,
+	}) {
+		t.Fatalf("invalid header %x", data[:16])
+	}
+	parsed, err := ParseSimpleBlob(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, sbox, err := parsed.ImportKey(prvB, pubA, legacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, cek) || *sbox != gost28147.SboxIdGost2814789CryptoProAParamSet {
+		t.Fatal("session key differs")
+	}
+	parsed.MAC[0] ^= 1
+	if _, _, err = parsed.ImportKey(prvB, pubA, legacy); err != ErrMAC {
+		t.Fatal("invalid MAC accepted")
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	testExportImport(t, gost3410.CurveIdGostR34102001CryptoProXchAParamSet(), true)
+	testExportImport(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	testExportImport(t, gost3410.CurveIdtc26gost34102012512paramSetA(), false)
+}
+
+func TestPublicKeyBlob(t *testing.T) {
+	for _, c := range []*gost3410.Curve{
+		gost3410.CurveIdGostR34102001CryptoProAParamSet(),
+		gost3410.CurveIdtc26gost34102012512paramSetB(),
+	} {
+		prv, err := gost3410.GenPrivateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := (&PublicKeyBlob{
+			KeyAlg:             CalgGR3410EL,
+			Pub:                pub,
+			DigestParamSet:     gost3410.OIDGostR341194CryptoProParamSet,
+			EncryptionParamSet: gost28147.SboxIdGost2814789CryptoProAParamSet.OID(),
+		}).Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		blob, err := ParsePublicKeyBlob(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if blob.KeyAlg != CalgGR3410EL ||
+			!blob.Pub.C.Equal(c) ||
+			blob.Pub.X.Cmp(pub.X) != 0 ||
+			blob.Pub.Y.Cmp(pub.Y) != 0 ||
+			!blob.DigestParamSet.Equal(gost3410.OIDGostR341194CryptoProParamSet) {
+			t.Fatal("public key blob differs")
+		}
+		if _, err = ParseSimpleBlob(data); err == nil {
+			t.Fatal("PUBLICKEYBLOB parsed as SIMPLEBLOB")
+		}
+	}
+}
@@ -0,0 +1,450 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// CryptoPro CSP key containers (header.key, masks.key, primary.key,
+// masks2.key, primary2.key, name.key files in a directory).
+//
+// Private key is stored multiplied by the random mask modulo subgroup
+// order (so it is unmasked with the mask's inverse) and encrypted with
+// GOST 28147-89 in ECB mode under the key derived from the PIN and
+// masks' salt. Header and masks are authenticated with 28147-89 MAC
+// under the same key.
+//
+// Layout follows publicly available descriptions of the format and was
+// not checked against containers produced by CryptoPro CSP.
+package cryptopro
+
+import (
+	"crypto/hmac"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost341194"
+)
+
+const (
+	HeaderFile     = "header.key"
+	NameFile       = "name.key"
+	MasksFile      = "masks.key"
+	Masks2File     = "masks2.key"
+	PrimaryFile    = "primary.key"
+	Primary2File   = "primary2.key"
+	saltSize       = 12
+	macSize        = 4
+	fingerprintLen = 8
+)
+
+var (
+	// CryptoPro specific key algorithms, met in containers.
+	OIDGostR34102001DH      = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 98}
+	OIDGostR34102001ESDH    = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 99}
+	OIDTc26Agreement2012256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 6, 1}
+	OIDTc26Agreement2012512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 6, 2}
+
+	ErrPIN = errors.New("gogost/cryptopro: integrity check failed, wrong PIN?")
+)
+
+type keyParameters struct {
+	PublicKeyParamSet  asn1.ObjectIdentifier
+	DigestParamSet     asn1.ObjectIdentifier `asn1:"optional"`
+	EncryptionParamSet asn1.ObjectIdentifier `asn1:"optional"`
+}
+
+type privateKeyParameters struct {
+	Attributes asn1.BitString
+	Algo       pkix.AlgorithmIdentifier `asn1:"tag:0,optional"`
+}
+
+type keyContainerContent struct {
+	ContainerAlgoIdentifier       pkix.AlgorithmIdentifier `asn1:"tag:0,optional"`
+	ContainerName                 string                   `asn1:"tag:1,optional,ia5"`
+	Attributes                    asn1.BitString
+	PrimaryPrivateKeyParameters   privateKeyParameters
+	SecondaryPrivateKeyParameters privateKeyParameters `asn1:"tag:4,optional"`
+	PrimaryCertificate            []byte               `asn1:"tag:5,optional"`
+	SecondaryCertificate          []byte               `asn1:"tag:6,optional"`
+	PrimaryFP                     []byte               `asn1:"tag:10,optional"`
+	SecondaryFP                   []byte               `asn1:"tag:11,optional"`
+}
+
+type header struct {
+	KeyContainerContent     asn1.RawValue
+	HMACKeyContainerContent []byte
+}
+
+type masks struct {
+	Mask       []byte
+	RandomSalt []byte
+	HMACRandom []byte
+}
+
+type name struct {
+	Name string `asn1:"ia5"`
+}
+
+type Container struct {
+	Name string
+
+	// Primary key pair and optional secondary (exchange) one.
+	Primary   *gost3410.PrivateKey
+	Secondary *gost3410.PrivateKey
+
+	PrimaryCertificate   []byte
+	SecondaryCertificate []byte
+
+	// 34.10-2001 key identifiers, GOST R 34.11-94 based PIN key
+	// derivation and CryptoPro-A 28147-89 parameters are used.
+	// Otherwise 34.10-2012, Streebog-256 and paramSetZ.
+	Legacy bool
+}
+
+func newGostR341194() hash.Hash {
+	return gost341194.New(&gost28147.SboxIdGostR341194CryptoProParamSet)
+}
+
+func (c *Container) params() (h func() hash.Hash, sbox *gost28147.Sbox) {
+	if c.Legacy {
+		return newGostR341194, &gost28147.SboxIdGost2814789CryptoProAParamSet
+	}
+	return gost34112012256.New, &gost28147.SboxIdtc26gost28147paramZ
+}
+
+func mac(c *gost28147.Cipher, data []byte) []byte {
+	m, err := c.NewMAC(macSize, data[:gost28147.BlockSize])
+	if err != nil {
+		panic(err)
+	}
+	m.Write(data[gost28147.BlockSize:])
+	return m.Sum(nil)
+}
+
+func unmarshal(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	rest, err := asn1.Unmarshal(data, v)
+	if err != nil {
+		return fmt.Errorf("gogost/cryptopro: %s: %w", filepath.Base(path), err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/cryptopro: %s: trailing data", filepath.Base(path))
+	}
+	return nil
+}
+
+func fingerprint(prv *gost3410.PrivateKey) ([]byte, error) {
+	pub, err := prv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	return pub.RawLE()[:fingerprintLen], nil
+}
+
+// Get the curve from key parameters and whether it is legacy 34.10-2001
+// key. Encryption parameters Sbox is returned if specified.
+func parseKeyAlgo(ai pkix.AlgorithmIdentifier) (*gost3410.Curve, bool, *gost28147.Sbox, error) {
+	var legacy bool
+	switch {
+	case ai.Algorithm.Equal(gost3410.OIDGostR34102001),
+		ai.Algorithm.Equal(OIDGostR34102001DH),
+		ai.Algorithm.Equal(OIDGostR34102001ESDH):
+		legacy = true
+	case ai.Algorithm.Equal(gost3410.OIDTc26Gost34102012256),
+		ai.Algorithm.Equal(gost3410.OIDTc26Gost34102012512),
+		ai.Algorithm.Equal(OIDTc26Agreement2012256),
+		ai.Algorithm.Equal(OIDTc26Agreement2012512):
+	default:
+		return nil, false, nil, fmt.Errorf("gogost/cryptopro: unknown key algorithm %s", ai.Algorithm)
+	}
+	var params keyParameters
+	if _, err := asn1.Unmarshal(ai.Parameters.FullBytes, &params); err != nil {
+		return nil, false, nil, fmt.Errorf("gogost/cryptopro: invalid key parameters: %w", err)
+	}
+	curve := gost3410.CurveByOID(params.PublicKeyParamSet)
+	if curve == nil {
+		return nil, false, nil, fmt.Errorf("gogost/cryptopro: unknown curve %s", params.PublicKeyParamSet)
+	}
+	var sbox *gost28147.Sbox
+	if params.EncryptionParamSet != nil {
+		if sbox, _ = gost28147.SboxByOID(params.EncryptionParamSet); sbox == nil {
+			return nil, false, nil, fmt.Errorf(
+				"gogost/cryptopro: unknown encryption parameters %s",
+				params.EncryptionParamSet,
+			)
+		}
+	}
+	return curve, legacy, sbox, nil
+}
+
+// Read masks file, derive the key encryption key and unmask the
+// private key from primary file.
+func (c *Container) openKey(
+	dir, masksFile, primaryFile, pin string,
+	curve *gost3410.Curve,
+	sbox *gost28147.Sbox,
+) (*gost3410.PrivateKey, *gost28147.Cipher, error) {
+	var m masks
+	if err := unmarshal(filepath.Join(dir, masksFile), &m); err != nil {
+		return nil, nil, err
+	}
+	var encrypted []byte
+	if err := unmarshal(filepath.Join(dir, primaryFile), &encrypted); err != nil {
+		return nil, nil, err
+	}
+	size := curve.PointSize()
+	if len(m.Mask) != size || len(encrypted) != size || len(m.RandomSalt) != saltSize {
+		return nil, nil, errors.New("gogost/cryptopro: invalid key sizes")
+	}
+	h, _ := c.params()
+	kek := gost28147.NewCipher(deriveKEK(h, pin, m.RandomSalt), sbox)
+	if !hmac.Equal(mac(kek, append(append([]byte{}, m.Mask...), m.RandomSalt...)), m.HMACRandom) {
+		return nil, nil, ErrPIN
+	}
+	masked := make([]byte, size)
+	for i := 0; i < size; i += gost28147.BlockSize {
+		kek.Decrypt(masked[i:], encrypted[i:])
+	}
+	k := le2big(masked)
+	mask := le2big(m.Mask)
+	if mask.ModInverse(mask, curve.Q) == nil {
+		return nil, nil, errors.New("gogost/cryptopro: invalid mask")
+	}
+	k.Mul(k, mask).Mod(k, curve.Q)
+	if k.Sign() == 0 {
+		return nil, nil, errors.New("gogost/cryptopro: zero private key")
+	}
+	return &gost3410.PrivateKey{C: curve, Key: k}, kek, nil
+}
+
+// Open the container in the directory with the PIN. Fingerprints of
+// the keys are checked.
+func Open(dir, pin string) (*Container, error) {
+	var hdr header
+	if err := unmarshal(filepath.Join(dir, HeaderFile), &hdr); err != nil {
+		return nil, err
+	}
+	var content keyContainerContent
+	if _, err := asn1.Unmarshal(hdr.KeyContainerContent.FullBytes, &content); err != nil {
+		return nil, fmt.Errorf("gogost/cryptopro: %s: %w", HeaderFile, err)
+	}
+	c := &Container{
+		Name:                 content.ContainerName,
+		PrimaryCertificate:   content.PrimaryCertificate,
+		SecondaryCertificate: content.SecondaryCertificate,
+	}
+	var n name
+	if err := unmarshal(filepath.Join(dir, NameFile), &n); err == nil {
+		c.Name = n.Name
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	curve, legacy, sbox, err := parseKeyAlgo(content.PrimaryPrivateKeyParameters.Algo)
+	if err != nil {
+		return nil, err
+	}
+	c.Legacy = legacy
+	if sbox == nil {
+		_, sbox = c.params()
+	}
+	var kek *gost28147.Cipher
+	c.Primary, kek, err = c.openKey(dir, MasksFile, PrimaryFile, pin, curve, sbox)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac(kek, hdr.KeyContainerContent.FullBytes), hdr.HMACKeyContainerContent) {
+		return nil, ErrPIN
+	}
+	fp, err := fingerprint(c.Primary)
+	if err != nil {
+		return nil, err
+	}
+	if content.PrimaryFP != nil && !hmac.Equal(fp, content.PrimaryFP) {
+		return nil, errors.New("gogost/cryptopro: primary key fingerprint mismatch")
+	}
+
+	if content.SecondaryPrivateKeyParameters.Algo.Algorithm == nil {
+		return c, nil
+	}
+	curve, _, sbox, err = parseKeyAlgo(content.SecondaryPrivateKeyParameters.Algo)
+	if err != nil {
+		return nil, err
+	}
+	if sbox == nil {
+		_, sbox = c.params()
+	}
+	c.Secondary, _, err = c.openKey(dir, Masks2File, Primary2File, pin, curve, sbox)
+	if err != nil {
+		return nil, err
+	}
+	if fp, err = fingerprint(c.Secondary); err != nil {
+		return nil, err
+	}
+	if content.SecondaryFP != nil && !hmac.Equal(fp, content.SecondaryFP) {
+		return nil, errors.New("gogost/cryptopro: secondary key fingerprint mismatch")
+	}
+	return c, nil
+}
+
+func (c *Container) keyParams(prv *gost3410.PrivateKey) (privateKeyParameters, error) {
+	ai, err := gost3410.KeyAlgorithm(prv.C, c.Legacy)
+	if err != nil {
+		return privateKeyParameters{}, err
+	}
+	var params keyParameters
+	if _, err = asn1.Unmarshal(ai.Parameters.FullBytes, &params); err != nil {
+		return privateKeyParameters{}, err
+	}
+	_, sbox := c.params()
+	params.EncryptionParamSet = sbox.OID()
+	der, err := asn1.Marshal(params)
+	if err != nil {
+		return privateKeyParameters{}, err
+	}
+	ai.Parameters = asn1.RawValue{FullBytes: der}
+	return privateKeyParameters{
+		Attributes: asn1.BitString{Bytes: []byte{0}, BitLength: 1},
+		Algo:       ai,
+	}, nil
+}
+
+func writeDER(path string, v interface{}) error {
+	der, err := asn1.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, der, 0o600)
+}
+
+// Mask, encrypt and write the private key. Key encryption key is
+// returned.
+func (c *Container) writeKey(
+	rand io.Reader,
+	dir, masksFile, primaryFile, pin string,
+	prv *gost3410.PrivateKey,
+) (*gost28147.Cipher, error) {
+	size := prv.C.PointSize()
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, err
+	}
+	maskRaw := make([]byte, size)
+	var mask *big.Int
+	for {
+		if _, err := io.ReadFull(rand, maskRaw); err != nil {
+			return nil, err
+		}
+		mask = le2big(maskRaw)
+		if mask.Sign() > 0 && mask.Cmp(prv.C.Q) < 0 {
+			break
+		}
+	}
+	h, sbox := c.params()
+	kek := gost28147.NewCipher(deriveKEK(h, pin, salt), sbox)
+	k := new(big.Int).Mul(prv.Key, mask)
+	k.Mod(k, prv.C.Q)
+	masked := big2le(k, size)
+	encrypted := make([]byte, size)
+	for i := 0; i < size; i += gost28147.BlockSize {
+		kek.Encrypt(encrypted[i:], masked[i:])
+	}
+	if err := writeDER(filepath.Join(dir, masksFile), masks{
+		Mask:       maskRaw,
+		RandomSalt: salt,
+		HMACRandom: mac(kek, append(append([]byte{}, maskRaw...), salt...)),
+	}); err != nil {
+		return nil, err
+	}
+	return kek, writeDER(filepath.Join(dir, primaryFile), encrypted)
+}
+
+// Write the container to the directory, creating it if necessary,
+// protecting keys with the PIN.
+func (c *Container) Write(rand io.Reader, dir, pin string) error {
+	if c.Primary == nil {
+		return errors.New("gogost/cryptopro: no primary key")
+	}
+	content := keyContainerContent{
+		ContainerName:        c.Name,
+		Attributes:           asn1.BitString{Bytes: []byte{0}, BitLength: 1},
+		PrimaryCertificate:   c.PrimaryCertificate,
+		SecondaryCertificate: c.SecondaryCertificate,
+	}
+	var err error
+	if content.PrimaryPrivateKeyParameters, err = c.keyParams(c.Primary); err != nil {
+		return err
+	}
+	if content.PrimaryFP, err = fingerprint(c.Primary); err != nil {
+		return err
+	}
+	if c.Secondary != nil {
+		if content.SecondaryPrivateKeyParameters, err = c.keyParams(c.Secondary); err != nil {
+			return err
+		}
+		if content.SecondaryFP, err = fingerprint(c.Secondary); err != nil {
+			return err
+		}
+	}
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	kek, err := c.writeKey(rand, dir, MasksFile, PrimaryFile, pin, c.Primary)
+	if err != nil {
+		return err
+	}
+	if c.Secondary != nil {
+		if _, err = c.writeKey(rand, dir, Masks2File, Primary2File, pin, c.Secondary); err != nil {
+			return err
+		}
+	}
+	der, err := asn1.Marshal(content)
+	if err != nil {
+		return err
+	}
+	if err = writeDER(filepath.Join(dir, HeaderFile), header{
+		KeyContainerContent:     asn1.RawValue{FullBytes: der},
+		HMACKeyContainerContent: mac(kek, der),
+	}); err != nil {
+		return err
+	}
+	return writeDER(filepath.Join(dir, NameFile), name{Name: c.Name})
+}
+
+func le2big(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i := range b {
+		be[i] = b[len(b)-1-i]
+	}
+	return new(big.Int).SetBytes(be)
+}
+
+func big2le(n *big.Int, size int) []byte {
+	b := n.FillBytes(make([]byte, size))
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
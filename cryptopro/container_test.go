@@ -0,0 +1,137 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cryptopro
+
+import (
+	"bytes"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func testRoundtrip(t *testing.T, legacy bool, curves ...*gost3410.Curve) {
+	c := &Container{Name: "test-container", Legacy: legacy}
+	var err error
+	if c.Primary, err = gost3410.GenPrivateKey(curves[0], rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	if len(curves) > 1 {
+		if c.Secondary, err = gost3410.GenPrivateKey(curves[1], rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+		c.SecondaryCertificate = []byte{0x30, 0x00}
+	}
+	dir := filepath.Join(t.TempDir(), "test.000")
+	if err = c.Write(rand.Reader, dir, "12345678"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(dir, "12345678")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != c.Name || got.Legacy != legacy {
+		t.Fatal("container parameters differ")
+	}
+	if !got.Primary.C.Equal(curves[0]) || got.Primary.Key.Cmp(c.Primary.Key) != 0 {
+		t.Fatal("primary key differs")
+	}
+	if len(curves) > 1 {
+		if got.Secondary == nil ||
+			!got.Secondary.C.Equal(curves[1]) ||
+			got.Secondary.Key.Cmp(c.Secondary.Key) != 0 {
+			t.Fatal("secondary key differs")
+		}
+		if !bytes.Equal(got.SecondaryCertificate, c.SecondaryCertificate) {
+			t.Fatal("secondary certificate differs")
+		}
+	} else if got.Secondary != nil {
+		t.Fatal("unexpected secondary key")
+	}
+	if _, err = Open(dir, "1234567"); err != ErrPIN {
+		t.Fatal("wrong PIN accepted")
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	testRoundtrip(t, true, gost3410.CurveIdGostR34102001CryptoProAParamSet())
+	testRoundtrip(
+		t, true,
+		gost3410.CurveIdGostR34102001CryptoProAParamSet(),
+		gost3410.CurveIdGostR34102001CryptoProXchAParamSet(),
+	)
+}
+
+func Test2012(t *testing.T) {
+	testRoundtrip(t, false, gost3410.CurveIdtc26gost34102012256paramSetA())
+	testRoundtrip(
+		t, false,
+		gost3410.CurveIdtc26gost34102012512paramSetA(),
+		gost3410.CurveIdtc26gost34102012512paramSetB(),
+	)
+}
+
+func TestEmptyPIN(t *testing.T) {
+	prv, err := gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012256paramSetB(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err = (&Container{Primary: prv}).Write(rand.Reader, dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Open(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Primary.Key.Cmp(prv.Key) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestFingerprintMismatch(t *testing.T) {
+	c := gost3410.CurveIdtc26gost34102012256paramSetB()
+	prv0, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prv1, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir0, dir1 := t.TempDir(), t.TempDir()
+	if err = (&Container{Primary: prv0}).Write(rand.Reader, dir0, "pin"); err != nil {
+		t.Fatal(err)
+	}
+	if err = (&Container{Primary: prv1}).Write(rand.Reader, dir1, "pin"); err != nil {
+		t.Fatal(err)
+	}
+	// Substitute another key protected with the same PIN
+	for _, f := range []string{MasksFile, PrimaryFile} {
+		data, err := os.ReadFile(filepath.Join(dir1, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(filepath.Join(dir0, f), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = Open(dir0, "pin"); err == nil {
+		t.Fatal("substituted key accepted")
+	}
+}
@@ -0,0 +1,72 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package cryptopro
+
+import (
+	"hash"
+)
+
+// Initial value of PIN key derivation iterations.
+var kdfInit = []byte("DENEFH028.760246785.IUEFHWUIO.EF")
+
+// Derive key encryption key from the PIN and masks.key salt. PIN's
+// characters are expanded to 4 bytes each. Hash is GOST R 34.11-94 for
+// 34.10-2001 containers and Streebog-256 for 34.10-2012 ones.
+func deriveKEK(h func() hash.Hash, pin string, salt []byte) []byte {
+	pin4 := make([]byte, 4*len(pin))
+	for i, c := range []byte(pin) {
+		pin4[4*i] = c
+	}
+	hsh := h()
+	hsh.Write(salt)
+	hsh.Write(pin4)
+	digest := hsh.Sum(nil)
+	iterations := 2
+	if len(pin) > 0 {
+		iterations = 2000
+	}
+	current := append([]byte{}, kdfInit...)
+	m36 := make([]byte, len(current))
+	m5c := make([]byte, len(current))
+	pads := func() {
+		for j := range current {
+			m36[j] = current[j] ^ 0x36
+			m5c[j] = current[j] ^ 0x5C
+		}
+	}
+	var tmp []byte
+	for i := 0; i < iterations; i++ {
+		pads()
+		hsh.Reset()
+		hsh.Write(m36)
+		hsh.Write(digest)
+		tmp = hsh.Sum(tmp[:0])
+		hsh.Reset()
+		hsh.Write(m5c)
+		hsh.Write(tmp)
+		current = hsh.Sum(current[:0])
+	}
+	pads()
+	hsh.Reset()
+	hsh.Write(m36)
+	hsh.Write(salt)
+	hsh.Write(pin4)
+	tmp = hsh.Sum(tmp[:0])
+	hsh.Reset()
+	hsh.Write(m5c)
+	hsh.Write(tmp)
+	return hsh.Sum(nil)
+}
@@ -0,0 +1,304 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// DANE TLSA resource records (RFC 6698, RFC 7671) creation and
+// verification against certificate chains, including GOST ones.
+package dane
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+// Certificate usages.
+const (
+	UsagePKIXTA = 0
+	UsagePKIXEE = 1
+	UsageDANETA = 2
+	UsageDANEEE = 3
+)
+
+// Selectors.
+const (
+	SelectorCert = 0
+	SelectorSPKI = 1
+)
+
+// Matching types. Streebog has no IANA assigned matching type, so
+// GOST-only deployments have to agree on their own numbers, like
+// MatchingPrivate.
+const (
+	MatchingExact   = 0
+	MatchingSHA256  = 1
+	MatchingSHA512  = 2
+	MatchingPrivate = 255
+)
+
+var ErrNoMatch = errors.New("gogost/dane: no certificate matches TLSA record")
+
+// Digest functions of non-exact matching types.
+type Hashes map[uint8]func() hash.Hash
+
+// SHA-256 and SHA-512 matching types of RFC 6698.
+func StandardHashes() Hashes {
+	return Hashes{MatchingSHA256: sha256.New, MatchingSHA512: sha512.New}
+}
+
+// Standard hashes with Streebog-256/512 under the given matching types.
+// Zero number leaves the corresponding Streebog out.
+func StreebogHashes(mt256, mt512 uint8) Hashes {
+	hs := StandardHashes()
+	if mt256 != MatchingExact {
+		hs[mt256] = gost34112012256.New
+	}
+	if mt512 != MatchingExact {
+		hs[mt512] = gost34112012512.New
+	}
+	return hs
+}
+
+type TLSA struct {
+	Usage        uint8
+	Selector     uint8
+	MatchingType uint8
+	Data         []byte
+}
+
+// Certificate association data of the certificate.
+func Association(cert *x509.Certificate, selector, matchingType uint8, hs Hashes) ([]byte, error) {
+	var data []byte
+	switch selector {
+	case SelectorCert:
+		data = cert.Raw
+	case SelectorSPKI:
+		data = cert.RawSubjectPublicKeyInfo
+	default:
+		return nil, fmt.Errorf("gogost/dane: unknown selector %d", selector)
+	}
+	if matchingType == MatchingExact {
+		return data, nil
+	}
+	newHash := hs[matchingType]
+	if newHash == nil {
+		return nil, fmt.Errorf("gogost/dane: unknown matching type %d", matchingType)
+	}
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+func NewTLSA(cert *x509.Certificate, usage, selector, matchingType uint8, hs Hashes) (*TLSA, error) {
+	if usage > UsageDANEEE {
+		return nil, fmt.Errorf("gogost/dane: unknown usage %d", usage)
+	}
+	data, err := Association(cert, selector, matchingType, hs)
+	if err != nil {
+		return nil, err
+	}
+	return &TLSA{
+		Usage:        usage,
+		Selector:     selector,
+		MatchingType: matchingType,
+		Data:         data,
+	}, nil
+}
+
+// Record's RDATA presentation format: "3 1 1 ABCD...".
+func (t *TLSA) String() string {
+	return fmt.Sprintf(
+		"%d %d %d %s", t.Usage, t.Selector, t.MatchingType,
+		strings.ToUpper(hex.EncodeToString(t.Data)),
+	)
+}
+
+// Parse TLSA record in presentation format. Either bare RDATA, or the
+// whole resource record line with owner name, TTL and class is
+// accepted. Association data may be split with whitespaces and
+// parentheses.
+func ParseTLSA(s string) (*TLSA, error) {
+	s = strings.NewReplacer("(", " ", ")", " ").Replace(s)
+	if i := strings.IndexByte(s, ';'); i != -1 {
+		s = s[:i]
+	}
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if strings.EqualFold(f, "TLSA") {
+			fields = fields[i+1:]
+			break
+		}
+	}
+	if len(fields) < 4 {
+		return nil, errors.New("gogost/dane: too short TLSA record")
+	}
+	var nums [3]uint8
+	for i := range nums {
+		n, err := strconv.ParseUint(fields[i], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("gogost/dane: invalid TLSA record: %w", err)
+		}
+		nums[i] = uint8(n)
+	}
+	data, err := hex.DecodeString(strings.Join(fields[3:], ""))
+	if err != nil {
+		return nil, fmt.Errorf("gogost/dane: invalid TLSA record: %w", err)
+	}
+	return &TLSA{
+		Usage:        nums[0],
+		Selector:     nums[1],
+		MatchingType: nums[2],
+		Data:         data,
+	}, nil
+}
+
+// Does certificate match the record's association data (usage is
+// not taken into account).
+func (t *TLSA) Match(cert *x509.Certificate, hs Hashes) (bool, error) {
+	data, err := Association(cert, t.Selector, t.MatchingType, hs)
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(data, t.Data) == 1, nil
+}
+
+type VerifyOptions struct {
+	// PKIX trust anchors for PKIX-TA and PKIX-EE usages. System ones are
+	// used for non-GOST chains if empty.
+	Roots []*x509.Certificate
+
+	// Time to check validity periods at, current one by default.
+	CurrentTime time.Time
+
+	// If set, end entity certificate must be valid for the host name.
+	// It is not checked with DANE-EE usage.
+	DNSName string
+
+	// Digest functions, StandardHashes by default.
+	Hashes Hashes
+}
+
+// Build PKIX chains from the end entity certificate to the roots, with
+// x509gost for GOST certificates and the stock crypto/x509 otherwise.
+func pkixVerify(
+	chain, roots []*x509.Certificate,
+	opts *VerifyOptions,
+) ([][]*x509.Certificate, error) {
+	if _, _, err := x509gost.PublicKey(chain[0]); err == nil {
+		rootsPool, inters := x509gost.NewCertPool(), x509gost.NewCertPool()
+		for _, c := range roots {
+			rootsPool.AddCert(c)
+		}
+		for _, c := range chain[1:] {
+			inters.AddCert(c)
+		}
+		return x509gost.Verify(chain[0], x509gost.VerifyOptions{
+			Roots:         rootsPool,
+			Intermediates: inters,
+			CurrentTime:   opts.CurrentTime,
+			DNSName:       opts.DNSName,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+	}
+	xopts := x509.VerifyOptions{
+		Intermediates: x509.NewCertPool(),
+		CurrentTime:   opts.CurrentTime,
+		DNSName:       opts.DNSName,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if len(roots) > 0 {
+		xopts.Roots = x509.NewCertPool()
+		for _, c := range roots {
+			xopts.Roots.AddCert(c)
+		}
+	}
+	for _, c := range chain[1:] {
+		xopts.Intermediates.AddCert(c)
+	}
+	return chain[0].Verify(xopts)
+}
+
+// Verify that the chain, presented by the server and starting with
+// the end entity certificate, satisfies the TLSA record:
+//
+//   - DANE-EE: end entity certificate matches;
+//   - PKIX-EE: it also passes PKIX validation against opts.Roots;
+//   - DANE-TA: one of the chain's certificates matches and the end
+//     entity passes PKIX validation with it as the sole trust anchor;
+//   - PKIX-TA: one of the PKIX validated chain's CA certificates matches.
+func Verify(t *TLSA, chain []*x509.Certificate, opts VerifyOptions) error {
+	if len(chain) == 0 {
+		return errors.New("gogost/dane: empty chain")
+	}
+	if opts.Hashes == nil {
+		opts.Hashes = StandardHashes()
+	}
+	switch t.Usage {
+	case UsageDANEEE, UsagePKIXEE:
+		ok, err := t.Match(chain[0], opts.Hashes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNoMatch
+		}
+		if t.Usage == UsagePKIXEE {
+			_, err = pkixVerify(chain, opts.Roots, &opts)
+		}
+		return err
+	case UsageDANETA:
+		for _, ta := range chain {
+			ok, err := t.Match(ta, opts.Hashes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			_, err = pkixVerify(chain, []*x509.Certificate{ta}, &opts)
+			return err
+		}
+		return ErrNoMatch
+	case UsagePKIXTA:
+		chains, err := pkixVerify(chain, opts.Roots, &opts)
+		if err != nil {
+			return err
+		}
+		for _, c := range chains {
+			for _, ta := range c[1:] {
+				ok, err := t.Match(ta, opts.Hashes)
+				if err != nil {
+					return err
+				}
+				if ok {
+					return nil
+				}
+			}
+		}
+		return ErrNoMatch
+	}
+	return fmt.Errorf("gogost/dane: unknown usage %d", t.Usage)
+}
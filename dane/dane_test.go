@@ -0,0 +1,188 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dane
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/internal/testpki"
+)
+
+func issue(
+	t *testing.T,
+	cn string,
+	ca bool,
+	parent *x509.Certificate,
+	parentPrv *gost3410.PrivateKey,
+) (*x509.Certificate, *gost3410.PrivateKey) {
+	tmpl := x509.Certificate{
+		Subject:               pkix.Name{CommonName: cn},
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	if !ca {
+		tmpl.DNSNames = []string{cn}
+	}
+	return testpki.New(t, gost3410.CurveIdtc26gost34102012256paramSetA(), &tmpl, parent, parentPrv)
+}
+
+func TestAssociation(t *testing.T) {
+	cert, _ := issue(t, "example.com", false, nil, nil)
+	hs := StreebogHashes(MatchingPrivate, 0)
+	data, err := Association(cert, SelectorSPKI, MatchingSHA256, hs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	if !bytes.Equal(data, h[:]) {
+		t.FailNow()
+	}
+	if data, err = Association(cert, SelectorCert, MatchingExact, hs); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, cert.Raw) {
+		t.FailNow()
+	}
+	if data, err = Association(cert, SelectorCert, MatchingPrivate, hs); err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 32 {
+		t.FailNow()
+	}
+	if _, err = Association(cert, SelectorCert, 3, hs); err == nil {
+		t.Fatal("unknown matching type accepted")
+	}
+	hs = StreebogHashes(0, 3)
+	if data, err = Association(cert, SelectorSPKI, 3, hs); err != nil {
+		t.Fatal(err)
+	}
+	h512 := gost34112012512.New()
+	h512.Write(cert.RawSubjectPublicKeyInfo)
+	if !bytes.Equal(data, h512.Sum(nil)) {
+		t.FailNow()
+	}
+}
+
+func TestParse(t *testing.T) {
+	cert, _ := issue(t, "example.com", false, nil, nil)
+	tlsa, err := NewTLSA(cert, UsageDANEEE, SelectorSPKI, MatchingSHA512, nil)
+	if err == nil {
+		t.Fatal("nil hashes accepted")
+	}
+	tlsa, err = NewTLSA(cert, UsageDANEEE, SelectorSPKI, MatchingSHA512, StandardHashes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := tlsa.String()
+	for _, rr := range []string{
+		s,
+		"_443._tcp.example.com. 3600 IN TLSA " + s + " ; comment",
+		"_443._tcp.example.com. IN TLSA ( " + s[:70] + "\n" + s[70:] + " )",
+	} {
+		got, err := ParseTLSA(rr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != s {
+			t.Fatal("differs", rr)
+		}
+	}
+	for _, rr := range []string{"3 1 1", "3 1 256 00", "3 1 1 0g"} {
+		if _, err = ParseTLSA(rr); err == nil {
+			t.Fatal("accepted", rr)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	root, rootPrv := issue(t, "Root", true, nil, nil)
+	inter, interPrv := issue(t, "Intermediate", true, root, rootPrv)
+	leaf, _ := issue(t, "example.com", false, inter, interPrv)
+	other, _ := issue(t, "example.com", false, nil, nil)
+	chain := []*x509.Certificate{leaf, inter}
+	hs := StreebogHashes(MatchingPrivate, 0)
+	opts := VerifyOptions{
+		Roots:   []*x509.Certificate{root},
+		DNSName: "example.com",
+		Hashes:  hs,
+	}
+	for _, tc := range []struct {
+		usage uint8
+		cert  *x509.Certificate
+		ok    bool
+	}{
+		{UsageDANEEE, leaf, true},
+		{UsageDANEEE, inter, false},
+		{UsagePKIXEE, leaf, true},
+		{UsageDANETA, inter, true},
+		{UsageDANETA, other, false},
+		{UsagePKIXTA, root, true},
+		{UsagePKIXTA, inter, true},
+		{UsagePKIXTA, leaf, false},
+	} {
+		for _, selector := range []uint8{SelectorCert, SelectorSPKI} {
+			for _, mt := range []uint8{MatchingExact, MatchingSHA256, MatchingSHA512, MatchingPrivate} {
+				tlsa, err := NewTLSA(tc.cert, tc.usage, selector, mt, hs)
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = Verify(tlsa, chain, opts)
+				if tc.ok && err != nil {
+					t.Fatal(tc.usage, tc.cert.Subject, selector, mt, err)
+				}
+				if !tc.ok && err == nil {
+					t.Fatal("accepted", tc.usage, tc.cert.Subject, selector, mt)
+				}
+			}
+		}
+	}
+
+	// PKIX usages fail without the trust anchor
+	for usage, cert := range map[uint8]*x509.Certificate{
+		UsagePKIXEE: leaf,
+		UsagePKIXTA: inter,
+	} {
+		r, err := NewTLSA(cert, usage, SelectorSPKI, MatchingSHA256, hs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Verify(r, chain, VerifyOptions{DNSName: "example.com"}) == nil {
+			t.Fatal("untrusted chain accepted", usage)
+		}
+	}
+
+	// Name is checked for DANE-TA, but not for DANE-EE
+	opts.DNSName = "example.net"
+	r, err := NewTLSA(inter, UsageDANETA, SelectorSPKI, MatchingSHA256, hs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if Verify(r, chain, opts) == nil {
+		t.Fatal("wrong name accepted")
+	}
+	if r, err = NewTLSA(leaf, UsageDANEEE, SelectorSPKI, MatchingSHA256, hs); err != nil {
+		t.Fatal(err)
+	}
+	if err = Verify(r, chain, opts); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,304 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// DNSSEC ECC-GOST algorithm (RFC 5933): DNSKEY, DS and RRSIG records
+// with GOST R 34.10-2001 signatures and GOST R 34.11-94 digests.
+package dnssec
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost341194"
+)
+
+const (
+	AlgorithmECCGOST = 12
+
+	DigestSHA256 = 2
+	DigestGOST94 = 3
+	DigestSHA384 = 4
+
+	ProtocolDNSSEC = 3
+
+	FlagZone = 0x0100
+	FlagSEP  = 0x0001
+
+	ClassINET = 1
+
+	TypeDS     = 43
+	TypeRRSIG  = 46
+	TypeDNSKEY = 48
+)
+
+// GOST R 34.11-94 hash with CryptoPro parameters, as RFC 5933 requires.
+func NewHash() hash.Hash {
+	return gost341194.New(&gost28147.SboxIdGostR341194CryptoProParamSet)
+}
+
+// The only curve allowed by RFC 5933.
+func Curve() *gost3410.Curve {
+	return gost3410.CurveIdGostR34102001CryptoProAParamSet()
+}
+
+// Convert domain name in presentation format to the canonical wire
+// format (RFC 4034 6.2): uncompressed, with lowercased ASCII letters.
+// Name is always treated as the absolute one. \DDD and \X escapes are
+// supported.
+func CanonicalName(name string) ([]byte, error) {
+	if name == "." || name == "" {
+		return []byte{0}, nil
+	}
+	name = strings.TrimSuffix(name, ".")
+	var wire []byte
+	var label []byte
+	flush := func() error {
+		if len(label) == 0 {
+			return fmt.Errorf("gogost/dnssec: empty label in %q", name)
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("gogost/dnssec: too long label in %q", name)
+		}
+		wire = append(wire, byte(len(label)))
+		wire = append(wire, label...)
+		label = label[:0]
+		return nil
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch c {
+		case '.':
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		case '\\':
+			i++
+			if i == len(name) {
+				return nil, fmt.Errorf("gogost/dnssec: bad escape in %q", name)
+			}
+			c = name[i]
+			if c >= '0' && c <= '9' {
+				if i+2 >= len(name) {
+					return nil, fmt.Errorf("gogost/dnssec: bad escape in %q", name)
+				}
+				n := 0
+				for _, d := range name[i : i+3] {
+					if d < '0' || d > '9' {
+						return nil, fmt.Errorf("gogost/dnssec: bad escape in %q", name)
+					}
+					n = n*10 + int(d-'0')
+				}
+				if n > 255 {
+					return nil, fmt.Errorf("gogost/dnssec: bad escape in %q", name)
+				}
+				c = byte(n)
+				i += 2
+			}
+		}
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		label = append(label, c)
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	wire = append(wire, 0)
+	if len(wire) > 255 {
+		return nil, fmt.Errorf("gogost/dnssec: too long name %q", name)
+	}
+	return wire, nil
+}
+
+// Split wire format name to labels, without the root one.
+func labels(wire []byte) (ls [][]byte) {
+	for len(wire) > 0 && wire[0] != 0 {
+		ls = append(ls, wire[1:1+wire[0]])
+		wire = wire[1+wire[0]:]
+	}
+	return
+}
+
+// Labels field value of RRSIG: number of labels without the root
+// and leading wildcard ones.
+func LabelsCount(wire []byte) int {
+	ls := labels(wire)
+	if len(ls) > 0 && bytes.Equal(ls[0], []byte("*")) {
+		return len(ls) - 1
+	}
+	return len(ls)
+}
+
+type DNSKEY struct {
+	Flags     uint16
+	Protocol  uint8
+	Algorithm uint8
+	PublicKey []byte
+}
+
+// Create ECC-GOST DNSKEY with 64-byte little-endian X||Y public key,
+// the same as in RFC 4491 certificates.
+func NewDNSKEY(pub *gost3410.PublicKey, flags uint16) (*DNSKEY, error) {
+	if !pub.C.Equal(Curve()) {
+		return nil, errors.New("gogost/dnssec: only CryptoPro-A curve is allowed")
+	}
+	return &DNSKEY{
+		Flags:     flags,
+		Protocol:  ProtocolDNSSEC,
+		Algorithm: AlgorithmECCGOST,
+		PublicKey: pub.RawLE(),
+	}, nil
+}
+
+func (k *DNSKEY) Marshal() []byte {
+	rdata := make([]byte, 4, 4+len(k.PublicKey))
+	binary.BigEndian.PutUint16(rdata, k.Flags)
+	rdata[2] = k.Protocol
+	rdata[3] = k.Algorithm
+	return append(rdata, k.PublicKey...)
+}
+
+func ParseDNSKEY(rdata []byte) (*DNSKEY, error) {
+	if len(rdata) < 4 {
+		return nil, errors.New("gogost/dnssec: too short DNSKEY")
+	}
+	return &DNSKEY{
+		Flags:     binary.BigEndian.Uint16(rdata),
+		Protocol:  rdata[2],
+		Algorithm: rdata[3],
+		PublicKey: append([]byte{}, rdata[4:]...),
+	}, nil
+}
+
+// Key tag, as RFC 4034 appendix B calculates it.
+func (k *DNSKEY) KeyTag() uint16 {
+	var ac uint32
+	for i, b := range k.Marshal() {
+		if i&1 == 0 {
+			ac += uint32(b) << 8
+		} else {
+			ac += uint32(b)
+		}
+	}
+	ac += ac >> 16
+	return uint16(ac)
+}
+
+func (k *DNSKEY) GOSTPublicKey() (*gost3410.PublicKey, error) {
+	if k.Algorithm != AlgorithmECCGOST {
+		return nil, fmt.Errorf("gogost/dnssec: unsupported algorithm %d", k.Algorithm)
+	}
+	return gost3410.NewPublicKeyLE(Curve(), k.PublicKey)
+}
+
+// Record's RDATA presentation format: "257 3 12 base64".
+func (k *DNSKEY) String() string {
+	return fmt.Sprintf(
+		"%d %d %d %s", k.Flags, k.Protocol, k.Algorithm,
+		base64.StdEncoding.EncodeToString(k.PublicKey),
+	)
+}
+
+// Resource record of the key with the given owner name.
+func (k *DNSKEY) RR(owner string, ttl uint32) RR {
+	return RR{Name: owner, Type: TypeDNSKEY, Class: ClassINET, TTL: ttl, Data: k.Marshal()}
+}
+
+type DS struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     []byte
+}
+
+// Create DS record for the key owned by the zone. GOST R 34.11-94
+// (RFC 5933), SHA-256 and SHA-384 digests are supported.
+func NewDS(owner string, key *DNSKEY, digestType uint8) (*DS, error) {
+	var h hash.Hash
+	switch digestType {
+	case DigestSHA256:
+		h = sha256.New()
+	case DigestGOST94:
+		h = NewHash()
+	case DigestSHA384:
+		h = sha512.New384()
+	default:
+		return nil, fmt.Errorf("gogost/dnssec: unsupported digest type %d", digestType)
+	}
+	name, err := CanonicalName(owner)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(name)
+	h.Write(key.Marshal())
+	return &DS{
+		KeyTag:     key.KeyTag(),
+		Algorithm:  key.Algorithm,
+		DigestType: digestType,
+		Digest:     h.Sum(nil),
+	}, nil
+}
+
+func (ds *DS) Marshal() []byte {
+	rdata := make([]byte, 4, 4+len(ds.Digest))
+	binary.BigEndian.PutUint16(rdata, ds.KeyTag)
+	rdata[2] = ds.Algorithm
+	rdata[3] = ds.DigestType
+	return append(rdata, ds.Digest...)
+}
+
+func ParseDS(rdata []byte) (*DS, error) {
+	if len(rdata) < 4 {
+		return nil, errors.New("gogost/dnssec: too short DS")
+	}
+	return &DS{
+		KeyTag:     binary.BigEndian.Uint16(rdata),
+		Algorithm:  rdata[2],
+		DigestType: rdata[3],
+		Digest:     append([]byte{}, rdata[4:]...),
+	}, nil
+}
+
+// Does DS record correspond to the key owned by the zone.
+func (ds *DS) Match(owner string, key *DNSKEY) (bool, error) {
+	our, err := NewDS(owner, key, ds.DigestType)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(our.Marshal(), ds.Marshal()), nil
+}
+
+// Record's RDATA presentation format: "60485 12 3 HEX".
+func (ds *DS) String() string {
+	return fmt.Sprintf(
+		"%d %d %d %s", ds.KeyTag, ds.Algorithm, ds.DigestType,
+		strings.ToUpper(hex.EncodeToString(ds.Digest)),
+	)
+}
+
+func (ds *DS) RR(owner string, ttl uint32) RR {
+	return RR{Name: owner, Type: TypeDS, Class: ClassINET, TTL: ttl, Data: ds.Marshal()}
+}
@@ -0,0 +1,266 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dnssec
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func TestCanonicalName(t *testing.T) {
+	for name, wire := range map[string]string{
+		".":             "\x00",
+		"Example.COM":   "\x07example\x03com\x00",
+		"example.com.":  "\x07example\x03com\x00",
+		"*.Example.com": "\x01*\x07example\x03com\x00",
+		`a\.b.com.`:     "\x03a.b\x03com\x00",
+		`\065\032b.com`: "\x03a b\x03com\x00",
+		`\\.com`:        "\x01\\\x03com\x00",
+	} {
+		got, err := CanonicalName(name)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if string(got) != wire {
+			t.Fatalf("%s: %q", name, got)
+		}
+	}
+	for _, name := range []string{"a..com", `a\`, `a\25`, `a\256.com`} {
+		if _, err := CanonicalName(name); err == nil {
+			t.Fatal("accepted", name)
+		}
+	}
+	wire, _ := CanonicalName("*.example.com")
+	if LabelsCount(wire) != 2 {
+		t.FailNow()
+	}
+}
+
+func TestKeyTag(t *testing.T) {
+	key := DNSKEY{Flags: FlagZone, Protocol: ProtocolDNSSEC, Algorithm: 12, PublicKey: []byte{0xAB}}
+	if key.KeyTag() != 0x0100+0x030C+0xAB00 {
+		t.FailNow()
+	}
+	key.PublicKey = []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+	// 0x0100+0x030C+0xFFFF+0xFFFF+0xFF00 = 0x3030A, folded
+	if key.KeyTag() != 0x030A+0x0003 {
+		t.FailNow()
+	}
+}
+
+func genKey(t *testing.T, flags uint16) (*gost3410.PrivateKey, *DNSKEY) {
+	prv, err := gost3410.GenPrivateKey(Curve(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := prv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := NewDNSKEY(pub, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prv, key
+}
+
+func TestDNSKEY(t *testing.T) {
+	prv, key := genKey(t, FlagZone|FlagSEP)
+	if len(key.PublicKey) != 64 {
+		t.FailNow()
+	}
+	parsed, err := ParseDNSKEY(key.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.KeyTag() != key.KeyTag() || parsed.String() != key.String() {
+		t.FailNow()
+	}
+	pub, err := parsed.GOSTPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pub.Equal(prv.Public()) {
+		t.FailNow()
+	}
+
+	other, err := gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012256paramSetA(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPub, err := other.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewDNSKEY(otherPub, FlagZone); err == nil {
+		t.Fatal("non CryptoPro-A curve accepted")
+	}
+}
+
+func TestDS(t *testing.T) {
+	_, key := genKey(t, FlagZone|FlagSEP)
+	ds, err := NewDS("Example.NET.", key, DigestGOST94)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHash()
+	h.Write([]byte("\x07example\x03net\x00"))
+	h.Write(key.Marshal())
+	if !bytes.Equal(ds.Digest, h.Sum(nil)) || ds.KeyTag != key.KeyTag() || ds.Algorithm != 12 {
+		t.FailNow()
+	}
+	parsed, err := ParseDS(ds.Marshal())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, owner := range []string{"example.net", "EXAMPLE.net."} {
+		if ok, err := parsed.Match(owner, key); err != nil || !ok {
+			t.Fatal("does not match", owner, err)
+		}
+	}
+	if ok, _ := parsed.Match("example.com", key); ok {
+		t.Fatal("another owner matches")
+	}
+	for _, dt := range []uint8{DigestSHA256, DigestSHA384} {
+		if _, err = NewDS("example.net", key, dt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = NewDS("example.net", key, 1); err == nil {
+		t.Fatal("SHA-1 accepted")
+	}
+}
+
+// RFC 5933 4.1 example DNSKEY and DS records.
+func TestRFC5933Example(t *testing.T) {
+	pub, _ := base64.StdEncoding.DecodeString("" +
+		"LMgXRHzSbIJGn6i16K+sDjaDf/k1o9DbxScO" +
+		"gEYqYS/rlh2Mf+BRAY3QHPbwoPh2fkDKBroF" +
+		"SRGR7ZYcx+YIQw==")
+	key, err := ParseDNSKEY(append([]byte{0x01, 0x01, 3, 12}, pub...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.KeyTag() != 40692 {
+		t.Fatal("key tag", key.KeyTag())
+	}
+	gostPub, err := key.GOSTPublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gostPub.C.Contains(gostPub.X, gostPub.Y) {
+		t.Fatal("public key is not on the curve")
+	}
+	ds, err := NewDS("example.net.", key, DigestGOST94)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds.String() != "40692 12 3 "+
+		"22261A8B0E0D799183E35E24E2AD6BB58533CBA7E3B14D659E9CA09B"+
+		"2071398F" {
+		t.Fatal(ds)
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	prv, key := genKey(t, FlagZone)
+	inception := time.Now().Add(-time.Hour)
+	expiration := time.Now().Add(time.Hour)
+	rrset := []RR{
+		{Name: "www.example.net.", Type: 1, Class: ClassINET, TTL: 3600, Data: []byte{192, 0, 2, 2}},
+		{Name: "WWW.example.net", Type: 1, Class: ClassINET, TTL: 3600, Data: []byte{192, 0, 2, 1}},
+	}
+	sig, err := Sign(rand.Reader, prv, key, "example.net.", rrset, inception, expiration)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.Labels != 3 || sig.TypeCovered != 1 || !sig.ValidAt(time.Now()) {
+		t.FailNow()
+	}
+	rdata, err := sig.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig, err = ParseRRSIG(rdata); err != nil {
+		t.Fatal(err)
+	}
+	if sig.SignerName != "example.net." {
+		t.FailNow()
+	}
+
+	// Order, duplicates and received TTLs are irrelevant
+	reordered := []RR{rrset[1], rrset[0], rrset[1]}
+	reordered[0].TTL = 10
+	if err = Verify(sig, key, reordered); err != nil {
+		t.Fatal(err)
+	}
+	if err = Verify(sig, key, rrset[:1]); err != ErrSignature {
+		t.Fatal("partial RRset accepted", err)
+	}
+	tampered := *sig
+	tampered.OrigTTL++
+	if err = Verify(&tampered, key, rrset); err != ErrSignature {
+		t.Fatal("tampered RRSIG accepted", err)
+	}
+	_, otherKey := genKey(t, FlagZone)
+	if err = Verify(sig, otherKey, rrset); err == nil {
+		t.Fatal("another key accepted")
+	}
+	if _, err = Sign(rand.Reader, prv, otherKey, "example.net.", rrset, inception, expiration); err == nil {
+		t.Fatal("mismatched private key accepted")
+	}
+	mixed := []RR{rrset[0], {Name: "example.net", Type: 1, Class: ClassINET, Data: []byte{1, 2, 3, 4}}}
+	if _, err = Sign(rand.Reader, prv, key, "example.net.", mixed, inception, expiration); err == nil {
+		t.Fatal("inconsistent RRset accepted")
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	prv, key := genKey(t, FlagZone)
+	wild := []RR{{Name: "*.example.net.", Type: 16, Class: ClassINET, TTL: 60, Data: []byte("\x03txt")}}
+	sig, err := Sign(rand.Reader, prv, key, "example.net.", wild, time.Now(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.Labels != 2 {
+		t.FailNow()
+	}
+	expanded := []RR{wild[0]}
+	expanded[0].Name = "a.b.example.net."
+	if err = Verify(sig, key, expanded); err != nil {
+		t.Fatal(err)
+	}
+	expanded[0].Name = "net."
+	if err = Verify(sig, key, expanded); err == nil {
+		t.Fatal("too short owner accepted")
+	}
+}
+
+func TestValidAt(t *testing.T) {
+	// Validity period spanning the 32-bit wrap-around
+	sig := RRSIG{Inception: 0xFFFFFF00, Expiration: 0x100}
+	if !sig.ValidAt(time.Unix(1<<32, 0)) {
+		t.FailNow()
+	}
+	if sig.ValidAt(time.Unix(1<<32+0x200, 0)) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,305 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package dnssec
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+var (
+	ErrSignature = errors.New("gogost/dnssec: invalid signature")
+	ErrKeyTag    = errors.New("gogost/dnssec: key tag mismatch")
+)
+
+// Resource record. Data is RDATA in wire format. Domain names inside
+// it must already be in canonical form (RFC 4034 6.2) for signing and
+// verification.
+type RR struct {
+	Name  string
+	Type  uint16
+	Class uint16
+	TTL   uint32
+	Data  []byte
+}
+
+type RRSIG struct {
+	TypeCovered uint16
+	Algorithm   uint8
+	Labels      uint8
+	OrigTTL     uint32
+	Expiration  uint32
+	Inception   uint32
+	KeyTag      uint16
+	SignerName  string
+	Signature   []byte
+}
+
+// RDATA without the signature field.
+func (sig *RRSIG) marshalHeader() ([]byte, error) {
+	signer, err := CanonicalName(sig.SignerName)
+	if err != nil {
+		return nil, err
+	}
+	rdata := make([]byte, 18, 18+len(signer)+len(sig.Signature))
+	binary.BigEndian.PutUint16(rdata[0:], sig.TypeCovered)
+	rdata[2] = sig.Algorithm
+	rdata[3] = sig.Labels
+	binary.BigEndian.PutUint32(rdata[4:], sig.OrigTTL)
+	binary.BigEndian.PutUint32(rdata[8:], sig.Expiration)
+	binary.BigEndian.PutUint32(rdata[12:], sig.Inception)
+	binary.BigEndian.PutUint16(rdata[16:], sig.KeyTag)
+	return append(rdata, signer...), nil
+}
+
+func (sig *RRSIG) Marshal() ([]byte, error) {
+	rdata, err := sig.marshalHeader()
+	if err != nil {
+		return nil, err
+	}
+	return append(rdata, sig.Signature...), nil
+}
+
+// Parse RRSIG RDATA. Signer's name is expected to be uncompressed.
+func ParseRRSIG(rdata []byte) (*RRSIG, error) {
+	if len(rdata) < 19 {
+		return nil, errors.New("gogost/dnssec: too short RRSIG")
+	}
+	sig := RRSIG{
+		TypeCovered: binary.BigEndian.Uint16(rdata[0:]),
+		Algorithm:   rdata[2],
+		Labels:      rdata[3],
+		OrigTTL:     binary.BigEndian.Uint32(rdata[4:]),
+		Expiration:  binary.BigEndian.Uint32(rdata[8:]),
+		Inception:   binary.BigEndian.Uint32(rdata[12:]),
+		KeyTag:      binary.BigEndian.Uint16(rdata[16:]),
+	}
+	rest := rdata[18:]
+	var ls []string
+	for {
+		if len(rest) == 0 || int(rest[0]) >= len(rest) || rest[0] > 63 {
+			return nil, errors.New("gogost/dnssec: invalid RRSIG signer's name")
+		}
+		if rest[0] == 0 {
+			rest = rest[1:]
+			break
+		}
+		ls = append(ls, escapeLabel(rest[1:1+rest[0]]))
+		rest = rest[1+rest[0]:]
+	}
+	sig.SignerName = strings.Join(ls, ".") + "."
+	sig.Signature = append([]byte{}, rest...)
+	return &sig, nil
+}
+
+func escapeLabel(label []byte) string {
+	var b strings.Builder
+	for _, c := range label {
+		switch {
+		case c == '.' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c <= ' ' || c >= 0x7F:
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// RRSIG time format of the presentation format.
+const TimeFormat = "20060102150405"
+
+// Record's RDATA presentation format. The covered type is shown in
+// RFC 3597 generic TYPE### form.
+func (sig *RRSIG) String() string {
+	return fmt.Sprintf(
+		"TYPE%d %d %d %d %s %s %d %s %s",
+		sig.TypeCovered, sig.Algorithm, sig.Labels, sig.OrigTTL,
+		time.Unix(int64(sig.Expiration), 0).UTC().Format(TimeFormat),
+		time.Unix(int64(sig.Inception), 0).UTC().Format(TimeFormat),
+		sig.KeyTag, sig.SignerName,
+		base64.StdEncoding.EncodeToString(sig.Signature),
+	)
+}
+
+// Is the time within the validity period of the signature, using
+// serial number arithmetic (RFC 4034 3.1.5).
+func (sig *RRSIG) ValidAt(t time.Time) bool {
+	now := uint32(t.Unix())
+	return int32(now-sig.Inception) >= 0 && int32(sig.Expiration-now) >= 0
+}
+
+// Data covered by the signature (RFC 4034 3.1.8.1): RRSIG RDATA without
+// the signature followed by the RRset in the canonical form and order.
+func (sig *RRSIG) signedData(rrset []RR) ([]byte, error) {
+	if len(rrset) == 0 {
+		return nil, errors.New("gogost/dnssec: empty RRset")
+	}
+	owner, err := CanonicalName(rrset[0].Name)
+	if err != nil {
+		return nil, err
+	}
+	for _, rr := range rrset[1:] {
+		name, err := CanonicalName(rr.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(name, owner) ||
+			rr.Type != rrset[0].Type ||
+			rr.Class != rrset[0].Class {
+			return nil, errors.New("gogost/dnssec: inconsistent RRset")
+		}
+	}
+	if rrset[0].Type != sig.TypeCovered {
+		return nil, errors.New("gogost/dnssec: RRset type differs from the covered one")
+	}
+	ls := labels(owner)
+	if int(sig.Labels) > LabelsCount(owner) {
+		return nil, errors.New("gogost/dnssec: too many labels in RRSIG")
+	}
+	if int(sig.Labels) < len(ls) {
+		// Wildcard expanded owner name
+		wild := []byte{1, '*'}
+		for _, l := range ls[len(ls)-int(sig.Labels):] {
+			wild = append(wild, byte(len(l)))
+			wild = append(wild, l...)
+		}
+		owner = append(wild, 0)
+	}
+	rdatas := make([][]byte, 0, len(rrset))
+	for _, rr := range rrset {
+		rdatas = append(rdatas, rr.Data)
+	}
+	sort.Slice(rdatas, func(i, j int) bool {
+		return bytes.Compare(rdatas[i], rdatas[j]) < 0
+	})
+	data, err := sig.marshalHeader()
+	if err != nil {
+		return nil, err
+	}
+	var hdr [10]byte
+	binary.BigEndian.PutUint16(hdr[0:], rrset[0].Type)
+	binary.BigEndian.PutUint16(hdr[2:], rrset[0].Class)
+	binary.BigEndian.PutUint32(hdr[4:], sig.OrigTTL)
+	for i, rdata := range rdatas {
+		if i > 0 && bytes.Equal(rdata, rdatas[i-1]) {
+			continue
+		}
+		if len(rdata) > 0xFFFF {
+			return nil, errors.New("gogost/dnssec: too long RDATA")
+		}
+		binary.BigEndian.PutUint16(hdr[8:], uint16(len(rdata)))
+		data = append(data, owner...)
+		data = append(data, hdr[:]...)
+		data = append(data, rdata...)
+	}
+	return data, nil
+}
+
+// Sign the RRset with the private key corresponding to the zone's
+// DNSKEY. Signature is s||r over the reversed GOST R 34.11-94 digest,
+// as RFC 4490 and RFC 5933 define.
+func Sign(
+	rand io.Reader,
+	prv *gost3410.PrivateKey,
+	key *DNSKEY,
+	signer string,
+	rrset []RR,
+	inception, expiration time.Time,
+) (*RRSIG, error) {
+	pub, err := prv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	our, err := NewDNSKEY(pub, key.Flags)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(our.Marshal(), key.Marshal()) {
+		return nil, errors.New("gogost/dnssec: private key does not match DNSKEY")
+	}
+	if len(rrset) == 0 {
+		return nil, errors.New("gogost/dnssec: empty RRset")
+	}
+	owner, err := CanonicalName(rrset[0].Name)
+	if err != nil {
+		return nil, err
+	}
+	sig := RRSIG{
+		TypeCovered: rrset[0].Type,
+		Algorithm:   AlgorithmECCGOST,
+		Labels:      uint8(LabelsCount(owner)),
+		OrigTTL:     rrset[0].TTL,
+		Expiration:  uint32(expiration.Unix()),
+		Inception:   uint32(inception.Unix()),
+		KeyTag:      key.KeyTag(),
+		SignerName:  signer,
+	}
+	data, err := sig.signedData(rrset)
+	if err != nil {
+		return nil, err
+	}
+	h := NewHash()
+	h.Write(data)
+	sig.Signature, err = (&gost3410.PrivateKeyReverseDigest{Prv: prv}).Sign(rand, h.Sum(nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
+
+// Verify RRset's signature with the DNSKEY. Validity period is not
+// checked, use ValidAt for that.
+func Verify(sig *RRSIG, key *DNSKEY, rrset []RR) error {
+	if sig.Algorithm != AlgorithmECCGOST || key.Algorithm != AlgorithmECCGOST {
+		return fmt.Errorf("gogost/dnssec: unsupported algorithm %d", sig.Algorithm)
+	}
+	if key.Protocol != ProtocolDNSSEC || key.Flags&FlagZone == 0 {
+		return errors.New("gogost/dnssec: DNSKEY is not a zone key")
+	}
+	if sig.KeyTag != key.KeyTag() {
+		return ErrKeyTag
+	}
+	pub, err := key.GOSTPublicKey()
+	if err != nil {
+		return err
+	}
+	data, err := sig.signedData(rrset)
+	if err != nil {
+		return err
+	}
+	h := NewHash()
+	h.Write(data)
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(h.Sum(nil), sig.Signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrSignature
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost28147
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+type cbcEncrypter struct {
+	c       *Cipher
+	n1      nv
+	n2      nv
+	meshing bool
+	count   int
+}
+
+// Create CBC mode encrypter. It is used by crypto/cipher.NewCBCEncrypter
+// and avoids per-block interface calls and conversions.
+func (c *Cipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCEncrypter: IV length must equal block size")
+	}
+	n1, n2 := block2nvs(iv)
+	return &cbcEncrypter{c: c, n1: n2, n2: n1}
+}
+
+// Create CBC mode encrypter with CryptoPro key meshing (RFC 4357): each
+// MeshingPeriod bytes the key is meshed and the previous ciphertext
+// block is encrypted with the new one.
+func (c *Cipher) NewCBCEncrypterMeshing(iv []byte) cipher.BlockMode {
+	e := c.clone().NewCBCEncrypter(iv).(*cbcEncrypter)
+	e.meshing = true
+	return e
+}
+
+func (e *cbcEncrypter) BlockSize() int {
+	return BlockSize
+}
+
+func (e *cbcEncrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var n1, n2 nv
+	for len(src) > 0 {
+		if e.meshing {
+			if e.count == MeshingPeriod {
+				e.c.meshKey()
+				e.n1, e.n2 = e.c.xcrypt(SeqEncrypt, e.n2, e.n1)
+				e.count = 0
+			}
+			e.count += BlockSize
+		}
+		n1, n2 = block2nvs(src)
+		// Previous block's output halves are swapped by nvs2block
+		e.n1, e.n2 = e.c.xcrypt(SeqEncrypt, n1^e.n2, n2^e.n1)
+		nvs2block(e.n1, e.n2, dst)
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
+}
+
+// Maximal number of blocks decrypted at once.
+const cbcBlocks = 32
+
+type cbcDecrypter struct {
+	c       *Cipher
+	iv      [BlockSize]byte
+	buf     [cbcBlocks * BlockSize]byte
+	meshing bool
+	count   int
+}
+
+// Create CBC mode decrypter. It is used by crypto/cipher.NewCBCDecrypter
+// and, unlike generic implementation, decrypts multiple blocks at once.
+func (c *Cipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCDecrypter: IV length must equal block size")
+	}
+	d := cbcDecrypter{c: c}
+	copy(d.iv[:], iv)
+	return &d
+}
+
+// Create CBC mode decrypter with CryptoPro key meshing (RFC 4357).
+func (c *Cipher) NewCBCDecrypterMeshing(iv []byte) cipher.BlockMode {
+	d := c.clone().NewCBCDecrypter(iv).(*cbcDecrypter)
+	d.meshing = true
+	return d
+}
+
+func (d *cbcDecrypter) BlockSize() int {
+	return BlockSize
+}
+
+func (d *cbcDecrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var n int
+	for len(src) > 0 {
+		n = len(src)
+		if n > len(d.buf) {
+			n = len(d.buf)
+		}
+		if d.meshing {
+			if d.count == MeshingPeriod {
+				d.c.meshKey()
+				d.c.Encrypt(d.iv[:], d.iv[:])
+				d.count = 0
+			}
+			if n > MeshingPeriod-d.count {
+				n = MeshingPeriod - d.count
+			}
+			d.count += n
+		}
+		// Ciphertext is saved, as dst and src may overlap
+		copy(d.buf[:n], src)
+		d.c.DecryptBlocks(dst[:n], src[:n])
+		subtle.XORBytes(dst[:BlockSize], dst[:BlockSize], d.iv[:])
+		subtle.XORBytes(dst[BlockSize:n], dst[BlockSize:n], d.buf[:n-BlockSize])
+		copy(d.iv[:], d.buf[n-BlockSize:n])
+		dst, src = dst[n:], src[n:]
+	}
+}
@@ -18,10 +18,14 @@ package gost28147
 import (
 	"bytes"
 	"crypto/cipher"
+	"crypto/rand"
 	"testing"
 	"testing/quick"
 )
 
+// Hide optimised modes, forcing crypto/cipher to use generic ones.
+type blockOnly struct{ cipher.Block }
+
 func TestCBCCrypter(t *testing.T) {
 	f := func(key [KeySize]byte, iv [BlockSize]byte, pt []byte) bool {
 		c := NewCipher(key[:], SboxDefault)
@@ -39,3 +43,45 @@ func TestCBCCrypter(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCBCGeneric(t *testing.T) {
+	f := func(key [KeySize]byte, iv [BlockSize]byte, pt []byte) bool {
+		pt = append(pt, make([]byte, cbcBlocks*BlockSize)...)
+		pt = pt[:len(pt)-len(pt)%BlockSize]
+		c := NewCipher(key[:], SboxDefault)
+		expected := make([]byte, len(pt))
+		cipher.NewCBCEncrypter(blockOnly{c}, iv[:]).CryptBlocks(expected, pt)
+		ct := make([]byte, len(pt))
+		e := cipher.NewCBCEncrypter(c, iv[:])
+		if _, ok := e.(*cbcEncrypter); !ok {
+			return false
+		}
+		e.CryptBlocks(ct, pt)
+		if !bytes.Equal(ct, expected) {
+			return false
+		}
+		d := cipher.NewCBCDecrypter(c, iv[:])
+		if _, ok := d.(*cbcDecrypter); !ok {
+			return false
+		}
+		d.CryptBlocks(ct[:BlockSize], ct[:BlockSize])
+		d.CryptBlocks(ct[BlockSize:], ct[BlockSize:])
+		return bytes.Equal(ct, pt)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkCBCDecrypt(b *testing.B) {
+	key := make([]byte, KeySize)
+	iv := make([]byte, BlockSize)
+	rand.Read(key)
+	buf := make([]byte, 1<<10)
+	d := cipher.NewCBCDecrypter(NewCipher(key, SboxDefault), iv)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.CryptBlocks(buf, buf)
+	}
+}
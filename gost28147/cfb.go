@@ -16,63 +16,98 @@
 package gost28147
 
 type CFBEncrypter struct {
-	c  *Cipher
-	iv []byte
+	c       *Cipher
+	iv      []byte
+	off     int
+	meshing bool
+	count   int
 }
 
 func (c *Cipher) NewCFBEncrypter(iv []byte) *CFBEncrypter {
 	if len(iv) != BlockSize {
 		panic("iv length is not equal to blocksize")
 	}
-	encrypter := CFBEncrypter{c: c, iv: make([]byte, BlockSize)}
+	encrypter := CFBEncrypter{c: c, iv: make([]byte, BlockSize), off: BlockSize}
 	copy(encrypter.iv, iv)
 	return &encrypter
 }
 
+// Create CFB encrypter with CryptoPro key meshing (RFC 4357): each
+// MeshingPeriod bytes the key is meshed and the feedback register is
+// encrypted with the new one.
+func (c *Cipher) NewCFBEncrypterMeshing(iv []byte) *CFBEncrypter {
+	encrypter := c.clone().NewCFBEncrypter(iv)
+	encrypter.meshing = true
+	return encrypter
+}
+
+// Produce the next gamma block from the feedback register.
+func cfbNext(c *Cipher, iv []byte, meshing bool, count *int) {
+	if meshing {
+		if *count == MeshingPeriod {
+			c.meshKey()
+			c.Encrypt(iv, iv)
+			*count = 0
+		}
+		*count += BlockSize
+	}
+	c.Encrypt(iv, iv)
+}
+
+// XOR the data with gamma. Data is not required to be multiple of the
+// block size: unused gamma is kept for the following call.
 func (c *CFBEncrypter) XORKeyStream(dst, src []byte) {
-	var n int
-	i := 0
-MainLoop:
-	for {
-		c.c.Encrypt(c.iv, c.iv)
-		for n = 0; n < BlockSize; n++ {
-			if i*BlockSize+n == len(src) {
-				break MainLoop
-			}
-			c.iv[n] ^= src[i*BlockSize+n]
-			dst[i*BlockSize+n] = c.iv[n]
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	for i := 0; i < len(src); i++ {
+		if c.off == BlockSize {
+			cfbNext(c.c, c.iv, c.meshing, &c.count)
+			c.off = 0
 		}
-		i++
+		c.iv[c.off] ^= src[i]
+		dst[i] = c.iv[c.off]
+		c.off++
 	}
 }
 
 type CFBDecrypter struct {
-	c  *Cipher
-	iv []byte
+	c       *Cipher
+	iv      []byte
+	off     int
+	meshing bool
+	count   int
 }
 
 func (c *Cipher) NewCFBDecrypter(iv []byte) *CFBDecrypter {
 	if len(iv) != BlockSize {
 		panic("iv length is not equal to blocksize")
 	}
-	decrypter := CFBDecrypter{c: c, iv: make([]byte, BlockSize)}
+	decrypter := CFBDecrypter{c: c, iv: make([]byte, BlockSize), off: BlockSize}
 	copy(decrypter.iv, iv)
 	return &decrypter
 }
 
+// Create CFB decrypter with CryptoPro key meshing (RFC 4357).
+func (c *Cipher) NewCFBDecrypterMeshing(iv []byte) *CFBDecrypter {
+	decrypter := c.clone().NewCFBDecrypter(iv)
+	decrypter.meshing = true
+	return decrypter
+}
+
 func (c *CFBDecrypter) XORKeyStream(dst, src []byte) {
-	var n int
-	i := 0
-MainLoop:
-	for {
-		c.c.Encrypt(c.iv, c.iv)
-		for n = 0; n < BlockSize; n++ {
-			if i*BlockSize+n == len(src) {
-				break MainLoop
-			}
-			dst[i*BlockSize+n] = c.iv[n] ^ src[i*BlockSize+n]
-			c.iv[n] = src[i*BlockSize+n]
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var b byte
+	for i := 0; i < len(src); i++ {
+		if c.off == BlockSize {
+			cfbNext(c.c, c.iv, c.meshing, &c.count)
+			c.off = 0
 		}
-		i++
+		b = src[i]
+		dst[i] = c.iv[c.off] ^ b
+		c.iv[c.off] = b
+		c.off++
 	}
 }
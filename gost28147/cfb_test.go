@@ -72,6 +72,37 @@ func TestCFBRandom(t *testing.T) {
 	}
 }
 
+// Data may be processed with chunks of arbitrary size: unused gamma is
+// kept between calls, giving the same result as the standard library's
+// CFB processing it at once.
+func TestCFBChunks(t *testing.T) {
+	f := func(key [KeySize]byte, iv [BlockSize]byte, pt []byte, chunks []uint8) bool {
+		c := NewCipher(key[:], SboxDefault)
+		expected := make([]byte, len(pt))
+		cipher.NewCFBEncrypter(c, iv[:]).XORKeyStream(expected, pt)
+		fe := c.NewCFBEncrypter(iv[:])
+		fd := c.NewCFBDecrypter(iv[:])
+		ct := make([]byte, len(pt))
+		pt2 := make([]byte, len(pt))
+		var n int
+		for off := 0; off < len(pt); off += n {
+			n = len(pt) - off
+			if len(chunks) > 0 {
+				if int(chunks[0]) < n {
+					n = int(chunks[0])
+				}
+				chunks = chunks[1:]
+			}
+			fe.XORKeyStream(ct[off:off+n], pt[off:off+n])
+			fd.XORKeyStream(pt2[off:off+n], ct[off:off+n])
+		}
+		return bytes.Equal(ct, expected) && bytes.Equal(pt2, pt)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCFBInterface(t *testing.T) {
 	var key [32]byte
 	var iv [8]byte
@@ -16,9 +16,11 @@
 package gost28147
 
 import (
+	"bytes"
 	"crypto/cipher"
 	"crypto/rand"
 	"testing"
+	"testing/quick"
 )
 
 func TestCipherInterface(t *testing.T) {
@@ -37,3 +39,36 @@ func BenchmarkCipher(b *testing.B) {
 		c.Encrypt(dst, src)
 	}
 }
+
+func TestBlocks(t *testing.T) {
+	f := func(key [KeySize]byte, data []byte) bool {
+		data = data[:len(data)-len(data)%BlockSize]
+		c := NewCipher(key[:], SboxDefault)
+		expected := make([]byte, len(data))
+		for i := 0; i < len(data); i += BlockSize {
+			c.Encrypt(expected[i:], data[i:])
+		}
+		got := make([]byte, len(data))
+		c.EncryptBlocks(got, data)
+		if !bytes.Equal(got, expected) {
+			return false
+		}
+		c.DecryptBlocks(got, got)
+		return bytes.Equal(got, data)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkCipherBlocks(b *testing.B) {
+	var key [KeySize]byte
+	rand.Read(key[:])
+	buf := make([]byte, 32*BlockSize)
+	c := NewCipher(key[:], SboxDefault)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.EncryptBlocks(buf, buf)
+	}
+}
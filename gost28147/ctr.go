@@ -15,10 +15,26 @@
 
 package gost28147
 
+import (
+	"crypto/subtle"
+)
+
+// Maximal number of keystream blocks generated at once.
+const ctrBlocks = 32
+
 type CTR struct {
-	c  *Cipher
-	n1 nv
-	n2 nv
+	c       *Cipher
+	c0      *Cipher
+	n10     nv
+	n20     nv
+	n1      nv
+	n2      nv
+	ks      [ctrBlocks * BlockSize]byte
+	ksOff   int
+	ksLen   int
+	meshing bool
+	legacy  bool
+	count   int
 }
 
 func (c *Cipher) NewCTR(iv []byte) *CTR {
@@ -27,30 +43,146 @@ func (c *Cipher) NewCTR(iv []byte) *CTR {
 	}
 	n1, n2 := block2nvs(iv)
 	n2, n1 = c.xcrypt(SeqEncrypt, n1, n2)
-	return &CTR{c, n1, n2}
+	return &CTR{c: c, c0: c, n10: n1, n20: n2, n1: n1, n2: n2}
 }
 
-func (c *CTR) XORKeyStream(dst, src []byte) {
-	var n1t nv
-	var n2t nv
-	block := make([]byte, BlockSize)
-	i := 0
-	var n int
-MainLoop:
-	for {
-		c.n1 += 0x01010101 // C2
-		c.n2 += 0x01010104 // C1
+// Create CTR with CryptoPro key meshing (RFC 4357): each MeshingPeriod
+// bytes the key is meshed and the counter is encrypted with the new one.
+// Each key depends on the previous one, so SetOffset on such stream
+// replays all meshings up to the offset and takes O(offset) time.
+func (c *Cipher) NewCTRMeshing(iv []byte) *CTR {
+	ctr := c.NewCTR(iv)
+	ctr.c = c.clone()
+	ctr.meshing = true
+	return ctr
+}
+
+// Create CTR with the counter increment of earlier gogost releases:
+// N4 wraps modulo 2^32 on overflow, instead of end-around carry, so the
+// keystream differs after that. Use it only to decrypt data encrypted by
+// them. SetOffset on such stream takes O(offset) time.
+func (c *Cipher) NewCTRLegacy(iv []byte) *CTR {
+	ctr := c.NewCTR(iv)
+	ctr.legacy = true
+	return ctr
+}
+
+const (
+	c1 = 0x01010104
+	c2 = 0x01010101
+)
+
+// Counter increment of GOST 28147-89 3.1: N3 is added with C2 modulo
+// 2^32, N4 with C1 modulo 2^32-1. The latter is done with end-around
+// carry, exactly as gost-engine's gost_cnt_next does, so 2^32-1 value
+// itself is kept and not reduced to zero.
+func (c *CTR) incr() {
+	c.n1 += c2
+	c.n2 += c1
+	if c.legacy {
 		if c.n2 >= 1<<32-1 {
 			c.n2 -= 1<<32 - 1
 		}
-		n1t, n2t = c.c.xcrypt(SeqEncrypt, c.n1, c.n2)
-		nvs2block(n1t, n2t, block)
-		for n = 0; n < BlockSize; n++ {
-			if i*BlockSize+n == len(src) {
-				break MainLoop
-			}
-			dst[i*BlockSize+n] = src[i*BlockSize+n] ^ block[n]
+		return
+	}
+	if c.n2 < c1 {
+		c.n2++
+	}
+}
+
+// Equivalent of blk incr() calls.
+func (c *CTR) advance(blk uint64) {
+	if blk == 0 {
+		return
+	}
+	if c.legacy {
+		for ; blk > 0; blk-- {
+			c.incr()
+		}
+		return
+	}
+	c.n1 += nv(blk * c2)
+	// Result of addition with end-around carry is in [1, 2^32-1] range
+	const m = 1<<32 - 1
+	c.n2 = nv((uint64(c.n2)+m-1+(blk%m)*c1%m)%m + 1)
+}
+
+func (c *CTR) mesh() {
+	c.c.meshKey()
+	c.n2, c.n1 = c.c.xcrypt(SeqEncrypt, c.n1, c.n2)
+	c.count = 0
+}
+
+// Set the keystream position to the given byte offset from the
+// beginning of the stream, as if that number of bytes was already
+// processed. It takes constant time, allowing random access decryption.
+// With key meshing it is O(offset) instead: one meshing, that is
+// decryption of four blocks and key schedule, per MeshingPeriod bytes.
+func (c *CTR) SetOffset(offset uint64) {
+	blk := offset / BlockSize
+	c.n1, c.n2 = c.n10, c.n20
+	if c.meshing {
+		*c.c = *c.c0
+		for ; blk >= MeshingPeriod/BlockSize; blk -= MeshingPeriod / BlockSize {
+			c.advance(MeshingPeriod / BlockSize)
+			c.mesh()
+		}
+		c.count = int(blk) * BlockSize
+	}
+	c.advance(blk)
+	c.ksOff, c.ksLen = 0, 0
+	if r := int(offset % BlockSize); r > 0 {
+		c.refill(1)
+		c.ksOff = r
+	}
+}
+
+func (c *CTR) refill(blocks int) {
+	if blocks > ctrBlocks {
+		blocks = ctrBlocks
+	}
+	if c.meshing {
+		if c.count == MeshingPeriod {
+			c.mesh()
+		}
+		if left := (MeshingPeriod - c.count) / BlockSize; blocks > left {
+			blocks = left
+		}
+		c.count += blocks * BlockSize
+	}
+	var n [2 * batchBlocks]nv
+	i := 0
+	for ; i+batchBlocks <= blocks; i += batchBlocks {
+		for j := 0; j < batchBlocks; j++ {
+			c.incr()
+			n[2*j], n[2*j+1] = c.n1, c.n2
+		}
+		c.c.xcrypt4(SeqEncrypt, &n)
+		for j := 0; j < batchBlocks; j++ {
+			nvs2block(n[2*j], n[2*j+1], c.ks[(i+j)*BlockSize:])
+		}
+	}
+	for ; i < blocks; i++ {
+		c.incr()
+		n[0], n[1] = c.c.xcrypt(SeqEncrypt, c.n1, c.n2)
+		nvs2block(n[0], n[1], c.ks[i*BlockSize:])
+	}
+	c.ksOff, c.ksLen = 0, blocks*BlockSize
+}
+
+// XOR the data with keystream. Unused keystream of the last block is
+// kept for the following call.
+func (c *CTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var n int
+	for len(src) > 0 {
+		if c.ksOff == c.ksLen {
+			c.refill((len(src) + BlockSize - 1) / BlockSize)
 		}
-		i++
+		n = subtle.XORBytes(dst, src, c.ks[c.ksOff:c.ksLen])
+		c.ksOff += n
+		dst, src = dst[n:], src[n:]
 	}
 }
@@ -19,6 +19,8 @@ import (
 	"bytes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"testing"
 	"testing/quick"
 )
@@ -172,6 +174,32 @@ func TestCTRRandom(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func TestCTRChunks(t *testing.T) {
+	f := func(key [KeySize]byte, iv [BlockSize]byte, pt []byte, chunks []uint8) bool {
+		c := NewCipher(key[:], SboxDefault)
+		expected := make([]byte, len(pt))
+		c.NewCTR(iv[:]).XORKeyStream(expected, pt)
+		ctr := c.NewCTR(iv[:])
+		got := make([]byte, len(pt))
+		var n int
+		for off := 0; off < len(pt); off += n {
+			n = len(pt) - off
+			if len(chunks) > 0 {
+				if int(chunks[0]) < n {
+					n = int(chunks[0])
+				}
+				chunks = chunks[1:]
+			}
+			ctr.XORKeyStream(got[off:off+n], pt[off:off+n])
+		}
+		return bytes.Equal(got, expected)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestCTRInterface(t *testing.T) {
 	var key [KeySize]byte
 	var iv [8]byte
@@ -194,3 +222,96 @@ func BenchmarkCTR(b *testing.B) {
 		ctr.XORKeyStream(dst, src)
 	}
 }
+
+func TestCTRCounterWraparound(t *testing.T) {
+	ctr := CTR{}
+	for _, v := range []struct{ n1, n2, n1Next, n2Next nv }{
+		{0x00000000, 0x00000000, 0x01010101, 0x01010104},
+		// 0xFFFFFFF0 + 0x01010104 = 0x1010100F4 = 0x010100F5 (mod 2^32-1)
+		{0xFFFFFFFF, 0xFFFFFFF0, 0x01010100, 0x010100F5},
+		// No overflow: 2^32-1 stays as is
+		{0xFEFEFEFF, 0xFEFEFEFB, 0x00000000, 0xFFFFFFFF},
+		// 2^32-1 is equivalent to zero
+		{0x00000000, 0xFFFFFFFF, 0x01010101, 0x01010104},
+		{0x00000000, 0xFEFEFEFC, 0x01010101, 0x00000001},
+	} {
+		ctr.n1, ctr.n2 = v.n1, v.n2
+		ctr.incr()
+		if ctr.n1 != v.n1Next || ctr.n2 != v.n2Next {
+			t.Fatalf("%08X %08X: %08X %08X", v.n1, v.n2, ctr.n1, ctr.n2)
+		}
+	}
+}
+
+func TestCTRSetOffset(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	iv := make([]byte, BlockSize)
+	rand.Read(iv)
+	c := NewCipher(key, SboxDefault)
+	// Long enough to wrap C1 addition several times
+	ks := make([]byte, 1024*BlockSize)
+	c.NewCTR(iv).XORKeyStream(ks, ks)
+	ctr := c.NewCTR(iv)
+	f := func(off, n uint16) bool {
+		o := int(off) % len(ks)
+		l := int(n) % (len(ks) - o)
+		got := make([]byte, l)
+		ctr.SetOffset(uint64(o))
+		ctr.XORKeyStream(got, got)
+		return bytes.Equal(got, ks[o:o+l])
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCTRSetOffsetFar(t *testing.T) {
+	c := NewCipher(make([]byte, KeySize), SboxDefault)
+	ctr := c.NewCTR(make([]byte, BlockSize))
+	f := func(blk uint64) bool {
+		blk >>= 4
+		ctr.SetOffset(blk * BlockSize)
+		ctr.incr()
+		n1, n2 := ctr.n1, ctr.n2
+		ctr.SetOffset((blk + 1) * BlockSize)
+		return ctr.n1 == n1 && ctr.n2 == n2
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+// Keystream of gogost releases before end-around carry N4 counter,
+// made by them. It crosses N4 overflow several times.
+func TestCTRLegacy(t *testing.T) {
+	key := make([]byte, KeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	iv := []byte{0, 1, 2, 3, 4, 5, 6, 7}
+	c := NewCipher(key, &SboxIdGostR341194CryptoProParamSet)
+	ks := make([]byte, 4096)
+	c.NewCTRLegacy(iv).XORKeyStream(ks, ks)
+	sum := sha256.Sum256(ks)
+	if hex.EncodeToString(sum[:]) != "d7ea766df24a89ecdd6cf90aef3758954d832dc56245948db95dae0f9f5e8266" {
+		t.FailNow()
+	}
+	if hex.EncodeToString(ks[:16]) != "166197b93b45883efab8ee82c0cc9c30" {
+		t.FailNow()
+	}
+	ksNew := make([]byte, len(ks))
+	c.NewCTR(iv).XORKeyStream(ksNew, ksNew)
+	if !bytes.Equal(ks[:BlockSize], ksNew[:BlockSize]) || bytes.Equal(ks, ksNew) {
+		t.Fatal("keystreams must differ only after N4 overflow")
+	}
+	ctr := c.NewCTRLegacy(iv)
+	for _, offset := range []uint64{4000, 1, 2049, 0, 4095} {
+		ctr.SetOffset(offset)
+		got := make([]byte, len(ks)-int(offset))
+		ctr.XORKeyStream(got, got)
+		if !bytes.Equal(got, ks[offset:]) {
+			t.Fatal("offset", offset)
+		}
+	}
+}
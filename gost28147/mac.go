@@ -27,13 +27,16 @@ var (
 )
 
 type MAC struct {
-	c    *Cipher
-	size int
-	iv   []byte
-	prev []byte
-	buf  []byte
-	n1   nv
-	n2   nv
+	c       *Cipher
+	c0      *Cipher
+	size    int
+	iv      []byte
+	prev    []byte
+	buf     []byte
+	n1      nv
+	n2      nv
+	meshing bool
+	count   int
 }
 
 // Create MAC with given tag size and initial initialization vector.
@@ -47,7 +50,7 @@ func (c *Cipher) NewMAC(size int, iv []byte) (*MAC, error) {
 	if len(iv) != BlockSize {
 		return nil, fmt.Errorf("gogost/gost28147: len(iv)=%d != %d", len(iv), BlockSize)
 	}
-	m := MAC{c: c, size: size, iv: iv}
+	m := MAC{c: c, c0: c, size: size, iv: iv}
 	n2, n1 := block2nvs(iv)
 	m.iv = make([]byte, BlockSize)
 	nvs2block(n1, n2, m.iv)
@@ -56,9 +59,25 @@ func (c *Cipher) NewMAC(size int, iv []byte) (*MAC, error) {
 	return &m, nil
 }
 
+// Create MAC with CryptoPro key meshing (RFC 4357): each MeshingPeriod
+// bytes the key is meshed, leaving the MAC state intact.
+func (c *Cipher) NewMACMeshing(size int, iv []byte) (*MAC, error) {
+	m, err := c.NewMAC(size, iv)
+	if err != nil {
+		return nil, err
+	}
+	m.c = c.clone()
+	m.meshing = true
+	return m, nil
+}
+
 func (m *MAC) Reset() {
 	copy(m.prev, m.iv)
 	m.buf = nil
+	if m.meshing {
+		*m.c = *m.c0
+		m.count = 0
+	}
 }
 
 func (m *MAC) BlockSize() int {
@@ -69,15 +88,27 @@ func (m *MAC) Size() int {
 	return m.size
 }
 
+// out = E(prev xor data), meshing the key beforehand if required.
+func (m *MAC) block(c *Cipher, count *int, out, data []byte) {
+	if m.meshing {
+		if *count == MeshingPeriod {
+			c.meshKey()
+			*count = 0
+		}
+		*count += BlockSize
+	}
+	for i := 0; i < BlockSize; i++ {
+		out[i] = m.prev[i] ^ data[i]
+	}
+	m.n1, m.n2 = block2nvs(out)
+	m.n1, m.n2 = c.xcrypt(SeqMAC, m.n1, m.n2)
+	nvs2block(m.n2, m.n1, out)
+}
+
 func (m *MAC) Write(b []byte) (int, error) {
 	m.buf = append(m.buf, b...)
 	for len(m.buf) >= BlockSize {
-		for i := 0; i < BlockSize; i++ {
-			m.prev[i] ^= m.buf[i]
-		}
-		m.n1, m.n2 = block2nvs(m.prev)
-		m.n1, m.n2 = m.c.xcrypt(SeqMAC, m.n1, m.n2)
-		nvs2block(m.n2, m.n1, m.prev)
+		m.block(m.c, &m.count, m.prev, m.buf)
 		m.buf = m.buf[8:]
 	}
 	return len(b), nil
@@ -87,16 +118,13 @@ func (m *MAC) Sum(b []byte) []byte {
 	if len(m.buf) == 0 {
 		return append(b, m.prev[0:m.size]...)
 	}
-	buf := m.buf
-	var i int
-	for i = 0; i < BlockSize-len(m.buf); i++ {
-		buf = append(buf, byte(0))
-	}
-	for i = 0; i < BlockSize; i++ {
-		buf[i] ^= m.prev[i]
+	var buf [BlockSize]byte
+	copy(buf[:], m.buf)
+	c, count := m.c, m.count
+	if m.meshing && count == MeshingPeriod {
+		// State must not be altered
+		c = c.clone()
 	}
-	m.n1, m.n2 = block2nvs(buf)
-	m.n1, m.n2 = m.c.xcrypt(SeqMAC, m.n1, m.n2)
-	nvs2block(m.n2, m.n1, buf)
+	m.block(c, &count, buf[:], buf[:])
 	return append(b, buf[0:m.size]...)
 }
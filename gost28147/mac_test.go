@@ -100,6 +100,28 @@ func TestMACRandom(t *testing.T) {
 	}
 }
 
+// Sum of the incomplete block must not alter buffered data.
+func TestMACSumKeepsState(t *testing.T) {
+	var key [KeySize]byte
+	rand.Read(key[:])
+	c := NewCipher(key[:], SboxDefault)
+	f := func(iv [BlockSize]byte, a, b []byte) bool {
+		m, err := c.NewMAC(8, iv[:])
+		if err != nil {
+			return false
+		}
+		m.Write(a)
+		m.Sum(nil)
+		m.Write(b)
+		whole, _ := c.NewMAC(8, iv[:])
+		whole.Write(append(append([]byte{}, a...), b...))
+		return bytes.Equal(m.Sum(nil), whole.Sum(nil))
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func TestMACInterface(t *testing.T) {
 	var key [KeySize]byte
 	var iv [8]byte
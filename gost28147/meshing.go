@@ -0,0 +1,42 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost28147
+
+// CryptoPro key meshing constant, RFC 4357 2.3.2.
+var CryptoProKeyMeshingKey = [KeySize]byte{
+	0x69, 0x00, 0x72, 0x22, 0x64, 0xC9, 0x04, 0x23,
+	0x8D, 0x3A, 0xDB, 0x96, 0x46, 0xE9, 0x2A, 0xC4,
+	0x18, 0xFE, 0xAC, 0x94, 0x00, 0xED, 0x07, 0x12,
+	0xC0, 0x86, 0xDC, 0xC2, 0xEF, 0x4C, 0xA9, 0x2B,
+}
+
+// Number of bytes processed with the single key, before CryptoPro key
+// meshing takes place.
+const MeshingPeriod = 1024
+
+// Replace the key with the decrypted CryptoProKeyMeshingKey. Only the
+// key schedule is rerun, Sbox table is kept.
+func (c *Cipher) meshKey() {
+	var key [KeySize]byte
+	c.xcryptBlocks(SeqDecrypt, key[:], CryptoProKeyMeshingKey[:])
+	c.setKey(key[:])
+}
+
+// Copy of the cipher, that can be meshed without affecting the original.
+func (c *Cipher) clone() *Cipher {
+	cc := *c
+	return &cc
+}
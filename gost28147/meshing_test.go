@@ -0,0 +1,233 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost28147
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+	"testing/quick"
+)
+
+// Straightforward block by block implementation of CryptoPro key
+// meshing for reference, following gost-engine's one.
+type meshRef struct {
+	c     *Cipher
+	iv    [BlockSize]byte
+	count int
+}
+
+func (r *meshRef) next() {
+	if r.count == MeshingPeriod {
+		var key [KeySize]byte
+		r.c.NewECBDecrypter().CryptBlocks(key[:], CryptoProKeyMeshingKey[:])
+		r.c = NewCipher(key[:], r.c.sbox)
+		r.c.Encrypt(r.iv[:], r.iv[:])
+		r.count = 0
+	}
+	r.count += BlockSize
+}
+
+func (r *meshRef) cfb(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := 0; i < len(data); i += BlockSize {
+		r.next()
+		r.c.Encrypt(r.iv[:], r.iv[:])
+		for j := 0; j < BlockSize && i+j < len(data); j++ {
+			r.iv[j] ^= data[i+j]
+			out[i+j] = r.iv[j]
+		}
+	}
+	return out
+}
+
+func (r *meshRef) ctr(data []byte) []byte {
+	out := make([]byte, len(data))
+	var gamma [BlockSize]byte
+	r.c.Encrypt(r.iv[:], r.iv[:])
+	for i := 0; i < len(data); i += BlockSize {
+		r.next()
+		n1 := binary.LittleEndian.Uint32(r.iv[:4]) + 0x01010101
+		n2 := binary.LittleEndian.Uint32(r.iv[4:])
+		n2 += 0x01010104
+		if n2 < 0x01010104 {
+			n2++
+		}
+		binary.LittleEndian.PutUint32(r.iv[:4], n1)
+		binary.LittleEndian.PutUint32(r.iv[4:], n2)
+		r.c.Encrypt(gamma[:], r.iv[:])
+		for j := 0; j < BlockSize && i+j < len(data); j++ {
+			out[i+j] = data[i+j] ^ gamma[j]
+		}
+	}
+	return out
+}
+
+func (r *meshRef) cbc(data []byte) []byte {
+	out := make([]byte, len(data))
+	for i := 0; i < len(data); i += BlockSize {
+		r.next()
+		for j := 0; j < BlockSize; j++ {
+			r.iv[j] ^= data[i+j]
+		}
+		r.c.Encrypt(r.iv[:], r.iv[:])
+		copy(out[i:], r.iv[:])
+	}
+	return out
+}
+
+func meshingData() (c *Cipher, iv, pt []byte) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	c = NewCipher(key, &SboxIdGost2814789CryptoProAParamSet)
+	iv = make([]byte, BlockSize)
+	rand.Read(iv)
+	pt = make([]byte, 5*MeshingPeriod+3*BlockSize)
+	rand.Read(pt)
+	return
+}
+
+// Process data with chunks of random sizes.
+func chunked(chunks []uint16, data []byte, f func(dst, src []byte)) []byte {
+	out := make([]byte, len(data))
+	var n int
+	for off := 0; off < len(data); off += n {
+		n = len(data) - off
+		if len(chunks) > 0 {
+			if int(chunks[0])%(2*MeshingPeriod) < n {
+				n = int(chunks[0]) % (2 * MeshingPeriod)
+			}
+			chunks = chunks[1:]
+		}
+		f(out[off:off+n], data[off:off+n])
+	}
+	return out
+}
+
+func TestMeshingCFB(t *testing.T) {
+	c, iv, pt := meshingData()
+	ref := meshRef{c: c}
+	copy(ref.iv[:], iv)
+	expected := ref.cfb(pt)
+	plain := make([]byte, len(pt))
+	c.NewCFBEncrypter(iv).XORKeyStream(plain, pt)
+	if !bytes.Equal(plain[:MeshingPeriod], expected[:MeshingPeriod]) ||
+		bytes.Equal(plain[MeshingPeriod:], expected[MeshingPeriod:]) {
+		t.FailNow()
+	}
+	f := func(chunks []uint16) bool {
+		e := c.NewCFBEncrypterMeshing(iv)
+		ct := chunked(chunks, pt, e.XORKeyStream)
+		if !bytes.Equal(ct, expected) {
+			return false
+		}
+		d := c.NewCFBDecrypterMeshing(iv)
+		return bytes.Equal(chunked(chunks, ct, d.XORKeyStream), pt)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMeshingCTR(t *testing.T) {
+	c, iv, pt := meshingData()
+	ref := meshRef{c: c}
+	copy(ref.iv[:], iv)
+	expected := ref.ctr(pt)
+	f := func(chunks []uint16) bool {
+		ctr := c.NewCTRMeshing(iv)
+		return bytes.Equal(chunked(chunks, pt, ctr.XORKeyStream), expected)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+	ctr := c.NewCTRMeshing(iv)
+	g := func(off, n uint16) bool {
+		o := int(off) % len(pt)
+		l := int(n) % (len(pt) - o)
+		got := make([]byte, l)
+		ctr.SetOffset(uint64(o))
+		ctr.XORKeyStream(got, pt[o:o+l])
+		return bytes.Equal(got, expected[o:o+l])
+	}
+	if err := quick.Check(g, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMeshingCBC(t *testing.T) {
+	c, iv, pt := meshingData()
+	ref := meshRef{c: c}
+	copy(ref.iv[:], iv)
+	expected := ref.cbc(pt)
+	f := func(chunks []uint16) bool {
+		for i := range chunks {
+			chunks[i] -= chunks[i] % BlockSize
+		}
+		e := c.NewCBCEncrypterMeshing(iv)
+		ct := chunked(chunks, pt, e.CryptBlocks)
+		if !bytes.Equal(ct, expected) {
+			return false
+		}
+		d := c.NewCBCDecrypterMeshing(iv)
+		return bytes.Equal(chunked(chunks, ct, d.CryptBlocks), pt)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMeshingMAC(t *testing.T) {
+	c, iv, pt := meshingData()
+	// Meshing does not touch the state, so MAC continues with the
+	// meshed key from the previous value
+	var sum []byte
+	ref := meshRef{c: c}
+	for i := 0; i < len(pt); i += MeshingPeriod {
+		ref.next()
+		m, _ := ref.c.NewMAC(BlockSize, iv)
+		if sum != nil {
+			m, _ = ref.c.NewMAC(BlockSize, sum)
+		}
+		end := i + MeshingPeriod
+		if end > len(pt) {
+			end = len(pt)
+		}
+		m.Write(pt[i:end])
+		sum = m.Sum(nil)
+		ref.count = MeshingPeriod
+	}
+	m, err := c.NewMACMeshing(BlockSize, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Write(pt[:MeshingPeriod])
+	plain, _ := c.NewMAC(BlockSize, iv)
+	plain.Write(pt[:MeshingPeriod])
+	if !bytes.Equal(m.Sum(nil), plain.Sum(nil)) {
+		t.FailNow()
+	}
+	m.Write(pt[MeshingPeriod:])
+	if !bytes.Equal(m.Sum(nil), sum) || !bytes.Equal(m.Sum(nil), sum) {
+		t.FailNow()
+	}
+	m.Reset()
+	m.Write(pt)
+	if !bytes.Equal(m.Sum(nil), sum) {
+		t.FailNow()
+	}
+}
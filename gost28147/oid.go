@@ -0,0 +1,59 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost28147
+
+import (
+	"encoding/asn1"
+)
+
+// Algorithm identifier of GOST 28147-89 encryption (RFC 4357).
+var OIDGost2814789 = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 21}
+
+var sboxOIDs = []struct {
+	oid     asn1.ObjectIdentifier
+	sbox    *Sbox
+	meshing bool
+}{
+	{asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 0}, &SboxIdGost2814789TestParamSet, false},
+	{asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 1}, &SboxIdGost2814789CryptoProAParamSet, true},
+	{asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 2}, &SboxIdGost2814789CryptoProBParamSet, true},
+	{asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 3}, &SboxIdGost2814789CryptoProCParamSet, true},
+	{asn1.ObjectIdentifier{1, 2, 643, 2, 2, 31, 4}, &SboxIdGost2814789CryptoProDParamSet, true},
+	{asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 5, 1, 1}, &SboxIdtc26gost28147paramZ, true},
+}
+
+// Get the encryption parameter set's Sbox by its identifier. Whether
+// CryptoPro key meshing has to be used with it is also returned.
+// Nil is returned for unknown identifiers.
+func SboxByOID(oid asn1.ObjectIdentifier) (sbox *Sbox, meshing bool) {
+	for _, s := range sboxOIDs {
+		if s.oid.Equal(oid) {
+			return s.sbox, s.meshing
+		}
+	}
+	return nil, false
+}
+
+// Get encryption parameter set's identifier of the Sbox. Nil is
+// returned for Sboxes without it.
+func (s *Sbox) OID() asn1.ObjectIdentifier {
+	for _, o := range sboxOIDs {
+		if *o.sbox == *s {
+			return o.oid
+		}
+	}
+	return nil
+}
@@ -124,3 +124,56 @@ func (s *Sbox) k(n nv) nv {
 		nv(s[6][(n>>24)&0x0F])<<24 +
 		nv(s[7][(n>>28)&0x0F])<<28
 }
+
+// Sbox substitution combined with the 11-bit cyclic shift, that is
+// the round function of 28147-89 and Magma, precomputed for each byte
+// of the input value.
+type SboxTable [4][256]uint32
+
+func (s *Sbox) newTable() *SboxTable {
+	t := new(SboxTable)
+	for j := 0; j < 4; j++ {
+		for b := 0; b < 256; b++ {
+			t[j][b] = uint32(((nv(s[2*j][b&0x0F]) | nv(s[2*j+1][b>>4])<<4) << (8 * j)).shift11())
+		}
+	}
+	return t
+}
+
+// Tables of the predefined Sboxes. They are never altered after
+// initialization, so they are shared between all ciphers.
+var sboxTables = func() map[Sbox]*SboxTable {
+	tables := make(map[Sbox]*SboxTable)
+	for _, s := range []*Sbox{
+		&SboxIdGost2814789TestParamSet,
+		&SboxIdGost2814789CryptoProAParamSet,
+		&SboxIdGost2814789CryptoProBParamSet,
+		&SboxIdGost2814789CryptoProCParamSet,
+		&SboxIdGost2814789CryptoProDParamSet,
+		&SboxIdtc26gost28147paramZ,
+		&SboxIdGostR341194TestParamSet,
+		&SboxIdGostR341194CryptoProParamSet,
+		&SboxEACParamSet,
+	} {
+		tables[*s] = s.newTable()
+	}
+	return tables
+}()
+
+// Get the precomputed table of the Sbox. Shared one is returned for
+// predefined Sboxes, a freshly computed one for others.
+func (s *Sbox) Table() *SboxTable {
+	if t, ok := sboxTables[*s]; ok {
+		return t
+	}
+	return s.newTable()
+}
+
+// Equivalent of s.k(n).shift11().
+func (t *SboxTable) F(n uint32) uint32 {
+	return t[0][n&0xFF] ^ t[1][(n>>8)&0xFF] ^ t[2][(n>>16)&0xFF] ^ t[3][n>>24]
+}
+
+func (t *SboxTable) f(n nv) nv {
+	return nv(t.F(uint32(n)))
+}
@@ -0,0 +1,38 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost28147
+
+import (
+	"testing"
+	"testing/quick"
+)
+
+func TestSboxTable(t *testing.T) {
+	for _, sbox := range []*Sbox{
+		&SboxIdGost2814789TestParamSet,
+		&SboxIdGost2814789CryptoProAParamSet,
+		&SboxIdtc26gost28147paramZ,
+		&SboxIdGostR341194CryptoProParamSet,
+	} {
+		tbl := sbox.Table()
+		f := func(n uint32) bool {
+			return tbl.f(nv(n)) == sbox.k(nv(n)).shift11()
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3410
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pedroalbanese/gogost/gost34112012256"
+)
+
+// Length of the UKM used by KEG.
+const KEGUKMSize = 24
+
+// KEG export keys generation function (R 1323565.1.020-2018). First 16
+// bytes of UKM are little-endian VKO factor (1 if zero), next 8 are
+// KDF_TREE seed for 256-bit curves. 64 bytes of encryption and MAC
+// keys are returned.
+func (prv *PrivateKey) KEG(pub *PublicKey, ukm []byte) ([]byte, error) {
+	if len(ukm) < KEGUKMSize {
+		return nil, errors.New("gogost/gost3410.PrivateKey.KEG: UKM is too short")
+	}
+	u := NewUKM(ukm[:16])
+	if u.Sign() == 0 {
+		u.SetInt64(1)
+	}
+	if prv.C.PointSize() == 64 {
+		key, err := prv.KEK2012512(pub, u)
+		if err != nil {
+			return nil, fmt.Errorf("gogost/gost3410.PrivateKey.KEG: %w", err)
+		}
+		return key, nil
+	}
+	key, err := prv.KEK2012256(pub, u)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/gost3410.PrivateKey.KEG: %w", err)
+	}
+	return gost34112012256.KDFTree(nil, key, []byte("kdf tree"), ukm[16:24], 1, 64), nil
+}
@@ -0,0 +1,51 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3410
+
+import (
+	"bytes"
+	"testing"
+	"testing/quick"
+)
+
+func TestRandomKEG(t *testing.T) {
+	for _, c := range []*Curve{
+		CurveIdtc26gost34102012256paramSetA(),
+		CurveIdtc26gost341012512paramSetA(),
+	} {
+		f := func(prvRaw1 [64]byte, prvRaw2 [64]byte, ukm [KEGUKMSize]byte) bool {
+			prv1, err := NewPrivateKey(c, prvRaw1[:c.PointSize()])
+			if err != nil {
+				return false
+			}
+			prv2, err := NewPrivateKey(c, prvRaw2[:c.PointSize()])
+			if err != nil {
+				return false
+			}
+			pub1, _ := prv1.PublicKey()
+			pub2, _ := prv2.PublicKey()
+			keys1, err := prv1.KEG(pub2, ukm[:])
+			if err != nil || len(keys1) != 64 {
+				return false
+			}
+			keys2, _ := prv2.KEG(pub1, ukm[:])
+			return bytes.Equal(keys1, keys2)
+		}
+		if err := quick.Check(f, &quick.Config{MaxCount: 10}); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3410
+
+import (
+	"encoding/asn1"
+	"hash"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/gost341194"
+)
+
+var (
+	// Public key algorithms
+	OIDGostR34102001       = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 19}
+	OIDTc26Gost34102012256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 1}
+	OIDTc26Gost34102012512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 1, 2}
+
+	// Signature algorithms
+	OIDGostR341194WithGostR34102001      = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 3}
+	OIDTc26SignWithDigestGost34102012256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 3, 2}
+	OIDTc26SignWithDigestGost34102012512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 3, 3}
+
+	// Digest algorithms and parameters
+	OIDGostR341194                  = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 9}
+	OIDGostR341194CryptoProParamSet = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 30, 1}
+	OIDTc26Gost34112012256          = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 2, 2}
+	OIDTc26Gost34112012512          = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 2, 3}
+)
+
+var curveOIDs = []struct {
+	oid   asn1.ObjectIdentifier
+	curve func() *Curve
+	names []string
+}{
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 0},
+		CurveIdGostR34102001TestParamSet,
+		[]string{"id-GostR3410-2001-TestParamSet"},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 1},
+		CurveIdGostR34102001CryptoProAParamSet,
+		[]string{"id-GostR3410-2001-CryptoPro-A-ParamSet"},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 2},
+		CurveIdGostR34102001CryptoProBParamSet,
+		[]string{"id-GostR3410-2001-CryptoPro-B-ParamSet"},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 2, 2, 35, 3},
+		CurveIdGostR34102001CryptoProCParamSet,
+		[]string{"id-GostR3410-2001-CryptoPro-C-ParamSet"},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 2, 2, 36, 0},
+		CurveIdGostR34102001CryptoProXchAParamSet,
+		[]string{"id-GostR3410-2001-CryptoPro-XchA-ParamSet"},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 2, 2, 36, 1},
+		CurveIdGostR34102001CryptoProXchBParamSet,
+		[]string{"id-GostR3410-2001-CryptoPro-XchB-ParamSet"},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 1},
+		CurveIdtc26gost341012256paramSetA,
+		[]string{
+			"id-tc26-gost-3410-12-256-paramSetA",
+			"id-tc26-gost-3410-2012-256-paramSetA",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 2},
+		CurveIdtc26gost341012256paramSetB,
+		[]string{
+			"id-tc26-gost-3410-12-256-paramSetB",
+			"id-tc26-gost-3410-2012-256-paramSetB",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 3},
+		CurveIdtc26gost341012256paramSetC,
+		[]string{
+			"id-tc26-gost-3410-12-256-paramSetC",
+			"id-tc26-gost-3410-2012-256-paramSetC",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 1, 4},
+		CurveIdtc26gost341012256paramSetD,
+		[]string{
+			"id-tc26-gost-3410-12-256-paramSetD",
+			"id-tc26-gost-3410-2012-256-paramSetD",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 0},
+		CurveIdtc26gost341012512paramSetTest,
+		[]string{
+			"id-tc26-gost-3410-12-512-paramSetTest",
+			"id-tc26-gost-3410-2012-512-paramSetTest",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 1},
+		CurveIdtc26gost341012512paramSetA,
+		[]string{
+			"id-tc26-gost-3410-12-512-paramSetA",
+			"id-tc26-gost-3410-2012-512-paramSetA",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 2},
+		CurveIdtc26gost341012512paramSetB,
+		[]string{
+			"id-tc26-gost-3410-12-512-paramSetB",
+			"id-tc26-gost-3410-2012-512-paramSetB",
+		},
+	},
+	{
+		asn1.ObjectIdentifier{1, 2, 643, 7, 1, 2, 1, 2, 3},
+		CurveIdtc26gost341012512paramSetC,
+		[]string{
+			"id-tc26-gost-3410-12-512-paramSetC",
+			"id-tc26-gost-3410-2012-512-paramSetC",
+		},
+	},
+}
+
+// Get the curve by its parameter set identifier. Nil is returned for
+// unknown identifiers.
+func CurveByOID(oid asn1.ObjectIdentifier) *Curve {
+	for _, c := range curveOIDs {
+		if c.oid.Equal(oid) {
+			return c.curve()
+		}
+	}
+	return nil
+}
+
+// Get the curve by any of its names, like
+// "id-tc26-gost-3410-2012-256-paramSetA". Nil is returned for unknown
+// names.
+func CurveByName(name string) *Curve {
+	for _, c := range curveOIDs {
+		for _, n := range c.names {
+			if n == name {
+				return c.curve()
+			}
+		}
+	}
+	return nil
+}
+
+// Canonical names of all curves having parameter set identifiers.
+func CurveNames() []string {
+	names := make([]string, 0, len(curveOIDs))
+	for _, c := range curveOIDs {
+		names = append(names, c.names[len(c.names)-1])
+	}
+	return names
+}
+
+// Get parameter set identifier of the curve, determined by its name.
+// Nil is returned for curves without it.
+func (c *Curve) OID() asn1.ObjectIdentifier {
+	for _, o := range curveOIDs {
+		for _, name := range o.names {
+			if name == c.Name {
+				return o.oid
+			}
+		}
+	}
+	return nil
+}
+
+// Get digest and signature algorithm identifiers used with the public
+// key algorithm. Nils are returned for unknown ones.
+func SignatureAlgorithms(keyAlgo asn1.ObjectIdentifier) (digest, signature asn1.ObjectIdentifier) {
+	switch {
+	case keyAlgo.Equal(OIDGostR34102001):
+		return OIDGostR341194, OIDGostR341194WithGostR34102001
+	case keyAlgo.Equal(OIDTc26Gost34102012256):
+		return OIDTc26Gost34112012256, OIDTc26SignWithDigestGost34102012256
+	case keyAlgo.Equal(OIDTc26Gost34102012512):
+		return OIDTc26Gost34112012512, OIDTc26SignWithDigestGost34102012512
+	}
+	return nil, nil
+}
+
+// Get digest algorithm identifier of the signature algorithm. Public
+// key algorithm identifiers are also accepted, as CMS uses them.
+func DigestBySignatureAlgorithm(sigAlgo asn1.ObjectIdentifier) asn1.ObjectIdentifier {
+	switch {
+	case sigAlgo.Equal(OIDGostR341194WithGostR34102001), sigAlgo.Equal(OIDGostR34102001):
+		return OIDGostR341194
+	case sigAlgo.Equal(OIDTc26SignWithDigestGost34102012256), sigAlgo.Equal(OIDTc26Gost34102012256):
+		return OIDTc26Gost34112012256
+	case sigAlgo.Equal(OIDTc26SignWithDigestGost34102012512), sigAlgo.Equal(OIDTc26Gost34102012512):
+		return OIDTc26Gost34112012512
+	}
+	return nil
+}
+
+// Create hash of the digest algorithm. GOST R 34.11-94 uses CryptoPro
+// parameters. Nil is returned for unknown identifiers.
+func NewHash(digest asn1.ObjectIdentifier) hash.Hash {
+	switch {
+	case digest.Equal(OIDGostR341194):
+		return gost341194.New(&gost28147.SboxIdGostR341194CryptoProParamSet)
+	case digest.Equal(OIDTc26Gost34112012256):
+		return gost34112012256.New()
+	case digest.Equal(OIDTc26Gost34112012512):
+		return gost34112012512.New()
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3410
+
+import "testing"
+
+func TestCurveByName(t *testing.T) {
+	for _, name := range CurveNames() {
+		c := CurveByName(name)
+		if c == nil {
+			t.Fatal("unknown", name)
+		}
+		if !CurveByOID(c.OID()).Equal(c) {
+			t.Fatal("OID differs", name)
+		}
+	}
+	if CurveByName("id-tc26-gost-3410-12-256-paramSetA") == nil {
+		t.Fatal("alias is unknown")
+	}
+	if CurveByName("unknown") != nil {
+		t.Fatal("unknown name accepted")
+	}
+}
@@ -0,0 +1,234 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3410
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// GostR3410-2012-PublicKeyParameters (RFC 9215, RFC 4491).
+type PublicKeyParameters struct {
+	PublicKeyParamSet asn1.ObjectIdentifier
+	DigestParamSet    asn1.ObjectIdentifier `asn1:"optional"`
+}
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type publicKeyInfo struct {
+	Algo      pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// Public key algorithm identifier of the keys on the curve. 34.10-2012
+// identifiers are used, unless legacy 34.10-2001 one is requested.
+func KeyAlgorithm(c *Curve, legacy bool) (pkix.AlgorithmIdentifier, error) {
+	var ai pkix.AlgorithmIdentifier
+	params := PublicKeyParameters{PublicKeyParamSet: c.OID()}
+	if params.PublicKeyParamSet == nil {
+		return ai, fmt.Errorf("gogost/gost3410: curve %s has no identifier", c.Name)
+	}
+	// CryptoPro curves are defined with the digest parameters
+	cryptoPro := len(params.PublicKeyParamSet) == 7 &&
+		params.PublicKeyParamSet[:5].Equal(asn1.ObjectIdentifier{1, 2, 643, 2, 2})
+	switch {
+	case legacy:
+		if c.PointSize() != 32 {
+			return ai, errors.New("gogost/gost3410: 34.10-2001 requires 256-bit curve")
+		}
+		ai.Algorithm = OIDGostR34102001
+		params.DigestParamSet = OIDGostR341194CryptoProParamSet
+	case c.PointSize() == 64:
+		ai.Algorithm = OIDTc26Gost34102012512
+	default:
+		ai.Algorithm = OIDTc26Gost34102012256
+		if cryptoPro {
+			params.DigestParamSet = OIDTc26Gost34112012256
+		}
+	}
+	der, err := asn1.Marshal(params)
+	if err != nil {
+		return ai, err
+	}
+	ai.Parameters = asn1.RawValue{FullBytes: der}
+	return ai, nil
+}
+
+// Get the curve from the public key algorithm identifier.
+func CurveByKeyAlgorithm(ai pkix.AlgorithmIdentifier) (*Curve, error) {
+	var pointSize int
+	switch {
+	case ai.Algorithm.Equal(OIDGostR34102001):
+		pointSize = 32
+	case ai.Algorithm.Equal(OIDTc26Gost34102012256):
+		pointSize = 32
+	case ai.Algorithm.Equal(OIDTc26Gost34102012512):
+		pointSize = 64
+	default:
+		return nil, fmt.Errorf("gogost/gost3410: unknown key algorithm %s", ai.Algorithm)
+	}
+	var params PublicKeyParameters
+	rest, err := asn1.Unmarshal(ai.Parameters.FullBytes, &params)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/gost3410: invalid key parameters: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost/gost3410: trailing data after key parameters")
+	}
+	c := CurveByOID(params.PublicKeyParamSet)
+	if c == nil {
+		return nil, fmt.Errorf("gogost/gost3410: unknown curve %s", params.PublicKeyParamSet)
+	}
+	if c.PointSize() != pointSize {
+		return nil, errors.New("gogost/gost3410: curve does not match key algorithm")
+	}
+	return c, nil
+}
+
+func marshalPKCS8PrivateKey(prv *PrivateKey, legacy bool) ([]byte, error) {
+	ai, err := KeyAlgorithm(prv.C, legacy)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := asn1.Marshal(prv.RawLE())
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{Algo: ai, PrivateKey: raw})
+}
+
+// Marshal the private key to PKCS#8 PrivateKeyInfo DER with 34.10-2012
+// algorithm identifier. Key is little-endian OCTET STRING.
+func MarshalPKCS8PrivateKey(prv *PrivateKey) ([]byte, error) {
+	return marshalPKCS8PrivateKey(prv, false)
+}
+
+// Marshal the private key to PKCS#8 PrivateKeyInfo DER with legacy
+// 34.10-2001 algorithm identifier.
+func MarshalPKCS8PrivateKey2001(prv *PrivateKey) ([]byte, error) {
+	return marshalPKCS8PrivateKey(prv, true)
+}
+
+// Parse PKCS#8 PrivateKeyInfo DER. Private key can be either
+// little-endian OCTET STRING, or INTEGER.
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var info pkcs8
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/gost3410: invalid PKCS#8: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost/gost3410: trailing data after PKCS#8")
+	}
+	if info.Version != 0 {
+		return nil, fmt.Errorf("gogost/gost3410: unsupported PKCS#8 version %d", info.Version)
+	}
+	c, err := CurveByKeyAlgorithm(info.Algo)
+	if err != nil {
+		return nil, err
+	}
+	var raw asn1.RawValue
+	if _, err = asn1.Unmarshal(info.PrivateKey, &raw); err != nil {
+		return nil, fmt.Errorf("gogost/gost3410: invalid private key: %w", err)
+	}
+	switch {
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString:
+		return NewPrivateKeyLE(c, raw.Bytes)
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagInteger:
+		var k *big.Int
+		if _, err = asn1.Unmarshal(info.PrivateKey, &k); err != nil {
+			return nil, fmt.Errorf("gogost/gost3410: invalid private key: %w", err)
+		}
+		if k.Sign() <= 0 || k.BitLen() > 8*c.PointSize() {
+			return nil, errors.New("gogost/gost3410: invalid private key")
+		}
+		return NewPrivateKeyBE(c, pad(k.Bytes(), c.PointSize()))
+	}
+	return nil, errors.New("gogost/gost3410: unsupported private key encoding")
+}
+
+func marshalPKIXPublicKey(pub *PublicKey, legacy bool) ([]byte, error) {
+	ai, err := KeyAlgorithm(pub.C, legacy)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := asn1.Marshal(pub.RawLE())
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(publicKeyInfo{
+		Algo:      ai,
+		PublicKey: asn1.BitString{Bytes: raw, BitLength: 8 * len(raw)},
+	})
+}
+
+// Marshal the public key to SubjectPublicKeyInfo DER with 34.10-2012
+// algorithm identifier. Key is OCTET STRING of LE(X)||LE(Y).
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	return marshalPKIXPublicKey(pub, false)
+}
+
+// Marshal the public key to SubjectPublicKeyInfo DER with legacy
+// 34.10-2001 algorithm identifier.
+func MarshalPKIXPublicKey2001(pub *PublicKey) ([]byte, error) {
+	return marshalPKIXPublicKey(pub, true)
+}
+
+// Parse the public key from the algorithm identifier and the value of
+// SubjectPublicKeyInfo's BIT STRING.
+func ParsePublicKey(ai pkix.AlgorithmIdentifier, bits []byte) (*PublicKey, error) {
+	c, err := CurveByKeyAlgorithm(ai)
+	if err != nil {
+		return nil, err
+	}
+	var raw []byte
+	rest, err := asn1.Unmarshal(bits, &raw)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/gost3410: invalid public key: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost/gost3410: trailing data after public key")
+	}
+	pub, err := NewPublicKeyLE(c, raw)
+	if err != nil {
+		return nil, err
+	}
+	if !c.Contains(pub.X, pub.Y) {
+		return nil, errors.New("gogost/gost3410: public key is not on the curve")
+	}
+	return pub, nil
+}
+
+// Parse SubjectPublicKeyInfo DER.
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var info publicKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/gost3410: invalid SubjectPublicKeyInfo: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost/gost3410: trailing data after SubjectPublicKeyInfo")
+	}
+	return ParsePublicKey(info.Algo, info.PublicKey.RightAlign())
+}
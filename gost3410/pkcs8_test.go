@@ -0,0 +1,141 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3410
+
+import (
+	"crypto/rand"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+)
+
+func TestPKCS8Roundtrip(t *testing.T) {
+	for _, o := range curveOIDs {
+		c := o.curve()
+		prv, err := GenPrivateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		marshalers := []func(*PrivateKey) ([]byte, error){MarshalPKCS8PrivateKey}
+		pubMarshalers := []func(*PublicKey) ([]byte, error){MarshalPKIXPublicKey}
+		if c.PointSize() == 32 {
+			marshalers = append(marshalers, MarshalPKCS8PrivateKey2001)
+			pubMarshalers = append(pubMarshalers, MarshalPKIXPublicKey2001)
+		}
+		for _, marshal := range marshalers {
+			der, err := marshal(prv)
+			if err != nil {
+				t.Fatal(c.Name, err)
+			}
+			got, err := ParsePKCS8PrivateKey(der)
+			if err != nil {
+				t.Fatal(c.Name, err)
+			}
+			if !got.C.Equal(c) || got.Key.Cmp(prv.Key) != 0 {
+				t.FailNow()
+			}
+		}
+		for _, marshal := range pubMarshalers {
+			der, err := marshal(pub)
+			if err != nil {
+				t.Fatal(c.Name, err)
+			}
+			got, err := ParsePKIXPublicKey(der)
+			if err != nil {
+				t.Fatal(c.Name, err)
+			}
+			if !got.C.Equal(c) || got.X.Cmp(pub.X) != 0 || got.Y.Cmp(pub.Y) != 0 {
+				t.FailNow()
+			}
+		}
+	}
+}
+
+func TestPKCS8IntegerKey(t *testing.T) {
+	c := CurveIdtc26gost34102012256paramSetA()
+	prv, err := GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ai, err := KeyAlgorithm(c, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := asn1.Marshal(prv.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := asn1.Marshal(pkcs8{Algo: ai, PrivateKey: raw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParsePKCS8PrivateKey(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Key.Cmp(prv.Key) != 0 {
+		t.FailNow()
+	}
+}
+
+func TestKeyAlgorithmDigestParams(t *testing.T) {
+	for _, c := range []*Curve{
+		CurveIdtc26gost34102012256paramSetA(),
+		CurveIdtc26gost34102012512paramSetA(),
+	} {
+		ai, err := KeyAlgorithm(c, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var params PublicKeyParameters
+		if _, err = asn1.Unmarshal(ai.Parameters.FullBytes, &params); err != nil {
+			t.Fatal(err)
+		}
+		if params.DigestParamSet != nil {
+			t.Fatal(c.Name, "unexpected digestParamSet")
+		}
+	}
+	ai, err := KeyAlgorithm(CurveIdGostR34102001CryptoProAParamSet(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params PublicKeyParameters
+	if _, err = asn1.Unmarshal(ai.Parameters.FullBytes, &params); err != nil {
+		t.Fatal(err)
+	}
+	if !params.DigestParamSet.Equal(OIDTc26Gost34112012256) {
+		t.FailNow()
+	}
+	if _, err = KeyAlgorithm(CurveIdtc26gost34102012512paramSetA(), true); err == nil {
+		t.Fatal("512-bit 34.10-2001 key accepted")
+	}
+}
+
+func TestPKIXPublicKeyNotOnCurve(t *testing.T) {
+	c := CurveIdtc26gost34102012256paramSetB()
+	pub := &PublicKey{C: c, X: big.NewInt(1), Y: big.NewInt(2)}
+	der, err := MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParsePKIXPublicKey(der); err == nil {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,46 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost34112012256
+
+import (
+	"crypto/hmac"
+	"math/big"
+)
+
+// KDF_TREE_GOSTR3411_2012_256 (R 50.1.113-2016) key derivation function.
+// Derive length bytes from the key, using r bytes long counter.
+func KDFTree(dst, key, label, seed []byte, r, length int) []byte {
+	if r < 1 || r > 4 {
+		panic("gogost/gost34112012256: invalid counter length")
+	}
+	l := big.NewInt(int64(length) * 8).Bytes()
+	h := hmac.New(New, key)
+	ctr := make([]byte, r)
+	var out []byte
+	for i := 1; len(out) < length; i++ {
+		for j := 0; j < r; j++ {
+			ctr[r-j-1] = byte(i >> (8 * j))
+		}
+		h.Write(ctr)
+		h.Write(label)
+		h.Write([]byte{0x00})
+		h.Write(seed)
+		h.Write(l)
+		out = h.Sum(out)
+		h.Reset()
+	}
+	return append(dst, out[:length]...)
+}
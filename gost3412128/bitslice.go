@@ -0,0 +1,245 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Bitsliced constant-time implementation. State of up to bsBlocks
+// blocks is kept in eight 64-bit planes: i-th plane holds i-th bits of
+// all bytes, where lane 16*g+p corresponds to p-th byte of g-th block.
+// S-box is evaluated as algebraic normal form circuit over the planes,
+// L is a set of lane rotations with masks applied to all blocks at once.
+// Neither of them makes secret-dependent memory accesses or branches.
+
+package gost3412128
+
+import (
+	"math/bits"
+)
+
+// Number of blocks processed simultaneously by bitsliced implementation.
+const bsBlocks = 4
+
+type bsState [8]uint64
+
+var (
+	// Monomials (as sets of input bits) of each S-box output bit.
+	bsPi    [8][]uint8
+	bsPiInv [8][]uint8
+
+	// Linear transformation masks: p-th lane of [i][j][d] mask is set
+	// if j-th bit of p-th output byte depends on i-th bit of
+	// ((p+d)%16)-th input byte.
+	bsL    [8][8][BlockSize]uint64
+	bsLInv [8][8][BlockSize]uint64
+
+	// Key schedule constants C_i in every block.
+	bsC [32]bsState
+
+	// Masks of lanes that do not wrap (lo) and do wrap (hi) when
+	// rotating each block's lanes by d positions.
+	bsRotLo [BlockSize]uint64
+	bsRotHi [BlockSize]uint64
+)
+
+// Replicate the lanes mask of single block to all bsBlocks ones.
+func bsReplicate(m uint16) (r uint64) {
+	for g := 0; g < bsBlocks; g++ {
+		r |= uint64(m) << (BlockSize * g)
+	}
+	return
+}
+
+// Algebraic normal form of each output bit of the S-box, computed with
+// Moebius transform.
+func bsANF(sbox *[256]byte) (anf [8][]uint8) {
+	var f [256]byte
+	for j := 0; j < 8; j++ {
+		for x := 0; x < 256; x++ {
+			f[x] = (sbox[x] >> j) & 1
+		}
+		for b := 0; b < 8; b++ {
+			for x := 0; x < 256; x++ {
+				if x&(1<<b) > 0 {
+					f[x] ^= f[x^(1<<b)]
+				}
+			}
+		}
+		for x := 0; x < 256; x++ {
+			if f[x] > 0 {
+				anf[j] = append(anf[j], uint8(x))
+			}
+		}
+	}
+	return
+}
+
+func bsLinearMasks(masks *[8][8][BlockSize]uint64, f func(*[BlockSize]byte)) {
+	var blk [BlockSize]byte
+	for q := 0; q < BlockSize; q++ {
+		for i := 0; i < 8; i++ {
+			blk = [BlockSize]byte{}
+			blk[q] = 1 << i
+			f(&blk)
+			for p := 0; p < BlockSize; p++ {
+				for j := 0; j < 8; j++ {
+					if (blk[p]>>j)&1 > 0 {
+						masks[i][j][(q-p+BlockSize)%BlockSize] |= bsReplicate(1 << p)
+					}
+				}
+			}
+		}
+	}
+}
+
+func bsInit() {
+	bsPi = bsANF(&pi)
+	bsPiInv = bsANF(&piInv)
+	bsLinearMasks(&bsL, l)
+	bsLinearMasks(&bsLInv, lInv)
+	for d := 0; d < BlockSize; d++ {
+		lo := uint16(1<<(BlockSize-d) - 1)
+		bsRotLo[d] = bsReplicate(lo)
+		bsRotHi[d] = bsReplicate(^lo)
+	}
+	for i := 0; i < len(bsC); i++ {
+		bsC[i].loadReplicated(cBlk[i][:])
+	}
+}
+
+// Load n blocks into the state. Remaining lanes are zeroed.
+func (x *bsState) load(src []byte, n int) {
+	*x = bsState{}
+	var b uint64
+	for g := 0; g < n; g++ {
+		for p := 0; p < BlockSize; p++ {
+			b = uint64(src[g*BlockSize+p])
+			for i := 0; i < 8; i++ {
+				x[i] |= ((b >> i) & 1) << (BlockSize*g + p)
+			}
+		}
+	}
+}
+
+// Load the same block into all bsBlocks positions.
+func (x *bsState) loadReplicated(blk []byte) {
+	var blks [bsBlocks * BlockSize]byte
+	for g := 0; g < bsBlocks; g++ {
+		copy(blks[g*BlockSize:], blk)
+	}
+	x.load(blks[:], bsBlocks)
+}
+
+func (x *bsState) store(dst []byte, n int) {
+	var b byte
+	for g := 0; g < n; g++ {
+		for p := 0; p < BlockSize; p++ {
+			b = 0
+			for i := 0; i < 8; i++ {
+				b |= byte((x[i]>>(BlockSize*g+p))&1) << i
+			}
+			dst[g*BlockSize+p] = b
+		}
+	}
+}
+
+func (x *bsState) xor(k *bsState) {
+	for i := 0; i < 8; i++ {
+		x[i] ^= k[i]
+	}
+}
+
+func (x *bsState) sbox(anf *[8][]uint8) {
+	var m [256]uint64
+	m[0] = 1<<64 - 1
+	for s := 1; s < 256; s++ {
+		hb := bits.Len8(uint8(s)) - 1
+		m[s] = m[s&^(1<<hb)] & x[hb]
+	}
+	var y uint64
+	for j := 0; j < 8; j++ {
+		y = 0
+		for _, s := range anf[j] {
+			y ^= m[s]
+		}
+		x[j] = y
+	}
+}
+
+func (x *bsState) linear(masks *[8][8][BlockSize]uint64) {
+	var y bsState
+	var r uint64
+	var m *[8][BlockSize]uint64
+	for i := 0; i < 8; i++ {
+		m = &masks[i]
+		for d := 0; d < BlockSize; d++ {
+			r = ((x[i] >> d) & bsRotLo[d]) |
+				((x[i] << (BlockSize - d)) & bsRotHi[d])
+			y[0] ^= r & m[0][d]
+			y[1] ^= r & m[1][d]
+			y[2] ^= r & m[2][d]
+			y[3] ^= r & m[3][d]
+			y[4] ^= r & m[4][d]
+			y[5] ^= r & m[5][d]
+			y[6] ^= r & m[6][d]
+			y[7] ^= r & m[7][d]
+		}
+	}
+	*x = y
+}
+
+// Key schedule performed on the bitsliced state with S and L circuits.
+// Key is loaded into every block, so round keys come out replicated for
+// all of them, ready to be XORed with the state.
+func (c *Cipher) bsExpand(key []byte) {
+	var k0, k1, t bsState
+	k0.loadReplicated(key[:BlockSize])
+	k1.loadReplicated(key[BlockSize:])
+	c.bsKs[0], c.bsKs[1] = k0, k1
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 8; j++ {
+			t = k0
+			t.xor(&bsC[8*i+j])
+			t.sbox(&bsPi)
+			t.linear(&bsL)
+			t.xor(&k1)
+			k0, k1 = t, k0
+		}
+		c.bsKs[2+2*i], c.bsKs[2+2*i+1] = k0, k1
+	}
+}
+
+// Encrypt up to bsBlocks blocks at once.
+func (c *Cipher) bsEncrypt(dst, src []byte, n int) {
+	var x bsState
+	x.load(src, n)
+	for i := 0; i < 9; i++ {
+		x.xor(&c.bsKs[i])
+		x.sbox(&bsPi)
+		x.linear(&bsL)
+	}
+	x.xor(&c.bsKs[9])
+	x.store(dst, n)
+}
+
+// Decrypt up to bsBlocks blocks at once.
+func (c *Cipher) bsDecrypt(dst, src []byte, n int) {
+	var x bsState
+	x.load(src, n)
+	for i := 9; i > 0; i-- {
+		x.xor(&c.bsKs[i])
+		x.linear(&bsLInv)
+		x.sbox(&bsPiInv)
+	}
+	x.xor(&c.bsKs[0])
+	x.store(dst, n)
+}
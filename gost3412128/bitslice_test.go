@@ -0,0 +1,114 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3412128
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/quick"
+)
+
+func TestBitslicedVector(t *testing.T) {
+	c := NewCipher(key, Bitsliced())
+	dst := make([]byte, BlockSize)
+	c.Encrypt(dst, pt[:])
+	if !bytes.Equal(dst, ct[:]) {
+		t.Fatal("encryption failed")
+	}
+	c.Decrypt(dst, ct[:])
+	if !bytes.Equal(dst, pt[:]) {
+		t.Fatal("decryption failed")
+	}
+}
+
+func TestBitslicedS(t *testing.T) {
+	var src [bsBlocks * BlockSize]byte
+	for i := 0; i < len(src); i++ {
+		src[i] = byte(i * 7)
+	}
+	var x bsState
+	x.load(src[:], bsBlocks)
+	x.sbox(&bsPi)
+	var got [bsBlocks * BlockSize]byte
+	x.store(got[:], bsBlocks)
+	for i := 0; i < len(src); i++ {
+		if got[i] != pi[src[i]] {
+			t.Fatal("S differs")
+		}
+	}
+	x.sbox(&bsPiInv)
+	x.store(got[:], bsBlocks)
+	if !bytes.Equal(got[:], src[:]) {
+		t.Fatal("S^-1 differs")
+	}
+}
+
+func TestBitslicedKeySchedule(t *testing.T) {
+	f := func(key [KeySize]byte) bool {
+		ref := NewCipher(key[:])
+		bs := NewCipher(key[:], Bitsliced())
+		// Table-based round keys are not computed at all
+		if bs.ks != ([10][BlockSize]byte{}) ||
+			bs.rke != ([10][2]uint64{}) || bs.rkd != ([10][2]uint64{}) {
+			return false
+		}
+		var k bsState
+		for i := 0; i < 10; i++ {
+			k.loadReplicated(ref.ks[i][:])
+			if k != bs.bsKs[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestBitslicedBlocks(t *testing.T) {
+	f := func(key [KeySize]byte, data []byte) bool {
+		data = data[:len(data)-len(data)%BlockSize]
+		ref := NewCipher(key[:])
+		bs := NewCipher(key[:], Bitsliced())
+		expected := make([]byte, len(data))
+		ref.EncryptBlocks(expected, data)
+		got := make([]byte, len(data))
+		bs.EncryptBlocks(got, data)
+		if !bytes.Equal(got, expected) {
+			return false
+		}
+		bs.DecryptBlocks(got, got)
+		return bytes.Equal(got, data)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkBitslicedEncryptBlocks(b *testing.B) {
+	key := make([]byte, KeySize)
+	io.ReadFull(rand.Reader, key)
+	c := NewCipher(key, Bitsliced())
+	blks := make([]byte, bsBlocks*BlockSize)
+	b.SetBytes(int64(len(blks)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.EncryptBlocks(blks, blks)
+	}
+}
@@ -0,0 +1,112 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3412128
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+type cbcEncrypter struct {
+	c   *Cipher
+	iv0 uint64
+	iv1 uint64
+}
+
+// Create CBC mode encrypter. It is used by crypto/cipher.NewCBCEncrypter.
+func (c *Cipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCEncrypter: IV length must equal block size")
+	}
+	return &cbcEncrypter{
+		c:   c,
+		iv0: binary.LittleEndian.Uint64(iv[:8]),
+		iv1: binary.LittleEndian.Uint64(iv[8:]),
+	}
+}
+
+func (e *cbcEncrypter) BlockSize() int {
+	return BlockSize
+}
+
+func (e *cbcEncrypter) CryptBlocks(dst, src []byte) {
+	validateBlocks(dst, src)
+	if e.c.bsKs != nil {
+		var blk [BlockSize]byte
+		for len(src) > 0 {
+			binary.LittleEndian.PutUint64(blk[:8], e.iv0^binary.LittleEndian.Uint64(src[:8]))
+			binary.LittleEndian.PutUint64(blk[8:], e.iv1^binary.LittleEndian.Uint64(src[8:]))
+			e.c.bsEncrypt(dst, blk[:], 1)
+			e.iv0 = binary.LittleEndian.Uint64(dst[:8])
+			e.iv1 = binary.LittleEndian.Uint64(dst[8:])
+			dst, src = dst[BlockSize:], src[BlockSize:]
+		}
+		return
+	}
+	for len(src) > 0 {
+		e.iv0, e.iv1 = e.c.encryptWords(
+			e.iv0^binary.LittleEndian.Uint64(src[:8]),
+			e.iv1^binary.LittleEndian.Uint64(src[8:]),
+		)
+		binary.LittleEndian.PutUint64(dst[:8], e.iv0)
+		binary.LittleEndian.PutUint64(dst[8:], e.iv1)
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
+}
+
+// Maximal number of blocks decrypted at once.
+const cbcBlocks = 32
+
+type cbcDecrypter struct {
+	c   *Cipher
+	iv  [BlockSize]byte
+	buf [cbcBlocks * BlockSize]byte
+}
+
+// Create CBC mode decrypter. It is used by crypto/cipher.NewCBCDecrypter
+// and, unlike generic implementation, decrypts multiple blocks at once.
+func (c *Cipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCDecrypter: IV length must equal block size")
+	}
+	d := cbcDecrypter{c: c}
+	copy(d.iv[:], iv)
+	return &d
+}
+
+func (d *cbcDecrypter) BlockSize() int {
+	return BlockSize
+}
+
+func (d *cbcDecrypter) CryptBlocks(dst, src []byte) {
+	validateBlocks(dst, src)
+	var n int
+	for len(src) > 0 {
+		n = len(src)
+		if n > len(d.buf) {
+			n = len(d.buf)
+		}
+		// Ciphertext is saved, as dst and src may overlap
+		copy(d.buf[:n], src)
+		d.c.DecryptBlocks(dst[:n], src[:n])
+		xor(dst, dst, d.iv[:])
+		for i := BlockSize; i < n; i += BlockSize {
+			xor(dst[i:], dst[i:], d.buf[i-BlockSize:])
+		}
+		copy(d.iv[:], d.buf[n-BlockSize:n])
+		dst, src = dst[n:], src[n:]
+	}
+}
@@ -0,0 +1,88 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3412128
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+	"testing"
+	"testing/quick"
+)
+
+func TestCBCGeneric(t *testing.T) {
+	f := func(key [KeySize]byte, iv [BlockSize]byte, data []byte, bs bool) bool {
+		data = data[:len(data)-len(data)%BlockSize]
+		var c *Cipher
+		if bs {
+			c = NewCipher(key[:], Bitsliced())
+		} else {
+			c = NewCipher(key[:])
+		}
+		expected := make([]byte, len(data))
+		cipher.NewCBCEncrypter(blockOnly{c}, iv[:]).CryptBlocks(expected, data)
+		got := make([]byte, len(data))
+		e := cipher.NewCBCEncrypter(c, iv[:])
+		if _, ok := e.(*cbcEncrypter); !ok {
+			return false
+		}
+		half := len(data) / BlockSize / 2 * BlockSize
+		e.CryptBlocks(got[:half], data[:half])
+		e.CryptBlocks(got[half:], data[half:])
+		if !bytes.Equal(got, expected) {
+			return false
+		}
+		d := cipher.NewCBCDecrypter(c, iv[:])
+		if _, ok := d.(*cbcDecrypter); !ok {
+			return false
+		}
+		d.CryptBlocks(got[:half], got[:half])
+		d.CryptBlocks(got[half:], got[half:])
+		return bytes.Equal(got, data)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCBCLong(t *testing.T) {
+	data := make([]byte, 3*cbcBlocks*BlockSize+3*BlockSize)
+	io.ReadFull(rand.Reader, data)
+	iv := make([]byte, BlockSize)
+	c := NewCipher(key)
+	ct := make([]byte, len(data))
+	cipher.NewCBCEncrypter(c, iv).CryptBlocks(ct, data)
+	expected := make([]byte, len(data))
+	cipher.NewCBCDecrypter(blockOnly{c}, iv).CryptBlocks(expected, ct)
+	cipher.NewCBCDecrypter(c, iv).CryptBlocks(ct, ct)
+	if !bytes.Equal(ct, expected) || !bytes.Equal(ct, data) {
+		t.FailNow()
+	}
+}
+
+func BenchmarkCBCDecrypt(b *testing.B) {
+	key := make([]byte, KeySize)
+	io.ReadFull(rand.Reader, key)
+	iv := make([]byte, BlockSize)
+	buf := make([]byte, 1<<10)
+	d := cipher.NewCBCDecrypter(NewCipher(key), iv)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.CryptBlocks(buf, buf)
+	}
+}
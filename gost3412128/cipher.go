@@ -16,6 +16,10 @@
 // GOST 34.12-2015 128-bit (Кузнечик (Kuznechik)) block cipher.
 package gost3412128
 
+import (
+	"encoding/binary"
+)
+
 const (
 	BlockSize = 16
 	KeySize   = 32
@@ -54,6 +58,12 @@ var (
 	piInv   [256]byte
 	cBlk    [32]*[BlockSize]byte
 	gfCache [256][256]byte
+
+	// Precomputed L(S(x)) and L^-1(S^-1(x)) values for each byte
+	// position of the block. Each 128-bit value is kept as a pair of
+	// little-endian 64-bit words.
+	lsTable    [BlockSize][256][2]uint64
+	lsInvTable [BlockSize][256][2]uint64
 )
 
 func gf(a, b byte) (c byte) {
@@ -185,20 +195,135 @@ func init() {
 		cBlk[i][15] = byte(i) + 1
 		l(cBlk[i])
 	}
+	var blk [BlockSize]byte
+	for i := 0; i < BlockSize; i++ {
+		for b := 0; b < 256; b++ {
+			blk = [BlockSize]byte{}
+			blk[i] = pi[b]
+			l(&blk)
+			lsTable[i][b] = blk2words(&blk)
+			blk = [BlockSize]byte{}
+			blk[i] = piInv[b]
+			lInv(&blk)
+			lsInvTable[i][b] = blk2words(&blk)
+		}
+	}
+	bsInit()
+}
+
+func blk2words(blk *[BlockSize]byte) [2]uint64 {
+	return [2]uint64{
+		binary.LittleEndian.Uint64(blk[:8]),
+		binary.LittleEndian.Uint64(blk[8:]),
+	}
+}
+
+// Apply tabled linear transformation to the block represented as two
+// 64-bit words. With lsTable it is L(S(x)), with lsInvTable it is
+// L^-1(S^-1(x)).
+func lsWords(t *[BlockSize][256][2]uint64, x0, x1 uint64) (y0, y1 uint64) {
+	y0 = t[0][byte(x0>>0)][0] ^
+		t[8][byte(x1>>0)][0] ^
+		t[1][byte(x0>>8)][0] ^
+		t[9][byte(x1>>8)][0] ^
+		t[2][byte(x0>>16)][0] ^
+		t[10][byte(x1>>16)][0] ^
+		t[3][byte(x0>>24)][0] ^
+		t[11][byte(x1>>24)][0] ^
+		t[4][byte(x0>>32)][0] ^
+		t[12][byte(x1>>32)][0] ^
+		t[5][byte(x0>>40)][0] ^
+		t[13][byte(x1>>40)][0] ^
+		t[6][byte(x0>>48)][0] ^
+		t[14][byte(x1>>48)][0] ^
+		t[7][byte(x0>>56)][0] ^
+		t[15][byte(x1>>56)][0]
+	y1 = t[0][byte(x0>>0)][1] ^
+		t[8][byte(x1>>0)][1] ^
+		t[1][byte(x0>>8)][1] ^
+		t[9][byte(x1>>8)][1] ^
+		t[2][byte(x0>>16)][1] ^
+		t[10][byte(x1>>16)][1] ^
+		t[3][byte(x0>>24)][1] ^
+		t[11][byte(x1>>24)][1] ^
+		t[4][byte(x0>>32)][1] ^
+		t[12][byte(x1>>32)][1] ^
+		t[5][byte(x0>>40)][1] ^
+		t[13][byte(x1>>40)][1] ^
+		t[6][byte(x0>>48)][1] ^
+		t[14][byte(x1>>48)][1] ^
+		t[7][byte(x0>>56)][1] ^
+		t[15][byte(x1>>56)][1]
+	return
+}
+
+// L^-1(x) through lsInvTable: S^-1 is cancelled by preliminary S.
+func lInvWords(x0, x1 uint64) (y0, y1 uint64) {
+	var e *[2]uint64
+	for i := 0; i < 8; i++ {
+		e = &lsInvTable[i][pi[byte(x0>>(8*i))]]
+		y0 ^= e[0]
+		y1 ^= e[1]
+		e = &lsInvTable[8+i][pi[byte(x1>>(8*i))]]
+		y0 ^= e[0]
+		y1 ^= e[1]
+	}
+	return
+}
+
+func sInvWord(x uint64) (y uint64) {
+	for i := 0; i < 8; i++ {
+		y |= uint64(piInv[byte(x>>(8*i))]) << (8 * i)
+	}
+	return
 }
 
 type Cipher struct {
 	ks [10][BlockSize]byte
+
+	// Encryption round keys and decryption ones, already transformed
+	// with L^-1, as little-endian 64-bit words.
+	rke [10][2]uint64
+	rkd [10][2]uint64
+
+	// Round keys for bitsliced implementation, nil if it is not used.
+	bsKs *[10]bsState
+}
+
+// Cipher creation option.
+type Option func(*Cipher)
+
+// Use constant-time bitsliced implementation instead of the table-based
+// one, which is vulnerable to cache-timing attacks. Key schedule is also
+// bitsliced then. It is much slower, especially when blocks are
+// processed one by one, so EncryptBlocks and DecryptBlocks are
+// preferable.
+func Bitsliced() Option {
+	return func(c *Cipher) { c.bsKs = new([10]bsState) }
 }
 
 func (c *Cipher) BlockSize() int {
 	return BlockSize
 }
 
-func NewCipher(key []byte) *Cipher {
+func NewCipher(key []byte, opts ...Option) *Cipher {
 	if len(key) != KeySize {
 		panic("invalid key size")
 	}
+	var c Cipher
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.bsKs != nil {
+		c.bsExpand(key)
+	} else {
+		c.expand(key)
+	}
+	return &c
+}
+
+// Table-based key schedule.
+func (c *Cipher) expand(key []byte) {
 	var ks [10][BlockSize]byte
 	var kr0 [BlockSize]byte
 	var kr1 [BlockSize]byte
@@ -219,28 +344,191 @@ func NewCipher(key []byte) *Cipher {
 		copy(ks[2+2*i][:], kr0[:])
 		copy(ks[2+2*i+1][:], kr1[:])
 	}
-	return &Cipher{ks}
+	c.ks = ks
+	for i := 0; i < 10; i++ {
+		c.rke[i] = blk2words(&ks[i])
+		copy(krt[:], ks[i][:])
+		lInv(&krt)
+		c.rkd[i] = blk2words(&krt)
+	}
 }
 
-func (c *Cipher) Encrypt(dst, src []byte) {
-	blk := new([BlockSize]byte)
-	copy(blk[:], src)
+func (c *Cipher) encryptWords(x0, x1 uint64) (uint64, uint64) {
 	for i := 0; i < 9; i++ {
-		xor(blk[:], blk[:], c.ks[i][:])
-		s(blk)
-		l(blk)
+		x0, x1 = lsWords(&lsTable, x0^c.rke[i][0], x1^c.rke[i][1])
 	}
-	xor(blk[:], blk[:], c.ks[9][:])
-	copy(dst, blk[:])
+	return x0 ^ c.rke[9][0], x1 ^ c.rke[9][1]
+}
+
+// Decryption is performed in L^-1-transformed domain: as L^-1 is
+// linear, L^-1(x xor k) = L^-1(x) xor L^-1(k), so round keys are
+// transformed beforehand and each round needs only single table pass.
+func (c *Cipher) decryptWords(x0, x1 uint64) (uint64, uint64) {
+	x0, x1 = lInvWords(x0, x1)
+	for i := 9; i > 1; i-- {
+		x0, x1 = lsWords(&lsInvTable, x0^c.rkd[i][0], x1^c.rkd[i][1])
+	}
+	return sInvWord(x0^c.rkd[1][0]) ^ c.rke[0][0],
+		sInvWord(x1^c.rkd[1][1]) ^ c.rke[0][1]
+}
+
+func (c *Cipher) Encrypt(dst, src []byte) {
+	_, _ = src[BlockSize-1], dst[BlockSize-1]
+	if c.bsKs != nil {
+		c.bsEncrypt(dst, src, 1)
+		return
+	}
+	x0, x1 := c.encryptWords(
+		binary.LittleEndian.Uint64(src[:8]),
+		binary.LittleEndian.Uint64(src[8:]),
+	)
+	binary.LittleEndian.PutUint64(dst[:8], x0)
+	binary.LittleEndian.PutUint64(dst[8:], x1)
 }
 
 func (c *Cipher) Decrypt(dst, src []byte) {
-	blk := new([BlockSize]byte)
-	copy(blk[:], src)
-	for i := 9; i > 0; i-- {
-		xor(blk[:], blk[:], c.ks[i][:])
-		lInv(blk)
-		sInv(blk)
-	}
-	xor(dst, blk[:], c.ks[0][:])
+	_, _ = src[BlockSize-1], dst[BlockSize-1]
+	if c.bsKs != nil {
+		c.bsDecrypt(dst, src, 1)
+		return
+	}
+	x0, x1 := c.decryptWords(
+		binary.LittleEndian.Uint64(src[:8]),
+		binary.LittleEndian.Uint64(src[8:]),
+	)
+	binary.LittleEndian.PutUint64(dst[:8], x0)
+	binary.LittleEndian.PutUint64(dst[8:], x1)
+}
+
+// Number of blocks processed simultaneously by table-based
+// implementation: each round key is applied to all of them in turn,
+// giving independent instruction streams to the processor.
+const batchBlocks = 4
+
+func (c *Cipher) encryptBatch(dst, src []byte) {
+	_, _ = src[batchBlocks*BlockSize-1], dst[batchBlocks*BlockSize-1]
+	a0 := binary.LittleEndian.Uint64(src[0:])
+	a1 := binary.LittleEndian.Uint64(src[8:])
+	b0 := binary.LittleEndian.Uint64(src[16:])
+	b1 := binary.LittleEndian.Uint64(src[24:])
+	c0 := binary.LittleEndian.Uint64(src[32:])
+	c1 := binary.LittleEndian.Uint64(src[40:])
+	d0 := binary.LittleEndian.Uint64(src[48:])
+	d1 := binary.LittleEndian.Uint64(src[56:])
+	var k *[2]uint64
+	for i := 0; i < 9; i++ {
+		k = &c.rke[i]
+		a0, a1 = lsWords(&lsTable, a0^k[0], a1^k[1])
+		b0, b1 = lsWords(&lsTable, b0^k[0], b1^k[1])
+		c0, c1 = lsWords(&lsTable, c0^k[0], c1^k[1])
+		d0, d1 = lsWords(&lsTable, d0^k[0], d1^k[1])
+	}
+	k = &c.rke[9]
+	binary.LittleEndian.PutUint64(dst[0:], a0^k[0])
+	binary.LittleEndian.PutUint64(dst[8:], a1^k[1])
+	binary.LittleEndian.PutUint64(dst[16:], b0^k[0])
+	binary.LittleEndian.PutUint64(dst[24:], b1^k[1])
+	binary.LittleEndian.PutUint64(dst[32:], c0^k[0])
+	binary.LittleEndian.PutUint64(dst[40:], c1^k[1])
+	binary.LittleEndian.PutUint64(dst[48:], d0^k[0])
+	binary.LittleEndian.PutUint64(dst[56:], d1^k[1])
+}
+
+func (c *Cipher) decryptBatch(dst, src []byte) {
+	_, _ = src[batchBlocks*BlockSize-1], dst[batchBlocks*BlockSize-1]
+	a0, a1 := lInvWords(
+		binary.LittleEndian.Uint64(src[0:]),
+		binary.LittleEndian.Uint64(src[8:]),
+	)
+	b0, b1 := lInvWords(
+		binary.LittleEndian.Uint64(src[16:]),
+		binary.LittleEndian.Uint64(src[24:]),
+	)
+	c0, c1 := lInvWords(
+		binary.LittleEndian.Uint64(src[32:]),
+		binary.LittleEndian.Uint64(src[40:]),
+	)
+	d0, d1 := lInvWords(
+		binary.LittleEndian.Uint64(src[48:]),
+		binary.LittleEndian.Uint64(src[56:]),
+	)
+	var k *[2]uint64
+	for i := 9; i > 1; i-- {
+		k = &c.rkd[i]
+		a0, a1 = lsWords(&lsInvTable, a0^k[0], a1^k[1])
+		b0, b1 = lsWords(&lsInvTable, b0^k[0], b1^k[1])
+		c0, c1 = lsWords(&lsInvTable, c0^k[0], c1^k[1])
+		d0, d1 = lsWords(&lsInvTable, d0^k[0], d1^k[1])
+	}
+	k = &c.rkd[1]
+	k0 := &c.rke[0]
+	binary.LittleEndian.PutUint64(dst[0:], sInvWord(a0^k[0])^k0[0])
+	binary.LittleEndian.PutUint64(dst[8:], sInvWord(a1^k[1])^k0[1])
+	binary.LittleEndian.PutUint64(dst[16:], sInvWord(b0^k[0])^k0[0])
+	binary.LittleEndian.PutUint64(dst[24:], sInvWord(b1^k[1])^k0[1])
+	binary.LittleEndian.PutUint64(dst[32:], sInvWord(c0^k[0])^k0[0])
+	binary.LittleEndian.PutUint64(dst[40:], sInvWord(c1^k[1])^k0[1])
+	binary.LittleEndian.PutUint64(dst[48:], sInvWord(d0^k[0])^k0[0])
+	binary.LittleEndian.PutUint64(dst[56:], sInvWord(d1^k[1])^k0[1])
+}
+
+func validateBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+}
+
+// Encrypt multiple consecutive blocks. len(src) must be a multiple of
+// the block size. Several blocks are processed simultaneously.
+func (c *Cipher) EncryptBlocks(dst, src []byte) {
+	validateBlocks(dst, src)
+	var n int
+	if c.bsKs != nil {
+		for len(src) > 0 {
+			n = len(src) / BlockSize
+			if n > bsBlocks {
+				n = bsBlocks
+			}
+			c.bsEncrypt(dst, src, n)
+			dst, src = dst[n*BlockSize:], src[n*BlockSize:]
+		}
+		return
+	}
+	for len(src) >= batchBlocks*BlockSize {
+		c.encryptBatch(dst, src)
+		dst, src = dst[batchBlocks*BlockSize:], src[batchBlocks*BlockSize:]
+	}
+	for len(src) > 0 {
+		c.Encrypt(dst, src)
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
+}
+
+// Decrypt multiple consecutive blocks. len(src) must be a multiple of
+// the block size.
+func (c *Cipher) DecryptBlocks(dst, src []byte) {
+	validateBlocks(dst, src)
+	var n int
+	if c.bsKs != nil {
+		for len(src) > 0 {
+			n = len(src) / BlockSize
+			if n > bsBlocks {
+				n = bsBlocks
+			}
+			c.bsDecrypt(dst, src, n)
+			dst, src = dst[n*BlockSize:], src[n*BlockSize:]
+		}
+		return
+	}
+	for len(src) >= batchBlocks*BlockSize {
+		c.decryptBatch(dst, src)
+		dst, src = dst[batchBlocks*BlockSize:], src[batchBlocks*BlockSize:]
+	}
+	for len(src) > 0 {
+		c.Decrypt(dst, src)
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
 }
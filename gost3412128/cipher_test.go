@@ -327,3 +327,26 @@ func TestVectorDecrypt(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestNoAllocs(t *testing.T) {
+	c := NewCipher(key)
+	blk := make([]byte, BlockSize)
+	if n := testing.AllocsPerRun(100, func() {
+		c.Encrypt(blk, blk)
+		c.Decrypt(blk, blk)
+	}); n != 0 {
+		t.Fatalf("%f allocations", n)
+	}
+}
+
+func BenchmarkEncryptBlocks(b *testing.B) {
+	key := make([]byte, KeySize)
+	io.ReadFull(rand.Reader, key)
+	c := NewCipher(key)
+	blks := make([]byte, 32*BlockSize)
+	b.SetBytes(int64(len(blks)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.EncryptBlocks(blks, blks)
+	}
+}
@@ -0,0 +1,107 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3412128
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"math/bits"
+)
+
+// Maximal number of keystream blocks generated at once.
+const ctrBlocks = 32
+
+// CTR mode stream with ability to seek to arbitrary offset.
+type CTR struct {
+	c        *Cipher
+	hi0, lo0 uint64
+	hi, lo   uint64
+	ctrs     [ctrBlocks * BlockSize]byte
+	ks       [ctrBlocks * BlockSize]byte
+	ksOff    int
+	ksLen    int
+}
+
+// Create CTR mode stream, with big-endian counter incremented over the
+// whole block. It is used by crypto/cipher.NewCTR and, unlike generic
+// implementation, encrypts multiple counter blocks at once. Returned
+// stream is *CTR.
+func (c *Cipher) NewCTR(iv []byte) cipher.Stream {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCTR: IV length must equal block size")
+	}
+	return newCTR(c, binary.BigEndian.Uint64(iv[:8]), binary.BigEndian.Uint64(iv[8:]))
+}
+
+// Create GOST R 34.13-2015 CTR mode stream. IV is half the block size
+// long and counter's initial value is IV||0.
+func (c *Cipher) NewCTR3413(iv []byte) *CTR {
+	if len(iv) != BlockSize/2 {
+		panic("gogost/gost3412128: IV length must equal half of block size")
+	}
+	return newCTR(c, binary.BigEndian.Uint64(iv), 0)
+}
+
+func newCTR(c *Cipher, hi, lo uint64) *CTR {
+	return &CTR{c: c, hi0: hi, lo0: lo, hi: hi, lo: lo}
+}
+
+// Set the keystream position to the given byte offset from the
+// beginning of the stream, as if that number of bytes was already
+// processed. It takes constant time, allowing random access decryption.
+func (s *CTR) SetOffset(offset uint64) {
+	var carry uint64
+	s.lo, carry = bits.Add64(s.lo0, offset/BlockSize, 0)
+	s.hi = s.hi0 + carry
+	s.ksOff, s.ksLen = 0, 0
+	if r := int(offset % BlockSize); r > 0 {
+		s.refill(1)
+		s.ksOff = r
+	}
+}
+
+func (s *CTR) refill(blocks int) {
+	if blocks > ctrBlocks {
+		blocks = ctrBlocks
+	}
+	for i := 0; i < blocks; i++ {
+		binary.BigEndian.PutUint64(s.ctrs[i*BlockSize:], s.hi)
+		binary.BigEndian.PutUint64(s.ctrs[i*BlockSize+8:], s.lo)
+		s.lo++
+		if s.lo == 0 {
+			s.hi++
+		}
+	}
+	s.ksLen = blocks * BlockSize
+	s.c.EncryptBlocks(s.ks[:s.ksLen], s.ctrs[:s.ksLen])
+	s.ksOff = 0
+}
+
+func (s *CTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var n int
+	for len(src) > 0 {
+		if s.ksOff == s.ksLen {
+			s.refill((len(src) + BlockSize - 1) / BlockSize)
+		}
+		n = subtle.XORBytes(dst, src, s.ks[s.ksOff:s.ksLen])
+		s.ksOff += n
+		dst, src = dst[n:], src[n:]
+	}
+}
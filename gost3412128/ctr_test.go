@@ -0,0 +1,131 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3412128
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"testing"
+	"testing/quick"
+)
+
+// Hide optimised modes, forcing crypto/cipher to use generic ones.
+type blockOnly struct{ cipher.Block }
+
+func TestCTR3413Vector(t *testing.T) {
+	pt, _ := hex.DecodeString("" +
+		"1122334455667700ffeeddccbbaa9988" +
+		"00112233445566778899aabbcceeff0a" +
+		"112233445566778899aabbcceeff0a00" +
+		"2233445566778899aabbcceeff0a0011")
+	ct, _ := hex.DecodeString("" +
+		"f195d8bec10ed1dbd57b5fa240bda1b8" +
+		"85eee733f6a13e5df33ce4b33c45dee4" +
+		"a5eae88be6356ed3d5e877f13564a3a5" +
+		"cb91fab1f20cbab6d1c6d15820bdba73")
+	iv := []byte{0x12, 0x34, 0x56, 0x78, 0x90, 0xab, 0xce, 0xf0}
+	dst := make([]byte, len(pt))
+	NewCipher(key).NewCTR3413(iv).XORKeyStream(dst, pt)
+	if !bytes.Equal(dst, ct) {
+		t.Fatal("encryption failed")
+	}
+	NewCipher(key, Bitsliced()).NewCTR3413(iv).XORKeyStream(dst, dst)
+	if !bytes.Equal(dst, pt) {
+		t.Fatal("decryption failed")
+	}
+}
+
+func TestCTRGeneric(t *testing.T) {
+	f := func(key [KeySize]byte, iv [BlockSize]byte, data []byte, chunks []uint8) bool {
+		c := NewCipher(key[:])
+		expected := make([]byte, len(data))
+		cipher.NewCTR(blockOnly{c}, iv[:]).XORKeyStream(expected, data)
+		got := make([]byte, len(data))
+		stream := cipher.NewCTR(c, iv[:])
+		if _, ok := stream.(*CTR); !ok {
+			return false
+		}
+		var n int
+		for off := 0; off < len(data); off += n {
+			n = len(data) - off
+			if len(chunks) > 0 {
+				if int(chunks[0]) < n {
+					n = int(chunks[0])
+				}
+				chunks = chunks[1:]
+			}
+			stream.XORKeyStream(got[off:off+n], data[off:off+n])
+		}
+		return bytes.Equal(got, expected)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCTROverflow(t *testing.T) {
+	iv := bytes.Repeat([]byte{0xFF}, BlockSize)
+	c := NewCipher(key)
+	data := make([]byte, 5*BlockSize)
+	expected := make([]byte, len(data))
+	cipher.NewCTR(blockOnly{c}, iv).XORKeyStream(expected, data)
+	got := make([]byte, len(data))
+	c.NewCTR(iv).XORKeyStream(got, data)
+	if !bytes.Equal(got, expected) {
+		t.FailNow()
+	}
+}
+
+func BenchmarkCTR(b *testing.B) {
+	key := make([]byte, KeySize)
+	io.ReadFull(rand.Reader, key)
+	iv := make([]byte, BlockSize)
+	buf := make([]byte, 1<<10)
+	ctr := cipher.NewCTR(NewCipher(key), iv)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctr.XORKeyStream(buf, buf)
+	}
+}
+
+func TestCTRSetOffset(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	c := NewCipher(key)
+	// Lower half of the counter overflows in the middle
+	iv := []byte{
+		0x12, 0x34, 0x56, 0x78, 0x90, 0xab, 0xcd, 0xef,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xf0,
+	}
+	ks := make([]byte, 64*BlockSize)
+	cipher.NewCTR(blockOnly{c}, iv).XORKeyStream(ks, ks)
+	ctr := c.NewCTR(iv).(*CTR)
+	f := func(off, n uint16) bool {
+		o := int(off) % len(ks)
+		l := int(n) % (len(ks) - o)
+		got := make([]byte, l)
+		ctr.SetOffset(uint64(o))
+		ctr.XORKeyStream(got, got)
+		return bytes.Equal(got, ks[o:o+l])
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
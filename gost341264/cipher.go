@@ -17,6 +17,10 @@
 package gost341264
 
 import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+
 	"github.com/pedroalbanese/gogost/gost28147"
 )
 
@@ -25,68 +29,269 @@ const (
 	KeySize   = 32
 )
 
+// Precomputed round function with the id-tc26-gost-28147-param-Z Sbox.
+var table = gost28147.SboxIdtc26gost28147paramZ.Table()
+
+// Block is processed as a single big-endian 64-bit word, whose higher
+// half is a1 and lower one is a0, in terms of the standard.
 type Cipher struct {
-	c   *gost28147.Cipher
-	blk *[BlockSize]byte
+	k [8]uint32
 }
 
 func NewCipher(key []byte) *Cipher {
 	if len(key) != KeySize {
 		panic("invalid key size")
 	}
-	keyCompatible := make([]byte, KeySize)
-	for i := 0; i < KeySize/4; i++ {
-		keyCompatible[i*4+0] = key[i*4+3]
-		keyCompatible[i*4+1] = key[i*4+2]
-		keyCompatible[i*4+2] = key[i*4+1]
-		keyCompatible[i*4+3] = key[i*4+0]
-	}
-	return &Cipher{
-		c:   gost28147.NewCipher(keyCompatible, &gost28147.SboxIdtc26gost28147paramZ),
-		blk: new([BlockSize]byte),
+	var c Cipher
+	for i := 0; i < 8; i++ {
+		c.k[i] = binary.BigEndian.Uint32(key[i*4:])
 	}
+	return &c
 }
 
 func (c *Cipher) BlockSize() int {
 	return BlockSize
 }
 
+func (c *Cipher) xcrypt(seq gost28147.Seq, w uint64) uint64 {
+	a0, a1 := uint32(w), uint32(w>>32)
+	t := table
+	for _, i := range seq {
+		a0, a1 = t.F(a0+c.k[i])^a1, a0
+	}
+	return uint64(a0)<<32 | uint64(a1)
+}
+
+// Encrypt single block.
+// If provided slices are shorter than the block size, then it will panic.
 func (c *Cipher) Encrypt(dst, src []byte) {
-	c.blk[0] = src[7]
-	c.blk[1] = src[6]
-	c.blk[2] = src[5]
-	c.blk[3] = src[4]
-	c.blk[4] = src[3]
-	c.blk[5] = src[2]
-	c.blk[6] = src[1]
-	c.blk[7] = src[0]
-	c.c.Encrypt(c.blk[:], c.blk[:])
-	dst[0] = c.blk[7]
-	dst[1] = c.blk[6]
-	dst[2] = c.blk[5]
-	dst[3] = c.blk[4]
-	dst[4] = c.blk[3]
-	dst[5] = c.blk[2]
-	dst[6] = c.blk[1]
-	dst[7] = c.blk[0]
+	binary.BigEndian.PutUint64(dst, c.xcrypt(
+		gost28147.SeqEncrypt, binary.BigEndian.Uint64(src),
+	))
 }
 
+// Decrypt single block.
+// If provided slices are shorter than the block size, then it will panic.
 func (c *Cipher) Decrypt(dst, src []byte) {
-	c.blk[0] = src[7]
-	c.blk[1] = src[6]
-	c.blk[2] = src[5]
-	c.blk[3] = src[4]
-	c.blk[4] = src[3]
-	c.blk[5] = src[2]
-	c.blk[6] = src[1]
-	c.blk[7] = src[0]
-	c.c.Decrypt(c.blk[:], c.blk[:])
-	dst[0] = c.blk[7]
-	dst[1] = c.blk[6]
-	dst[2] = c.blk[5]
-	dst[3] = c.blk[4]
-	dst[4] = c.blk[3]
-	dst[5] = c.blk[2]
-	dst[6] = c.blk[1]
-	dst[7] = c.blk[0]
+	binary.BigEndian.PutUint64(dst, c.xcrypt(
+		gost28147.SeqDecrypt, binary.BigEndian.Uint64(src),
+	))
+}
+
+// Number of blocks processed simultaneously by xcrypt4.
+const interleave = 4
+
+// Same as xcrypt, but for four blocks at once, giving independent
+// instruction streams to the processor.
+func (c *Cipher) xcrypt4(seq gost28147.Seq, w *[interleave]uint64) {
+	a0, a1 := uint32(w[0]), uint32(w[0]>>32)
+	b0, b1 := uint32(w[1]), uint32(w[1]>>32)
+	c0, c1 := uint32(w[2]), uint32(w[2]>>32)
+	d0, d1 := uint32(w[3]), uint32(w[3]>>32)
+	t := table
+	var k uint32
+	for _, i := range seq {
+		k = c.k[i]
+		a0, a1 = t.F(a0+k)^a1, a0
+		b0, b1 = t.F(b0+k)^b1, b0
+		c0, c1 = t.F(c0+k)^c1, c0
+		d0, d1 = t.F(d0+k)^d1, d0
+	}
+	w[0] = uint64(a0)<<32 | uint64(a1)
+	w[1] = uint64(b0)<<32 | uint64(b1)
+	w[2] = uint64(c0)<<32 | uint64(c1)
+	w[3] = uint64(d0)<<32 | uint64(d1)
+}
+
+// Maximal number of blocks of keystream buffered by CTR mode.
+const batchBlocks = 32
+
+func validateBlocks(dst, src []byte) {
+	if len(src)%BlockSize != 0 {
+		panic("input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+}
+
+func (c *Cipher) xcryptBlocks(seq gost28147.Seq, dst, src []byte) {
+	validateBlocks(dst, src)
+	var w [interleave]uint64
+	for len(src) >= interleave*BlockSize {
+		for i := 0; i < interleave; i++ {
+			w[i] = binary.BigEndian.Uint64(src[i*BlockSize:])
+		}
+		c.xcrypt4(seq, &w)
+		for i := 0; i < interleave; i++ {
+			binary.BigEndian.PutUint64(dst[i*BlockSize:], w[i])
+		}
+		dst, src = dst[interleave*BlockSize:], src[interleave*BlockSize:]
+	}
+	for len(src) > 0 {
+		binary.BigEndian.PutUint64(dst, c.xcrypt(seq, binary.BigEndian.Uint64(src)))
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
+}
+
+// Encrypt multiple consecutive blocks. len(src) must be a multiple of
+// the block size. Several blocks are processed simultaneously.
+func (c *Cipher) EncryptBlocks(dst, src []byte) {
+	c.xcryptBlocks(gost28147.SeqEncrypt, dst, src)
+}
+
+// Decrypt multiple consecutive blocks. len(src) must be a multiple of
+// the block size.
+func (c *Cipher) DecryptBlocks(dst, src []byte) {
+	c.xcryptBlocks(gost28147.SeqDecrypt, dst, src)
+}
+
+// CTR mode stream with ability to seek to arbitrary offset.
+type CTR struct {
+	c     *Cipher
+	ctr0  uint64
+	ctr   uint64
+	ks    [batchBlocks * BlockSize]byte
+	ksOff int
+	ksLen int
+}
+
+// Create CTR mode stream, with big-endian counter incremented over the
+// whole block. It is used by crypto/cipher.NewCTR and, unlike generic
+// implementation, encrypts multiple counter blocks at once. Returned
+// stream is *CTR.
+func (c *Cipher) NewCTR(iv []byte) cipher.Stream {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCTR: IV length must equal block size")
+	}
+	v := binary.BigEndian.Uint64(iv)
+	return &CTR{c: c, ctr0: v, ctr: v}
+}
+
+// Create GOST R 34.13-2015 CTR mode stream. IV is half the block size
+// long and counter's initial value is IV||0.
+func (c *Cipher) NewCTR3413(iv []byte) *CTR {
+	if len(iv) != BlockSize/2 {
+		panic("gogost/gost341264: IV length must equal half of block size")
+	}
+	v := uint64(binary.BigEndian.Uint32(iv)) << 32
+	return &CTR{c: c, ctr0: v, ctr: v}
+}
+
+// Set the keystream position to the given byte offset from the
+// beginning of the stream, as if that number of bytes was already
+// processed. It takes constant time, allowing random access decryption.
+func (s *CTR) SetOffset(offset uint64) {
+	s.ctr = s.ctr0 + offset/BlockSize
+	s.ksOff, s.ksLen = 0, 0
+	if r := int(offset % BlockSize); r > 0 {
+		s.refill(1)
+		s.ksOff = r
+	}
+}
+
+func (s *CTR) refill(blocks int) {
+	if blocks > batchBlocks {
+		blocks = batchBlocks
+	}
+	// Always whole interleaved groups: extra keystream is kept for
+	// the next call anyway
+	blocks = (blocks + interleave - 1) / interleave * interleave
+	var w [interleave]uint64
+	for i := 0; i < blocks; i += interleave {
+		for j := 0; j < interleave; j++ {
+			w[j] = s.ctr
+			s.ctr++
+		}
+		s.c.xcrypt4(gost28147.SeqEncrypt, &w)
+		for j := 0; j < interleave; j++ {
+			binary.BigEndian.PutUint64(s.ks[(i+j)*BlockSize:], w[j])
+		}
+	}
+	s.ksLen = blocks * BlockSize
+	s.ksOff = 0
+}
+
+func (s *CTR) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	var n int
+	for len(src) > 0 {
+		if s.ksOff == s.ksLen {
+			s.refill((len(src) + BlockSize - 1) / BlockSize)
+		}
+		n = subtle.XORBytes(dst, src, s.ks[s.ksOff:s.ksLen])
+		s.ksOff += n
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+type cbcEncrypter struct {
+	c  *Cipher
+	iv uint64
+}
+
+// Create CBC mode encrypter. It is used by crypto/cipher.NewCBCEncrypter.
+func (c *Cipher) NewCBCEncrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCEncrypter: IV length must equal block size")
+	}
+	return &cbcEncrypter{c: c, iv: binary.BigEndian.Uint64(iv)}
+}
+
+func (e *cbcEncrypter) BlockSize() int {
+	return BlockSize
+}
+
+func (e *cbcEncrypter) CryptBlocks(dst, src []byte) {
+	validateBlocks(dst, src)
+	for len(src) > 0 {
+		e.iv = e.c.xcrypt(gost28147.SeqEncrypt, e.iv^binary.BigEndian.Uint64(src))
+		binary.BigEndian.PutUint64(dst, e.iv)
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
+}
+
+type cbcDecrypter struct {
+	c  *Cipher
+	iv uint64
+}
+
+// Create CBC mode decrypter. It is used by crypto/cipher.NewCBCDecrypter
+// and, unlike generic implementation, decrypts multiple blocks at once.
+func (c *Cipher) NewCBCDecrypter(iv []byte) cipher.BlockMode {
+	if len(iv) != BlockSize {
+		panic("cipher.NewCBCDecrypter: IV length must equal block size")
+	}
+	return &cbcDecrypter{c: c, iv: binary.BigEndian.Uint64(iv)}
+}
+
+func (d *cbcDecrypter) BlockSize() int {
+	return BlockSize
+}
+
+func (d *cbcDecrypter) CryptBlocks(dst, src []byte) {
+	validateBlocks(dst, src)
+	var ct, w [interleave]uint64
+	for len(src) >= interleave*BlockSize {
+		// Ciphertext is read before writing, as dst and src may overlap
+		for i := 0; i < interleave; i++ {
+			ct[i] = binary.BigEndian.Uint64(src[i*BlockSize:])
+		}
+		w = ct
+		d.c.xcrypt4(gost28147.SeqDecrypt, &w)
+		binary.BigEndian.PutUint64(dst, w[0]^d.iv)
+		for i := 1; i < interleave; i++ {
+			binary.BigEndian.PutUint64(dst[i*BlockSize:], w[i]^ct[i-1])
+		}
+		d.iv = ct[interleave-1]
+		dst, src = dst[interleave*BlockSize:], src[interleave*BlockSize:]
+	}
+	for len(src) > 0 {
+		ct[0] = binary.BigEndian.Uint64(src)
+		binary.BigEndian.PutUint64(dst, d.c.xcrypt(gost28147.SeqDecrypt, ct[0])^d.iv)
+		d.iv = ct[0]
+		dst, src = dst[BlockSize:], src[BlockSize:]
+	}
 }
@@ -18,9 +18,15 @@ package gost341264
 import (
 	"bytes"
 	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
 	"testing"
+	"testing/quick"
 )
 
+// Hide optimised modes, forcing crypto/cipher to use generic ones.
+type blockOnly struct{ cipher.Block }
+
 func TestCipherInterface(t *testing.T) {
 	var _ cipher.Block = NewCipher(make([]byte, KeySize))
 }
@@ -45,3 +51,90 @@ func TestVector(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestCTR3413Vector(t *testing.T) {
+	key, _ := hex.DecodeString("" +
+		"ffeeddccbbaa99887766554433221100" +
+		"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	pt, _ := hex.DecodeString("" +
+		"92def06b3c130a59db54c704f8189d20" +
+		"4a98fb2e67a8024c8912409b17b57e41")
+	ct, _ := hex.DecodeString("" +
+		"4e98110c97b7b93c3e250d93d6e85d69" +
+		"136d868807b2dbef568eb680ab52a12d")
+	iv := []byte{0x12, 0x34, 0x56, 0x78}
+	dst := make([]byte, len(pt))
+	c := NewCipher(key)
+	c.NewCTR3413(iv).XORKeyStream(dst, pt)
+	if !bytes.Equal(dst, ct) {
+		t.Fatal("encryption failed")
+	}
+	c.NewCTR3413(iv).XORKeyStream(dst, dst)
+	if !bytes.Equal(dst, pt) {
+		t.Fatal("decryption failed")
+	}
+}
+
+func TestModesGeneric(t *testing.T) {
+	f := func(key [KeySize]byte, iv [BlockSize]byte, data []byte) bool {
+		c := NewCipher(key[:])
+		expected := make([]byte, len(data))
+		cipher.NewCTR(blockOnly{c}, iv[:]).XORKeyStream(expected, data)
+		got := make([]byte, len(data))
+		ctr := cipher.NewCTR(c, iv[:])
+		half := len(data) / 2
+		ctr.XORKeyStream(got[:half], data[:half])
+		ctr.XORKeyStream(got[half:], data[half:])
+		if !bytes.Equal(got, expected) {
+			return false
+		}
+
+		data = data[:len(data)-len(data)%BlockSize]
+		expected = expected[:len(data)]
+		got = got[:len(data)]
+		cipher.NewCBCEncrypter(blockOnly{c}, iv[:]).CryptBlocks(expected, data)
+		cipher.NewCBCEncrypter(c, iv[:]).CryptBlocks(got, data)
+		if !bytes.Equal(got, expected) {
+			return false
+		}
+		cipher.NewCBCDecrypter(c, iv[:]).CryptBlocks(got, got)
+		return bytes.Equal(got, data)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func BenchmarkCTR(b *testing.B) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	iv := make([]byte, BlockSize)
+	buf := make([]byte, 1<<10)
+	ctr := cipher.NewCTR(NewCipher(key), iv)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ctr.XORKeyStream(buf, buf)
+	}
+}
+
+func TestCTRSetOffset(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	c := NewCipher(key)
+	iv := []byte{0x12, 0x34, 0x56, 0x78}
+	ks := make([]byte, 256*BlockSize)
+	c.NewCTR3413(iv).XORKeyStream(ks, ks)
+	ctr := c.NewCTR3413(iv)
+	f := func(off, n uint16) bool {
+		o := int(off) % len(ks)
+		l := int(n) % (len(ks) - o)
+		got := make([]byte, l)
+		ctr.SetOffset(uint64(o))
+		ctr.XORKeyStream(got, got)
+		return bytes.Equal(got, ks[o:o+l])
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,132 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3413
+
+import (
+	"crypto/cipher"
+	"fmt"
+)
+
+// GOST R 34.13-2015 MAC (OMAC1) mode of operation.
+type MAC struct {
+	c    cipher.Block
+	size int
+	k1   []byte
+	k2   []byte
+	prev []byte
+	buf  []byte
+	tmp  []byte
+}
+
+// Shift left by one bit, xoring with the constant if the highest bit
+// was set.
+func dbl(dst, src []byte) {
+	var carry byte
+	for i := len(src) - 1; i >= 0; i-- {
+		b := src[i]
+		dst[i] = b<<1 | carry
+		carry = b >> 7
+	}
+	if carry == 0 {
+		return
+	}
+	if len(src) == 16 {
+		dst[len(dst)-1] ^= 0x87
+	} else {
+		dst[len(dst)-1] ^= 0x1B
+	}
+}
+
+// Create MAC with given tag size in bytes. Only 64 and 128 bit block
+// ciphers are allowed.
+func NewMAC(c cipher.Block, size int) (*MAC, error) {
+	bs := c.BlockSize()
+	if bs != 8 && bs != 16 {
+		return nil, fmt.Errorf("gogost/gost3413: invalid block size %d", bs)
+	}
+	if size == 0 || size > bs {
+		return nil, fmt.Errorf("gogost/gost3413: invalid tag size (0<%d<=%d)", size, bs)
+	}
+	m := MAC{
+		c:    c,
+		size: size,
+		k1:   make([]byte, bs),
+		k2:   make([]byte, bs),
+		prev: make([]byte, bs),
+		buf:  make([]byte, 0, bs),
+		tmp:  make([]byte, bs),
+	}
+	c.Encrypt(m.k1, m.k1)
+	dbl(m.k1, m.k1)
+	dbl(m.k2, m.k1)
+	return &m, nil
+}
+
+func (m *MAC) Reset() {
+	for i := range m.prev {
+		m.prev[i] = 0
+	}
+	m.buf = m.buf[:0]
+}
+
+func (m *MAC) BlockSize() int {
+	return m.c.BlockSize()
+}
+
+func (m *MAC) Size() int {
+	return m.size
+}
+
+func (m *MAC) Write(b []byte) (int, error) {
+	n := len(b)
+	bs := len(m.prev)
+	var l int
+	for len(b) > 0 {
+		// Full block is kept until more data arrives, as the last
+		// one is processed differently
+		if len(m.buf) == bs {
+			for i := 0; i < bs; i++ {
+				m.prev[i] ^= m.buf[i]
+			}
+			m.c.Encrypt(m.prev, m.prev)
+			m.buf = m.buf[:0]
+		}
+		l = bs - len(m.buf)
+		if l > len(b) {
+			l = len(b)
+		}
+		m.buf = append(m.buf, b[:l]...)
+		b = b[l:]
+	}
+	return n, nil
+}
+
+func (m *MAC) Sum(b []byte) []byte {
+	k := m.k1
+	copy(m.tmp, m.buf)
+	if len(m.buf) < len(m.tmp) {
+		k = m.k2
+		m.tmp[len(m.buf)] = 0x80
+		for i := len(m.buf) + 1; i < len(m.tmp); i++ {
+			m.tmp[i] = 0
+		}
+	}
+	for i := 0; i < len(m.tmp); i++ {
+		m.tmp[i] ^= m.prev[i] ^ k[i]
+	}
+	m.c.Encrypt(m.tmp, m.tmp)
+	return append(b, m.tmp[:m.size]...)
+}
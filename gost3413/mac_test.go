@@ -0,0 +1,84 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package gost3413
+
+import (
+	"bytes"
+	"encoding/hex"
+	"hash"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+)
+
+func TestMACInterface(t *testing.T) {
+	m, _ := NewMAC(gost3412128.NewCipher(make([]byte, 32)), 8)
+	var _ hash.Hash = m
+}
+
+func TestMACKuznechikVector(t *testing.T) {
+	key, _ := hex.DecodeString("" +
+		"8899aabbccddeeff0011223344556677" +
+		"fedcba98765432100123456789abcdef")
+	pt, _ := hex.DecodeString("" +
+		"1122334455667700ffeeddccbbaa9988" +
+		"00112233445566778899aabbcceeff0a" +
+		"112233445566778899aabbcceeff0a00" +
+		"2233445566778899aabbcceeff0a0011")
+	m, err := NewMAC(gost3412128.NewCipher(key), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(pt); i += 7 {
+		end := i + 7
+		if end > len(pt) {
+			end = len(pt)
+		}
+		m.Write(pt[i:end])
+	}
+	if !bytes.Equal(m.Sum(nil), []byte{
+		0x33, 0x6f, 0x4d, 0x29, 0x60, 0x59, 0xfb, 0xe3,
+	}) {
+		t.FailNow()
+	}
+}
+
+func TestMACMagmaVector(t *testing.T) {
+	key, _ := hex.DecodeString("" +
+		"ffeeddccbbaa99887766554433221100" +
+		"f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	pt, _ := hex.DecodeString("" +
+		"92def06b3c130a59db54c704f8189d20" +
+		"4a98fb2e67a8024c8912409b17b57e41")
+	m, err := NewMAC(gost341264.NewCipher(key), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Write(pt)
+	if !bytes.Equal(m.Sum(nil), []byte{0x15, 0x4e, 0x72, 0x10}) {
+		t.FailNow()
+	}
+	// Sum does not change the state
+	if !bytes.Equal(m.Sum(nil), []byte{0x15, 0x4e, 0x72, 0x10}) {
+		t.FailNow()
+	}
+	m.Reset()
+	m.Write(pt)
+	if !bytes.Equal(m.Sum(nil), []byte{0x15, 0x4e, 0x72, 0x10}) {
+		t.FailNow()
+	}
+}
@@ -13,7 +13,7 @@
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// GOST R 34.13-2015 padding methods.
+// GOST R 34.13-2015 padding methods and MAC mode of operation.
 package gost3413
 
 func PadSize(dataSize, blockSize int) int {
@@ -0,0 +1,184 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// BER to DER conversion of CMS-like structures.
+package ber
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+)
+
+var ErrInvalid = errors.New("gogost: invalid BER")
+
+// Parse BER TLV header: tag bytes, whether it is constructed, content
+// length (-1 for indefinite) and header length.
+func Header(data []byte) (tag []byte, compound bool, l, hl int, err error) {
+	if len(data) < 2 {
+		err = ErrInvalid
+		return
+	}
+	compound = data[0]&0x20 > 0
+	hl = 1
+	if data[0]&0x1F == 0x1F {
+		for {
+			if hl >= len(data) {
+				err = ErrInvalid
+				return
+			}
+			hl++
+			if data[hl-1]&0x80 == 0 {
+				break
+			}
+		}
+	}
+	tag = data[:hl]
+	if hl >= len(data) {
+		err = ErrInvalid
+		return
+	}
+	b := data[hl]
+	hl++
+	switch {
+	case b < 0x80:
+		l = int(b)
+	case b == 0x80:
+		if !compound {
+			err = ErrInvalid
+			return
+		}
+		l = -1
+	default:
+		n := int(b & 0x7F)
+		if n > 4 || hl+n > len(data) {
+			err = ErrInvalid
+			return
+		}
+		for _, c := range data[hl : hl+n] {
+			l = l<<8 | int(c)
+		}
+		hl += n
+		if l < 0 {
+			err = ErrInvalid
+		}
+	}
+	return
+}
+
+func derLength(l int) []byte {
+	if l < 0x80 {
+		return []byte{byte(l)}
+	}
+	var b []byte
+	for ; l > 0; l >>= 8 {
+		b = append([]byte{byte(l)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+// Convert single BER element to DER, returning the rest of data.
+// Indefinite lengths are replaced with definite ones and constructed
+// OCTET STRINGs are joined to primitive ones.
+func toDER(data []byte) (der, rest []byte, err error) {
+	tag, compound, l, hl, err := Header(data)
+	if err != nil {
+		return
+	}
+	if !compound {
+		if hl+l > len(data) {
+			return nil, nil, ErrInvalid
+		}
+		der = append(append(append([]byte{}, tag...), derLength(l)...), data[hl:hl+l]...)
+		return der, data[hl+l:], nil
+	}
+	content := data[hl:]
+	if l >= 0 {
+		if l > len(content) {
+			return nil, nil, ErrInvalid
+		}
+		content, rest = content[:l], content[l:]
+	}
+	var body bytes.Buffer
+	var elem []byte
+	for {
+		if l < 0 {
+			if len(content) < 2 {
+				return nil, nil, ErrInvalid
+			}
+			if content[0] == 0 && content[1] == 0 {
+				rest = content[2:]
+				break
+			}
+		} else if len(content) == 0 {
+			break
+		}
+		elem, content, err = toDER(content)
+		if err != nil {
+			return
+		}
+		body.Write(elem)
+	}
+	if len(tag) == 1 && tag[0] == 0x20|asn1.TagOctetString {
+		var joined []byte
+		if joined, err = joinOctets(body.Bytes()); err != nil {
+			return
+		}
+		der = append(append([]byte{asn1.TagOctetString}, derLength(len(joined))...), joined...)
+		return der, rest, nil
+	}
+	der = append(append(append([]byte{}, tag...), derLength(body.Len())...), body.Bytes()...)
+	return der, rest, nil
+}
+
+// Concatenate contents of DER OCTET STRINGs sequence.
+func joinOctets(data []byte) ([]byte, error) {
+	var joined []byte
+	var raw asn1.RawValue
+	var err error
+	for len(data) > 0 {
+		data, err = asn1.Unmarshal(data, &raw)
+		if err != nil {
+			return nil, err
+		}
+		if raw.Class != asn1.ClassUniversal || raw.Tag != asn1.TagOctetString {
+			return nil, ErrInvalid
+		}
+		joined = append(joined, raw.Bytes...)
+	}
+	return joined, nil
+}
+
+// Convert BER encoded data to DER. Many PFX and CMS files are produced
+// with indefinite lengths and constructed OCTET STRINGs.
+func ToDER(data []byte) ([]byte, error) {
+	der, rest, err := toDER(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost: trailing data after BER")
+	}
+	return der, nil
+}
+
+// Get the contents of OCTET STRING that may be encoded either as
+// primitive value, or as constructed one with implicit tag.
+func Octets(raw asn1.RawValue) ([]byte, error) {
+	if !raw.IsCompound {
+		return raw.Bytes, nil
+	}
+	return joinOctets(raw.Bytes)
+}
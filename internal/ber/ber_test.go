@@ -0,0 +1,49 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ber
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestToDER(t *testing.T) {
+	// SEQUENCE (indefinite) { constructed OCTET STRING (indefinite)
+	// { OCTET STRING "ab", OCTET STRING "c" }, INTEGER 1 }
+	data := []byte{
+		0x30, 0x80,
+		0x24, 0x80,
+		0x04, 0x02, 'a', 'b',
+		0x04, 0x01, 'c',
+		0x00, 0x00,
+		0x02, 0x01, 0x01,
+		0x00, 0x00,
+	}
+	der, err := ToDER(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(der, []byte{
+		0x30, 0x08,
+		0x04, 0x03, 'a', 'b', 'c',
+		0x02, 0x01, 0x01,
+	}) {
+		t.Fatalf("%x", der)
+	}
+	if _, err = ToDER(data[:len(data)-2]); err == nil {
+		t.Fatal("truncated BER accepted")
+	}
+}
@@ -0,0 +1,157 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// OpenSSL's index.txt compatible database of issued certificates.
+package caindex
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// index.txt time format
+const TimeFormat = "060102150405Z"
+
+// CRL reason code names, indexed by the code.
+var Reasons = []string{
+	"unspecified",
+	"keyCompromise",
+	"cACompromise",
+	"affiliationChanged",
+	"superseded",
+	"cessationOfOperation",
+	"certificateHold",
+	"",
+	"removeFromCRL",
+	"privilegeWithdrawn",
+	"aACompromise",
+}
+
+func ReasonByName(name string) (int, error) {
+	for i, r := range Reasons {
+		if r != "" && r == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown revocation reason: %s", name)
+}
+
+// Even-length uppercase hexadecimal serial, as OpenSSL writes it.
+func HexSerial(serial *big.Int) string {
+	s := fmt.Sprintf("%X", serial)
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	return s
+}
+
+// Issued certificate record: status, expiry, revocation time with
+// reason, serial, file, subject.
+type Entry struct {
+	Revoked   bool
+	NotAfter  time.Time
+	RevokedAt time.Time
+	Reason    int
+	Serial    *big.Int
+	Subject   string
+}
+
+func (e *Entry) String() string {
+	status, revoked := "V", ""
+	if e.Revoked {
+		status = "R"
+		revoked = e.RevokedAt.UTC().Format(TimeFormat) + "," + Reasons[e.Reason]
+	}
+	return strings.Join([]string{
+		status,
+		e.NotAfter.UTC().Format(TimeFormat),
+		revoked,
+		HexSerial(e.Serial),
+		"unknown",
+		e.Subject,
+	}, "\t")
+}
+
+func ParseEntry(line string) (*Entry, error) {
+	cols := strings.SplitN(line, "\t", 6)
+	if len(cols) != 6 {
+		return nil, errors.New("invalid index line")
+	}
+	e := Entry{Revoked: cols[0] == "R", Subject: cols[5]}
+	var err error
+	if e.NotAfter, err = time.Parse(TimeFormat, cols[1]); err != nil {
+		return nil, err
+	}
+	if e.Revoked {
+		at, reason, _ := strings.Cut(cols[2], ",")
+		if e.RevokedAt, err = time.Parse(TimeFormat, at); err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			if e.Reason, err = ReasonByName(reason); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var ok bool
+	if e.Serial, ok = new(big.Int).SetString(cols[3], 16); !ok {
+		return nil, fmt.Errorf("invalid serial: %s", cols[3])
+	}
+	return &e, nil
+}
+
+func Read(path string) (entries []*Entry, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		if scanner.Text() == "" {
+			continue
+		}
+		e, err := ParseEntry(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func Marshal(entries []*Entry) []byte {
+	var buf bytes.Buffer
+	for _, e := range entries {
+		buf.WriteString(e.String())
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// Find the entry by serial number, nil if there is none.
+func Lookup(entries []*Entry, serial *big.Int) *Entry {
+	for _, e := range entries {
+		if e.Serial.Cmp(serial) == 0 {
+			return e
+		}
+	}
+	return nil
+}
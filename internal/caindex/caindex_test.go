@@ -0,0 +1,42 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package caindex
+
+import (
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestEntryRoundtrip(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, e := range []*Entry{
+		{NotAfter: now, Serial: big.NewInt(0x102), Subject: "CN=foo,O=bar"},
+		{Revoked: true, NotAfter: now, RevokedAt: now, Reason: 1, Serial: big.NewInt(3), Subject: "CN=baz"},
+	} {
+		line := e.String()
+		got, err := ParseEntry(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.String() != line {
+			t.Fatal("differs", line, got.String())
+		}
+	}
+	if HexSerial(big.NewInt(0x102)) != "0102" {
+		t.Fatal("odd length serial")
+	}
+}
@@ -0,0 +1,94 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Keys and certificates for the tests.
+package testpki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+// Generate the new key on the curve.
+func Key(t testing.TB, curve *gost3410.Curve) *gost3410.PrivateKey {
+	prv, err := gost3410.GenPrivateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prv
+}
+
+// Issue certificate for the key from the template, signed by parent's
+// parentPrv, or self-signed if parent is nil. Missing serial number is
+// random, missing validity is an hour before and after now. Template's
+// RawSubjectPublicKeyInfo, if set, is used instead of the key's
+// 34.10-2012 one (for 34.10-2001 certificates).
+func Issue(
+	t testing.TB,
+	tmpl *x509.Certificate,
+	prv *gost3410.PrivateKey,
+	parent *x509.Certificate,
+	parentPrv *gost3410.PrivateKey,
+) *x509.Certificate {
+	c := *tmpl
+	if c.SerialNumber == nil {
+		serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SerialNumber = serial.Add(serial, big.NewInt(1))
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	if c.NotBefore.IsZero() {
+		c.NotBefore = now.Add(-time.Hour)
+	}
+	if c.NotAfter.IsZero() {
+		c.NotAfter = now.Add(time.Hour)
+	}
+	var pub any = prv.Public()
+	if len(c.RawSubjectPublicKeyInfo) > 0 {
+		pub = c.RawSubjectPublicKeyInfo
+	}
+	if parent == nil {
+		parent, parentPrv = &c, prv
+	}
+	der, err := x509gost.CreateCertificate(rand.Reader, &c, parent, pub, parentPrv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// Issue certificate like Issue does for the new key on the curve.
+func New(
+	t testing.TB,
+	curve *gost3410.Curve,
+	tmpl *x509.Certificate,
+	parent *x509.Certificate,
+	parentPrv *gost3410.PrivateKey,
+) (*x509.Certificate, *gost3410.PrivateKey) {
+	prv := Key(t, curve)
+	return Issue(t, tmpl, prv, parent, parentPrv), prv
+}
@@ -0,0 +1,235 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jose
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func genKey(t *testing.T, c *gost3410.Curve) *gost3410.PrivateKey {
+	prv, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return prv
+}
+
+var testCurves = []*gost3410.Curve{
+	gost3410.CurveIdtc26gost34102012256paramSetA(),
+	gost3410.CurveIdGostR34102001CryptoProAParamSet(),
+	gost3410.CurveIdtc26gost341012512paramSetA(),
+}
+
+func TestJWK(t *testing.T) {
+	for _, c := range testCurves {
+		prv := genKey(t, c)
+		jwk, err := NewJWK(prv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(jwk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var parsed JWK
+		if err = json.Unmarshal(data, &parsed); err != nil {
+			t.Fatal(err)
+		}
+		got, err := parsed.PrivateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got.Raw(), prv.Raw()) {
+			t.FailNow()
+		}
+		pub, err := parsed.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !pub.Equal(prv.Public()) {
+			t.FailNow()
+		}
+		pubJWK, err := NewJWK(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pubJWK.D != "" {
+			t.FailNow()
+		}
+		tp1, _ := jwk.Thumbprint()
+		tp2, _ := pubJWK.Thumbprint()
+		if tp1 != tp2 {
+			t.FailNow()
+		}
+
+		// Point outside the curve
+		y, _ := b64.DecodeString(parsed.Y)
+		y[len(y)-1] ^= 1
+		parsed.Y = b64.EncodeToString(y)
+		if _, err = parsed.PublicKey(); err == nil {
+			t.Fatal("invalid point accepted")
+		}
+	}
+	jwk, _ := NewJWK(genKey(t, testCurves[0]))
+	other, _ := NewJWK(genKey(t, testCurves[0]))
+	jwk.D = other.D
+	if _, err := jwk.PrivateKey(); err == nil {
+		t.Fatal("mismatched private key accepted")
+	}
+}
+
+func TestJWS(t *testing.T) {
+	payload := []byte(`{"sub":"1234567890","iat":1516239022}`)
+	for _, c := range testCurves {
+		prv := genKey(t, c)
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := Sign(rand.Reader, prv, payload, Header{Typ: "JWT", Kid: "key1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, header, err := Verify(token, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, payload) || header.Typ != "JWT" || header.Kid != "key1" {
+			t.FailNow()
+		}
+		if c.PointSize() == 64 && header.Alg != AlgGOST512 {
+			t.FailNow()
+		}
+
+		parts := strings.Split(token, ".")
+		tampered := parts[0] + "." + b64.EncodeToString([]byte("{}")) + "." + parts[2]
+		if _, _, err = Verify(tampered, pub); err != ErrSignature {
+			t.Fatal("tampered payload accepted", err)
+		}
+		if _, _, err = Verify(token, genKey(t, c).Public().(*gost3410.PublicKey)); err == nil {
+			t.Fatal("another key accepted")
+		}
+	}
+}
+
+func TestAlgorithmConfusion(t *testing.T) {
+	prv := genKey(t, testCurves[0])
+	pub, err := prv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := Sign(rand.Reader, prv, []byte("payload"), Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	for _, header := range []string{
+		`{"alg":"none"}`,
+		`{"alg":"HS256"}`,
+		`{"alg":"` + AlgGOST512 + `"}`,
+		`{"alg":"` + AlgGOST256 + `","enc":"` + EncKuznyechikMGM + `"}`,
+		`{"alg":"` + AlgGOST256 + `","crit":["exp"],"exp":1}`,
+	} {
+		forged := b64.EncodeToString([]byte(header)) + "." + parts[1] + "." + parts[2]
+		if _, _, err = Verify(forged, pub); err == nil || err == ErrSignature {
+			t.Fatal("accepted", header, err)
+		}
+		if _, _, err = Verify(b64.EncodeToString([]byte(header))+"."+parts[1]+".", pub); err == nil {
+			t.Fatal("accepted", header)
+		}
+	}
+
+	// 512-bit key does not accept 256-bit algorithm
+	prv512 := genKey(t, testCurves[2])
+	pub512, err := prv512.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Verify(token, pub512); err == nil || err == ErrSignature {
+		t.Fatal("256-bit algorithm accepted with 512-bit key", err)
+	}
+
+	// JWE is not JWS
+	jwe, err := Encrypt(rand.Reader, pub, []byte("payload"), Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Verify(jwe, pub); err == nil {
+		t.Fatal("JWE accepted as JWS")
+	}
+	if _, _, err = Decrypt(token, prv); err == nil {
+		t.Fatal("JWS accepted as JWE")
+	}
+}
+
+func TestJWE(t *testing.T) {
+	plaintext := []byte("secret message")
+	for _, c := range testCurves {
+		prv := genKey(t, c)
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := Encrypt(rand.Reader, pub, plaintext, Header{Cty: "text/plain"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, header, err := Decrypt(token, prv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) || header.Cty != "text/plain" || header.Alg != AlgVKO {
+			t.FailNow()
+		}
+		if _, _, err = Decrypt(token, genKey(t, c)); err != ErrDecrypt {
+			t.Fatal("another key decrypted", err)
+		}
+
+		parts := strings.Split(token, ".")
+		ct, _ := b64.DecodeString(parts[3])
+		ct[0] ^= 1
+		parts[3] = b64.EncodeToString(ct)
+		if _, _, err = Decrypt(strings.Join(parts, "."), prv); err != ErrDecrypt {
+			t.Fatal("tampered ciphertext accepted", err)
+		}
+		iv, _ := b64.DecodeString(parts[2])
+		iv[0] |= 0x80
+		parts[2] = b64.EncodeToString(iv)
+		if _, _, err = Decrypt(strings.Join(parts, "."), prv); err == nil {
+			t.Fatal("invalid IV accepted")
+		}
+	}
+
+	// Ephemeral key on another curve
+	prv := genKey(t, testCurves[0])
+	pub512, err := genKey(t, testCurves[2]).PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := Encrypt(rand.Reader, pub512, []byte("data"), Header{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Decrypt(token, prv); err == nil {
+		t.Fatal("ephemeral key on another curve accepted")
+	}
+}
@@ -0,0 +1,164 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jose
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/mgm"
+)
+
+const (
+	// VKO's UKM size.
+	UKMSize = 16
+
+	ivSize  = gost3412128.BlockSize
+	tagSize = gost3412128.BlockSize
+)
+
+var ErrDecrypt = errors.New("gogost/jose: decryption failed")
+
+// Content encryption key: KDF_GOSTR3411_2012_256 of the VKO
+// GOST R 34.10-2012 256-bit shared key, with enc as label and UKM as
+// seed.
+func deriveCEK(prv *gost3410.PrivateKey, pub *gost3410.PublicKey, ukm []byte) ([]byte, error) {
+	kek, err := prv.KEK2012256(pub, gost3410.NewUKM(ukm))
+	if err != nil {
+		return nil, err
+	}
+	return gost34112012256.NewKDF(kek).Derive(nil, []byte(EncKuznyechikMGM), ukm), nil
+}
+
+// Create JWE in compact serialization for the recipient's public key.
+// Direct VKO key agreement with ephemeral key is used: encrypted key
+// part is empty.
+func Encrypt(rand io.Reader, pub *gost3410.PublicKey, plaintext []byte, header Header) (string, error) {
+	eph, err := gost3410.GenPrivateKey(pub.C, rand)
+	if err != nil {
+		return "", err
+	}
+	ephPub, err := eph.PublicKey()
+	if err != nil {
+		return "", err
+	}
+	if header.EPK, err = NewJWK(ephPub); err != nil {
+		return "", err
+	}
+	ukm := make([]byte, UKMSize)
+	if _, err = io.ReadFull(rand, ukm); err != nil {
+		return "", err
+	}
+	iv := make([]byte, ivSize)
+	if _, err = io.ReadFull(rand, iv); err != nil {
+		return "", err
+	}
+	iv[0] &= 0x7F
+	header.Alg, header.Enc, header.UKM = AlgVKO, EncKuznyechikMGM, b64.EncodeToString(ukm)
+	encoded, err := encodeHeader(&header)
+	if err != nil {
+		return "", err
+	}
+	cek, err := deriveCEK(eph, pub, ukm)
+	if err != nil {
+		return "", err
+	}
+	aead, err := mgm.NewMGM(gost3412128.NewCipher(cek), tagSize)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, iv, plaintext, []byte(encoded))
+	ct, tag := sealed[:len(plaintext)], sealed[len(plaintext):]
+	return strings.Join([]string{
+		encoded, "", b64.EncodeToString(iv),
+		b64.EncodeToString(ct), b64.EncodeToString(tag),
+	}, "."), nil
+}
+
+// Decrypt JWE in compact serialization. Only AlgVKO with
+// EncKuznyechikMGM and ephemeral key on the recipient's curve are
+// accepted.
+func Decrypt(token string, prv *gost3410.PrivateKey) ([]byte, *Header, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, nil, errors.New("gogost/jose: JWE must have five parts")
+	}
+	header, err := decodeHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Alg != AlgVKO || header.Enc != EncKuznyechikMGM {
+		return nil, nil, fmt.Errorf(
+			"gogost/jose: unexpected algorithms %q/%q", header.Alg, header.Enc,
+		)
+	}
+	if parts[1] != "" {
+		return nil, nil, errors.New("gogost/jose: non-empty encrypted key")
+	}
+	if header.EPK == nil {
+		return nil, nil, errors.New("gogost/jose: no ephemeral public key")
+	}
+	ephPub, err := header.EPK.PublicKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ephPub.C.Equal(prv.C) || header.EPK.D != "" {
+		return nil, nil, errors.New("gogost/jose: invalid ephemeral public key")
+	}
+	ukm, err := b64.DecodeString(header.UKM)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ukm) != UKMSize {
+		return nil, nil, errors.New("gogost/jose: invalid UKM")
+	}
+	iv, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != ivSize || iv[0]&0x80 != 0 {
+		return nil, nil, errors.New("gogost/jose: invalid IV")
+	}
+	ct, err := b64.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, err
+	}
+	tag, err := b64.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tag) != tagSize {
+		return nil, nil, ErrDecrypt
+	}
+	cek, err := deriveCEK(prv, ephPub, ukm)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := mgm.NewMGM(gost3412128.NewCipher(cek), tagSize)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, append(ct, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	return plaintext, header, nil
+}
@@ -0,0 +1,201 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// JOSE (JWK, JWS, JWE) with GOST R 34.10-2012 keys, Streebog and
+// Kuznyechik-MGM. There are no registered GOST algorithms, so their
+// names are provisional and can be changed by the package variables.
+package jose
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+// Provisional identifiers.
+var (
+	// JWK key type.
+	KeyType = "GOST"
+
+	// JWS algorithms: GOST R 34.10-2012 with Streebog-256 for 256-bit
+	// keys and with Streebog-512 for 512-bit ones.
+	AlgGOST256 = "GOST3410-2012-256"
+	AlgGOST512 = "GOST3410-2012-512"
+
+	// JWE direct key agreement with VKO GOST R 34.10-2012.
+	AlgVKO = "VKO-GOST3410-2012"
+
+	// JWE content encryption with Kuznyechik in MGM mode.
+	EncKuznyechikMGM = "KUZNYECHIK-MGM"
+)
+
+var b64 = base64.RawURLEncoding
+
+// JSON Web Key with GOST R 34.10 public key coordinates and private
+// key, all big-endian and padded to the curve's point size. Curve is
+// identified by its canonical parameter set name.
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+}
+
+// Canonical name of the curve, empty for curves without identifiers.
+func curveName(c *gost3410.Curve) string {
+	oid := c.OID()
+	if oid == nil {
+		return ""
+	}
+	for _, name := range gost3410.CurveNames() {
+		if gost3410.CurveByName(name).OID().Equal(oid) {
+			return name
+		}
+	}
+	return ""
+}
+
+func pad(n *big.Int, size int) []byte {
+	return n.FillBytes(make([]byte, size))
+}
+
+// Create JWK from *gost3410.PublicKey or *gost3410.PrivateKey.
+func NewJWK(key any) (*JWK, error) {
+	var pub *gost3410.PublicKey
+	var prv *gost3410.PrivateKey
+	switch k := key.(type) {
+	case *gost3410.PublicKey:
+		pub = k
+	case *gost3410.PrivateKey:
+		var err error
+		if pub, err = k.PublicKey(); err != nil {
+			return nil, err
+		}
+		prv = k
+	default:
+		return nil, fmt.Errorf("gogost/jose: unsupported key type %T", key)
+	}
+	crv := curveName(pub.C)
+	if crv == "" {
+		return nil, errors.New("gogost/jose: curve has no name")
+	}
+	size := pub.C.PointSize()
+	jwk := JWK{
+		Kty: KeyType,
+		Crv: crv,
+		X:   b64.EncodeToString(pad(pub.X, size)),
+		Y:   b64.EncodeToString(pad(pub.Y, size)),
+	}
+	if prv != nil {
+		jwk.D = b64.EncodeToString(pad(prv.Key, size))
+	}
+	return &jwk, nil
+}
+
+func (jwk *JWK) curve() (*gost3410.Curve, error) {
+	if jwk.Kty != KeyType {
+		return nil, fmt.Errorf("gogost/jose: unsupported key type %s", jwk.Kty)
+	}
+	c := gost3410.CurveByName(jwk.Crv)
+	if c == nil {
+		return nil, fmt.Errorf("gogost/jose: unknown curve %s", jwk.Crv)
+	}
+	return c, nil
+}
+
+func decodeCoord(s string, size int) (*big.Int, error) {
+	raw, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != size {
+		return nil, errors.New("gogost/jose: invalid coordinate length")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// Public key, which point is checked to be on the curve.
+func (jwk *JWK) PublicKey() (*gost3410.PublicKey, error) {
+	c, err := jwk.curve()
+	if err != nil {
+		return nil, err
+	}
+	x, err := decodeCoord(jwk.X, c.PointSize())
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeCoord(jwk.Y, c.PointSize())
+	if err != nil {
+		return nil, err
+	}
+	if !c.Contains(x, y) {
+		return nil, errors.New("gogost/jose: point is not on the curve")
+	}
+	return &gost3410.PublicKey{C: c, X: x, Y: y}, nil
+}
+
+// Private key, which is checked to correspond to the public one.
+func (jwk *JWK) PrivateKey() (*gost3410.PrivateKey, error) {
+	if jwk.D == "" {
+		return nil, errors.New("gogost/jose: no private key")
+	}
+	pub, err := jwk.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := b64.DecodeString(jwk.D)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != pub.C.PointSize() {
+		return nil, errors.New("gogost/jose: invalid private key length")
+	}
+	prv, err := gost3410.NewPrivateKeyBE(pub.C, raw)
+	if err != nil {
+		return nil, err
+	}
+	our, err := prv.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	if !our.Equal(pub) {
+		return nil, errors.New("gogost/jose: private key does not match the public one")
+	}
+	return prv, nil
+}
+
+// RFC 7638 JWK thumbprint with SHA-256 over the required members.
+func (jwk *JWK) Thumbprint() (string, error) {
+	data, err := json.Marshal(struct {
+		Crv string `json:"crv"`
+		Kty string `json:"kty"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}{jwk.Crv, jwk.Kty, jwk.X, jwk.Y})
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return b64.EncodeToString(h[:]), nil
+}
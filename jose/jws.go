@@ -0,0 +1,135 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package jose
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+)
+
+var ErrSignature = errors.New("gogost/jose: invalid signature")
+
+// JOSE header. Only the listed parameters are supported.
+type Header struct {
+	Alg  string   `json:"alg"`
+	Enc  string   `json:"enc,omitempty"`
+	Typ  string   `json:"typ,omitempty"`
+	Cty  string   `json:"cty,omitempty"`
+	Kid  string   `json:"kid,omitempty"`
+	Crit []string `json:"crit,omitempty"`
+
+	// JWE ephemeral public key and VKO's UKM.
+	EPK *JWK   `json:"epk,omitempty"`
+	UKM string `json:"ukm,omitempty"`
+}
+
+// JWS algorithm and its hash for the key's curve.
+func sigAlgorithm(c *gost3410.Curve) (string, hash.Hash) {
+	if c.PointSize() == 64 {
+		return AlgGOST512, gost34112012512.New()
+	}
+	return AlgGOST256, gost34112012256.New()
+}
+
+func encodeHeader(header *Header) (string, error) {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(data), nil
+}
+
+// Decode protected header, rejecting unknown critical extensions.
+func decodeHeader(s string) (*Header, error) {
+	data, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var header Header
+	if err = json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if len(header.Crit) > 0 {
+		return nil, fmt.Errorf("gogost/jose: unsupported critical %v", header.Crit)
+	}
+	return &header, nil
+}
+
+// Create JWS in compact serialization. Header's alg is set according
+// to the key. Signature is s||r over the reversed Streebog digest of
+// the signing input, as in X.509 and CMS.
+func Sign(rand io.Reader, prv *gost3410.PrivateKey, payload []byte, header Header) (string, error) {
+	var h hash.Hash
+	header.Alg, h = sigAlgorithm(prv.C)
+	header.Enc, header.EPK, header.UKM = "", nil, ""
+	encoded, err := encodeHeader(&header)
+	if err != nil {
+		return "", err
+	}
+	input := encoded + "." + b64.EncodeToString(payload)
+	h.Write([]byte(input))
+	sig, err := (&gost3410.PrivateKeyReverseDigest{Prv: prv}).Sign(rand, h.Sum(nil), nil)
+	if err != nil {
+		return "", err
+	}
+	return input + "." + b64.EncodeToString(sig), nil
+}
+
+// Verify JWS in compact serialization. The only accepted algorithm is
+// the one corresponding to the key, so "none", HMAC or another curve's
+// algorithms are rejected, as JWE tokens are.
+func Verify(token string, pub *gost3410.PublicKey) ([]byte, *Header, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("gogost/jose: JWS must have three parts")
+	}
+	header, err := decodeHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	alg, h := sigAlgorithm(pub.C)
+	if header.Alg != alg || header.Enc != "" {
+		return nil, nil, fmt.Errorf("gogost/jose: unexpected algorithm %q", header.Alg)
+	}
+	sig, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sig) != 2*pub.C.PointSize() {
+		return nil, nil, ErrSignature
+	}
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(h.Sum(nil), sig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !valid {
+		return nil, nil, ErrSignature
+	}
+	payload, err := b64.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, header, nil
+}
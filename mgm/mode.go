@@ -26,10 +26,19 @@ import (
 
 var InvalidTag = errors.New("gogost/mgm: invalid authentication tag")
 
+// Number of counter blocks encrypted at once.
+const batchBlocks = 8
+
 type Mul interface {
 	Mul(x, y []byte) []byte
 }
 
+// Block cipher able to process multiple blocks in one call, like
+// bitsliced gost3412128.Cipher does.
+type BlocksEncrypter interface {
+	EncryptBlocks(dst, src []byte)
+}
+
 type MGM struct {
 	MaxSize   uint64
 	BlockSize int
@@ -40,6 +49,8 @@ type MGM struct {
 	bufC      []byte
 	padded    []byte
 	sum       []byte
+	ctrs      []byte
+	ks        []byte
 	mul       Mul
 }
 
@@ -61,6 +72,8 @@ func NewMGM(cipher cipher.Block, tagSize int) (cipher.AEAD, error) {
 		bufC:      make([]byte, blockSize),
 		padded:    make([]byte, blockSize),
 		sum:       make([]byte, blockSize),
+		ctrs:      make([]byte, batchBlocks*blockSize),
+		ks:        make([]byte, batchBlocks*blockSize),
 	}
 	if blockSize == 8 {
 		mgm.mul = newMul64()
@@ -114,6 +127,61 @@ func (mgm *MGM) validateSizes(text, additionalData []byte) {
 	}
 }
 
+func (mgm *MGM) encryptBlocks(dst, src []byte) {
+	if be, ok := mgm.cipher.(BlocksEncrypter); ok {
+		be.EncryptBlocks(dst, src)
+		return
+	}
+	for i := 0; i < len(src); i += mgm.BlockSize {
+		mgm.cipher.Encrypt(dst[i:i+mgm.BlockSize], src[i:i+mgm.BlockSize])
+	}
+}
+
+// Fill counter blocks buffer with up to batchBlocks sequential values
+// of bufP, incremented with incr function on the specified half, and
+// encrypt them to ks. Returns the number of blocks.
+func (mgm *MGM) keystream(blocks int, left bool) int {
+	if blocks > batchBlocks {
+		blocks = batchBlocks
+	}
+	for i := 0; i < blocks; i++ {
+		copy(mgm.ctrs[i*mgm.BlockSize:], mgm.bufP)
+		if left {
+			incr(mgm.bufP[:mgm.BlockSize/2])
+		} else {
+			incr(mgm.bufP[mgm.BlockSize/2:])
+		}
+	}
+	mgm.encryptBlocks(mgm.ks, mgm.ctrs[:blocks*mgm.BlockSize])
+	return blocks
+}
+
+// sum (xor)= H_i (x) D_i, H_i = E_K(Z_i), Z_{i+1} = incr_l(Z_i)
+// for each (possibly padded) data block D_i.
+func (mgm *MGM) authBlocks(data []byte) {
+	var n int
+	var blk []byte
+	for len(data) > 0 {
+		n = mgm.keystream((len(data)+mgm.BlockSize-1)/mgm.BlockSize, true)
+		for i := 0; i < n; i++ {
+			if len(data) >= mgm.BlockSize {
+				blk, data = data[:mgm.BlockSize], data[mgm.BlockSize:]
+			} else {
+				copy(mgm.padded, data)
+				for j := len(data); j < mgm.BlockSize; j++ {
+					mgm.padded[j] = 0
+				}
+				blk, data = mgm.padded, nil
+			}
+			xor(
+				mgm.sum,
+				mgm.sum,
+				mgm.mul.Mul(mgm.ks[i*mgm.BlockSize:(i+1)*mgm.BlockSize], blk),
+			)
+		}
+	}
+}
+
 func (mgm *MGM) auth(out, text, ad []byte) {
 	for i := 0; i < mgm.BlockSize; i++ {
 		mgm.sum[i] = 0
@@ -122,46 +190,8 @@ func (mgm *MGM) auth(out, text, ad []byte) {
 	textLen := len(text) * 8
 	mgm.icn[0] |= 0x80
 	mgm.cipher.Encrypt(mgm.bufP, mgm.icn) // Z_1 = E_K(1 || ICN)
-	for len(ad) >= mgm.BlockSize {
-		mgm.cipher.Encrypt(mgm.bufC, mgm.bufP) // H_i = E_K(Z_i)
-		xor(                                   // sum (xor)= H_i (x) A_i
-			mgm.sum,
-			mgm.sum,
-			mgm.mul.Mul(mgm.bufC, ad[:mgm.BlockSize]),
-		)
-		incr(mgm.bufP[:mgm.BlockSize/2]) // Z_{i+1} = incr_l(Z_i)
-		ad = ad[mgm.BlockSize:]
-	}
-	if len(ad) > 0 {
-		copy(mgm.padded, ad)
-		for i := len(ad); i < mgm.BlockSize; i++ {
-			mgm.padded[i] = 0
-		}
-		mgm.cipher.Encrypt(mgm.bufC, mgm.bufP)
-		xor(mgm.sum, mgm.sum, mgm.mul.Mul(mgm.bufC, mgm.padded))
-		incr(mgm.bufP[:mgm.BlockSize/2])
-	}
-
-	for len(text) >= mgm.BlockSize {
-		mgm.cipher.Encrypt(mgm.bufC, mgm.bufP) // H_{h+j} = E_K(Z_{h+j})
-		xor(                                   // sum (xor)= H_{h+j} (x) C_j
-			mgm.sum,
-			mgm.sum,
-			mgm.mul.Mul(mgm.bufC, text[:mgm.BlockSize]),
-		)
-		incr(mgm.bufP[:mgm.BlockSize/2]) // Z_{h+j+1} = incr_l(Z_{h+j})
-		text = text[mgm.BlockSize:]
-	}
-	if len(text) > 0 {
-		copy(mgm.padded, text)
-		for i := len(text); i < mgm.BlockSize; i++ {
-			mgm.padded[i] = 0
-		}
-		mgm.cipher.Encrypt(mgm.bufC, mgm.bufP)
-		xor(mgm.sum, mgm.sum, mgm.mul.Mul(mgm.bufC, mgm.padded))
-		incr(mgm.bufP[:mgm.BlockSize/2])
-	}
-
+	mgm.authBlocks(ad)
+	mgm.authBlocks(text)
 	mgm.cipher.Encrypt(mgm.bufP, mgm.bufP) // H_{h+q+1} = E_K(Z_{h+q+1})
 	// len(A) || len(C)
 	if mgm.BlockSize == 8 {
@@ -180,16 +210,16 @@ func (mgm *MGM) auth(out, text, ad []byte) {
 func (mgm *MGM) crypt(out, in []byte) {
 	mgm.icn[0] &= 0x7F
 	mgm.cipher.Encrypt(mgm.bufP, mgm.icn) // Y_1 = E_K(0 || ICN)
-	for len(in) >= mgm.BlockSize {
-		mgm.cipher.Encrypt(mgm.bufC, mgm.bufP) // E_K(Y_i)
-		xor(out, mgm.bufC, in)                 // C_i = P_i (xor) E_K(Y_i)
-		incr(mgm.bufP[mgm.BlockSize/2:])       // Y_i = incr_r(Y_{i-1})
-		out = out[mgm.BlockSize:]
-		in = in[mgm.BlockSize:]
-	}
-	if len(in) > 0 {
-		mgm.cipher.Encrypt(mgm.bufC, mgm.bufP)
-		xor(out, in, mgm.bufC)
+	var n int
+	for len(in) > 0 {
+		// E_K(Y_i), Y_i = incr_r(Y_{i-1})
+		n = mgm.keystream((len(in)+mgm.BlockSize-1)/mgm.BlockSize, false)
+		n *= mgm.BlockSize
+		if n > len(in) {
+			n = len(in)
+		}
+		xor(out, in[:n], mgm.ks) // C_i = P_i (xor) E_K(Y_i)
+		out, in = out[n:], in[n:]
 	}
 }
 
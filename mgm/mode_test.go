@@ -147,6 +147,33 @@ func TestSymmetric(t *testing.T) {
 	)
 }
 
+func TestBitsliced(t *testing.T) {
+	key := make([]byte, gost3412128.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	nonce := make([]byte, gost3412128.BlockSize)
+	aeadRef, _ := NewMGM(gost3412128.NewCipher(key), gost3412128.BlockSize)
+	aeadBS, _ := NewMGM(
+		gost3412128.NewCipher(key, gost3412128.Bitsliced()),
+		gost3412128.BlockSize,
+	)
+	f := func(plaintext, additionalData []byte) bool {
+		if len(plaintext) == 0 && len(additionalData) == 0 {
+			return true
+		}
+		sealed := aeadBS.Seal(nil, nonce, plaintext, additionalData)
+		if !bytes.Equal(sealed, aeadRef.Seal(nil, nonce, plaintext, additionalData)) {
+			return false
+		}
+		pt, err := aeadBS.Open(nil, nonce, sealed, additionalData)
+		return err == nil && bytes.Equal(pt, plaintext)
+	}
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
+
 func BenchmarkMGM64(b *testing.B) {
 	key := make([]byte, gost341264.KeySize)
 	if _, err := io.ReadFull(rand.Reader, key); err != nil {
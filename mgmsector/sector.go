@@ -0,0 +1,217 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Random access sector-level authenticated encryption with MGM mode.
+//
+// Underlying storage consists of fixed size records, one per sector:
+// nonce, encrypted sector and MGM tag. Nonce is random and generated on
+// each write of the sector, sector number is authenticated as
+// associated data. No counters are kept in the storage, so its rollback
+// does not lead to nonce reuse. For 128-bit ciphers nonce has 127
+// random bits. For 64-bit ones it has only 63, so the key must be
+// changed well before 2^32 writes are made.
+//
+// Records never written are read as zeros: all-zero record is treated
+// as empty sector. Moving ciphertexts between sectors is detected, but
+// neither resetting the sector to the empty state, nor replaying its
+// previous contents can be, as it requires state kept outside of the
+// storage.
+package mgmsector
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/pedroalbanese/gogost/mgm"
+)
+
+var (
+	ErrCorrupted = errors.New("gogost/mgmsector: corrupted record")
+	ErrTooBig    = errors.New("gogost/mgmsector: sector number is too big")
+)
+
+// Underlying storage of encrypted records, like *os.File.
+type Storage interface {
+	io.ReaderAt
+	io.WriterAt
+}
+
+// Encrypted storage. It is safe for concurrent use, but operations are
+// serialized.
+type Disk struct {
+	rand       io.Reader
+	sectorSize int
+	s          Storage
+	aead       cipher.AEAD
+	ad         [8]byte
+	rec        []byte
+	pt         []byte
+	mu         sync.Mutex
+}
+
+// Create encrypted storage over s with the given sector size. Block must
+// be either 64-bit or 128-bit cipher, like gost341264 or gost3412128.
+// Nonces are read from rand.
+func New(rand io.Reader, s Storage, block cipher.Block, sectorSize int) (*Disk, error) {
+	if sectorSize <= 0 {
+		return nil, fmt.Errorf("gogost/mgmsector: invalid sector size (0<%d)", sectorSize)
+	}
+	aead, err := mgm.NewMGM(block, block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	return &Disk{
+		rand:       rand,
+		sectorSize: sectorSize,
+		s:          s,
+		aead:       aead,
+		rec:        make([]byte, aead.NonceSize()+sectorSize+aead.Overhead()),
+		pt:         make([]byte, sectorSize),
+	}, nil
+}
+
+// Size of the plaintext sector.
+func (d *Disk) SectorSize() int {
+	return d.sectorSize
+}
+
+// Size of the record in the underlying storage.
+func (d *Disk) RecordSize() int {
+	return len(d.rec)
+}
+
+// Record offset of the sector. Sector number is set as associated data.
+func (d *Disk) offset(sector uint64) (int64, error) {
+	if sector > math.MaxInt64/uint64(len(d.rec)) {
+		return 0, ErrTooBig
+	}
+	binary.BigEndian.PutUint64(d.ad[:], sector)
+	return int64(sector) * int64(len(d.rec)), nil
+}
+
+// Read and decrypt the sector to d.pt. Returns io.EOF if the record
+// is beyond the end of the storage.
+func (d *Disk) read(sector uint64) error {
+	off, err := d.offset(sector)
+	if err != nil {
+		return err
+	}
+	n, err := d.s.ReadAt(d.rec, off)
+	if n == len(d.rec) {
+		err = nil
+	} else if err == io.EOF && n == 0 {
+		return io.EOF
+	} else if err == io.EOF {
+		return ErrCorrupted
+	} else {
+		return err
+	}
+	empty := true
+	for _, b := range d.rec {
+		empty = empty && b == 0
+	}
+	if empty {
+		for i := range d.pt {
+			d.pt[i] = 0
+		}
+		return nil
+	}
+	nonceSize := d.aead.NonceSize()
+	if d.rec[0]&0x80 > 0 {
+		return ErrCorrupted
+	}
+	if _, err = d.aead.Open(d.pt[:0], d.rec[:nonceSize], d.rec[nonceSize:], d.ad[:]); err != nil {
+		return fmt.Errorf("gogost/mgmsector: sector %d: %w", sector, err)
+	}
+	return nil
+}
+
+// Encrypt d.pt with the new random nonce and write it to the sector.
+func (d *Disk) write(sector uint64) error {
+	off, err := d.offset(sector)
+	if err != nil {
+		return err
+	}
+	nonceSize := d.aead.NonceSize()
+	if _, err = io.ReadFull(d.rand, d.rec[:nonceSize]); err != nil {
+		return err
+	}
+	d.rec[0] &= 0x7F
+	d.aead.Seal(d.rec[nonceSize:nonceSize], d.rec[:nonceSize], d.pt, d.ad[:])
+	_, err = d.s.WriteAt(d.rec, off)
+	return err
+}
+
+// Read decrypted data at the given offset. Each touched sector is
+// authenticated. io.EOF is returned when reading beyond the last
+// sector in the storage.
+func (d *Disk) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("gogost/mgmsector: negative offset")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sector := uint64(off) / uint64(d.sectorSize)
+	skip := int(uint64(off) % uint64(d.sectorSize))
+	for len(p) > 0 {
+		if err = d.read(sector); err != nil {
+			return
+		}
+		l := copy(p, d.pt[skip:])
+		n += l
+		p = p[l:]
+		sector++
+		skip = 0
+	}
+	return
+}
+
+// Encrypt and write data at the given offset. Partially overwritten
+// sectors are read and authenticated first. Storage is extended with
+// empty sectors if needed.
+func (d *Disk) WriteAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("gogost/mgmsector: negative offset")
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sector := uint64(off) / uint64(d.sectorSize)
+	skip := int(uint64(off) % uint64(d.sectorSize))
+	for len(p) > 0 {
+		if skip > 0 || len(p) < d.sectorSize {
+			if err = d.read(sector); err == io.EOF {
+				for i := range d.pt {
+					d.pt[i] = 0
+				}
+			} else if err != nil {
+				return
+			}
+		}
+		l := copy(d.pt[skip:], p)
+		if err = d.write(sector); err != nil {
+			return
+		}
+		n += l
+		p = p[l:]
+		sector++
+		skip = 0
+	}
+	return
+}
@@ -0,0 +1,199 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mgmsector
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+	"math"
+	"testing"
+	"testing/quick"
+
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+	"github.com/pedroalbanese/gogost/mgm"
+)
+
+type memStorage struct{ data []byte }
+
+func (m *memStorage) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (m *memStorage) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(m.data) {
+		m.data = append(m.data, make([]byte, end-len(m.data))...)
+	}
+	return copy(m.data[off:], p), nil
+}
+
+const testSectorSize = 64
+
+func newDisk(t *testing.T, block cipher.Block) (*Disk, *memStorage) {
+	s := &memStorage{}
+	d, err := New(rand.Reader, s, block, testSectorSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d, s
+}
+
+func blocks() []cipher.Block {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return []cipher.Block{gost3412128.NewCipher(key), gost341264.NewCipher(key)}
+}
+
+func TestRandomAccess(t *testing.T) {
+	for _, block := range blocks() {
+		d, _ := newDisk(t, block)
+		model := make([]byte, 16*testSectorSize)
+		f := func(off uint16, data []byte) bool {
+			o := int(off) % len(model)
+			if len(data) > len(model)-o {
+				data = data[:len(model)-o]
+			}
+			if n, err := d.WriteAt(data, int64(o)); err != nil || n != len(data) {
+				return false
+			}
+			copy(model[o:], data)
+			got := make([]byte, len(data)+testSectorSize)
+			n, err := d.ReadAt(got, int64(o))
+			if err != nil && err != io.EOF {
+				return false
+			}
+			written := (o + len(data) + testSectorSize - 1) / testSectorSize * testSectorSize
+			if n < written-o {
+				return false
+			}
+			return bytes.Equal(got[:n], model[o:o+n])
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestEmptyAndEOF(t *testing.T) {
+	d, s := newDisk(t, blocks()[0])
+	buf := make([]byte, 10)
+	if _, err := d.ReadAt(buf, 0); err != io.EOF {
+		t.FailNow()
+	}
+	// Sectors before the written one are holes
+	if _, err := d.WriteAt([]byte("hello"), 3*testSectorSize+1); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.data) != 4*d.RecordSize() {
+		t.FailNow()
+	}
+	got := make([]byte, 4*testSectorSize+1)
+	n, err := d.ReadAt(got, 0)
+	if err != io.EOF || n != 4*testSectorSize {
+		t.FailNow()
+	}
+	expected := make([]byte, 4*testSectorSize)
+	copy(expected[3*testSectorSize+1:], "hello")
+	if !bytes.Equal(got[:n], expected) {
+		t.FailNow()
+	}
+}
+
+func TestNonces(t *testing.T) {
+	d, s := newDisk(t, blocks()[1])
+	if d.SectorSize() != testSectorSize {
+		t.FailNow()
+	}
+	nonceSize := d.RecordSize() - testSectorSize - d.aead.Overhead()
+	data := make([]byte, testSectorSize)
+	seen := make(map[string]bool)
+	var first []byte
+	for i := 0; i < 4; i++ {
+		if _, err := d.WriteAt(data, 0); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			first = append([]byte{}, s.data...)
+		}
+		// Same plaintext is encrypted with another nonce each time, even
+		// after the rollback of the record
+		if seen[string(s.data[:nonceSize])] {
+			t.FailNow()
+		}
+		seen[string(s.data[:nonceSize])] = true
+		copy(s.data, first)
+	}
+	if _, err := d.WriteAt(data, math.MaxInt64-testSectorSize); err != ErrTooBig {
+		t.FailNow()
+	}
+}
+
+func TestTampering(t *testing.T) {
+	d, s := newDisk(t, blocks()[0])
+	data := make([]byte, 2*testSectorSize)
+	rand.Read(data)
+	if _, err := d.WriteAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, len(data))
+	rec := d.RecordSize()
+	orig := append([]byte{}, s.data...)
+
+	s.data[rec+d.aead.NonceSize()+3] ^= 1
+	if _, err := d.ReadAt(buf, 0); !errors.Is(err, mgm.InvalidTag) {
+		t.FailNow()
+	}
+
+	// Swapped sectors
+	copy(s.data, orig[rec:])
+	copy(s.data[rec:], orig[:rec])
+	if _, err := d.ReadAt(buf, 0); !errors.Is(err, mgm.InvalidTag) {
+		t.FailNow()
+	}
+
+	// Changed nonce
+	copy(s.data, orig)
+	s.data[d.aead.NonceSize()-1]++
+	if _, err := d.ReadAt(buf, 0); !errors.Is(err, mgm.InvalidTag) {
+		t.FailNow()
+	}
+
+	// Empty sector with garbage
+	copy(s.data, orig)
+	for i := 0; i < d.aead.NonceSize(); i++ {
+		s.data[i] = 0
+	}
+	if _, err := d.ReadAt(buf, 0); !errors.Is(err, mgm.InvalidTag) {
+		t.FailNow()
+	}
+
+	// Truncated record
+	copy(s.data, orig)
+	s.data = s.data[:len(s.data)-1]
+	if _, err := d.ReadAt(buf, 0); err != ErrCorrupted {
+		t.FailNow()
+	}
+}
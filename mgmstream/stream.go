@@ -0,0 +1,289 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Streaming authenticated encryption with chunked MGM mode.
+//
+// Stream is a STREAM-like construction: it begins with the header
+// containing magic, cipher identifier, chunk size and random nonce
+// prefix. The per-stream key is derived from the provided one and the
+// nonce prefix with KDF_GOSTR3411_2012_256. Data is split on fixed size
+// chunks, each sealed with MGM under the nonce consisting of the
+// prefix, big-endian 32-bit chunk counter and the last chunk flag.
+// Header is authenticated as additional data of each chunk. That way
+// chunks truncation, reordering and removing are detected. The last
+// chunk can be empty.
+package mgmstream
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost3412128"
+	"github.com/pedroalbanese/gogost/gost341264"
+	"github.com/pedroalbanese/gogost/mgm"
+)
+
+type Algo byte
+
+const (
+	AlgoKuznechik Algo = 1
+	AlgoMagma     Algo = 2
+)
+
+const (
+	KeySize          = 32
+	PrefixSize       = 16
+	HeaderSize       = len(Magic) + 1 + 4 + PrefixSize
+	DefaultChunkSize = 64 * 1024
+	MaxChunkSize     = 1 << 24
+)
+
+var (
+	Magic = [8]byte{'G', 'O', 'G', 'O', 'S', 'T', 'S', 1}
+
+	KDFLabel = []byte("gogost mgmstream")
+
+	ErrBadHeader = errors.New("gogost/mgmstream: invalid header")
+	ErrTruncated = errors.New("gogost/mgmstream: stream is truncated")
+	ErrTooLong   = errors.New("gogost/mgmstream: too many chunks")
+)
+
+// State common to both writer and reader.
+type stream struct {
+	aead      cipher.AEAD
+	header    [HeaderSize]byte
+	nonce     []byte
+	chunkSize int
+	ctr       uint64
+}
+
+func newStream(key []byte, header []byte) (*stream, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("gogost/mgmstream: invalid key size")
+	}
+	if !bytes.Equal(header[:len(Magic)], Magic[:]) {
+		return nil, ErrBadHeader
+	}
+	s := stream{}
+	copy(s.header[:], header)
+	s.chunkSize = int(binary.BigEndian.Uint32(header[len(Magic)+1:]))
+	if s.chunkSize == 0 || s.chunkSize > MaxChunkSize {
+		return nil, ErrBadHeader
+	}
+	prefix := header[HeaderSize-PrefixSize:]
+	k := gost34112012256.NewKDF(key).Derive(nil, KDFLabel, prefix)
+	var block cipher.Block
+	switch Algo(header[len(Magic)]) {
+	case AlgoKuznechik:
+		block = gost3412128.NewCipher(k)
+	case AlgoMagma:
+		block = gost341264.NewCipher(k)
+	default:
+		return nil, ErrBadHeader
+	}
+	var err error
+	s.aead, err = mgm.NewMGM(block, block.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	s.nonce = make([]byte, s.aead.NonceSize())
+	copy(s.nonce, prefix[:len(s.nonce)-5])
+	s.nonce[0] &= 0x7F
+	return &s, nil
+}
+
+// Prepare the nonce for the next chunk.
+func (s *stream) next(last bool) ([]byte, error) {
+	if s.ctr > 1<<32-1 {
+		return nil, ErrTooLong
+	}
+	binary.BigEndian.PutUint32(s.nonce[len(s.nonce)-5:], uint32(s.ctr))
+	if last {
+		s.nonce[len(s.nonce)-1] = 1
+	} else {
+		s.nonce[len(s.nonce)-1] = 0
+	}
+	s.ctr++
+	return s.nonce, nil
+}
+
+type Writer struct {
+	s      *stream
+	w      io.Writer
+	buf    []byte
+	closed bool
+}
+
+// Create the encrypting stream, immediately writing the header to w.
+// Close must be called to finish the stream, but it does not close w.
+// If chunkSize is zero, then DefaultChunkSize is used.
+func NewWriter(w io.Writer, key []byte, algo Algo, chunkSize int) (*Writer, error) {
+	if chunkSize == 0 {
+		chunkSize = DefaultChunkSize
+	}
+	if chunkSize < 0 || chunkSize > MaxChunkSize {
+		return nil, fmt.Errorf("gogost/mgmstream: invalid chunk size (0<%d<=%d)", chunkSize, MaxChunkSize)
+	}
+	var header [HeaderSize]byte
+	copy(header[:], Magic[:])
+	header[len(Magic)] = byte(algo)
+	binary.BigEndian.PutUint32(header[len(Magic)+1:], uint32(chunkSize))
+	if _, err := io.ReadFull(rand.Reader, header[HeaderSize-PrefixSize:]); err != nil {
+		return nil, err
+	}
+	s, err := newStream(key, header[:])
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(header[:]); err != nil {
+		return nil, err
+	}
+	return &Writer{
+		s:   s,
+		w:   w,
+		buf: make([]byte, 0, chunkSize+s.aead.Overhead()),
+	}, nil
+}
+
+func (w *Writer) seal(last bool) error {
+	nonce, err := w.s.next(last)
+	if err != nil {
+		return err
+	}
+	w.buf = w.s.aead.Seal(w.buf[:0], nonce, w.buf, w.s.header[:])
+	_, err = w.w.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.closed {
+		return 0, errors.New("gogost/mgmstream: write to closed stream")
+	}
+	var l int
+	for len(p) > 0 {
+		// Full chunk is sealed only when more data follows it, as
+		// the last one must be sealed with the flag set
+		if len(w.buf) == w.s.chunkSize {
+			if err = w.seal(false); err != nil {
+				return
+			}
+		}
+		l = w.s.chunkSize - len(w.buf)
+		if l > len(p) {
+			l = len(p)
+		}
+		w.buf = append(w.buf, p[:l]...)
+		n += l
+		p = p[l:]
+	}
+	return
+}
+
+// Seal the last chunk. Underlying writer is not closed.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+type Reader struct {
+	s    *stream
+	r    *bufio.Reader
+	buf  []byte
+	pt   []byte
+	done bool
+	err  error
+}
+
+// Create the decrypting stream, immediately reading the header from r.
+// Data is returned only after its chunk is authenticated.
+func NewReader(r io.Reader, key []byte) (*Reader, error) {
+	var header [HeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrBadHeader
+		}
+		return nil, err
+	}
+	s, err := newStream(key, header[:])
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		s:   s,
+		r:   bufio.NewReader(r),
+		buf: make([]byte, s.chunkSize+s.aead.Overhead()),
+	}, nil
+}
+
+func (r *Reader) open() error {
+	n, err := io.ReadFull(r.r, r.buf)
+	var last bool
+	switch err {
+	case nil:
+		// Full chunk is the last one only if nothing follows it
+		if _, err = r.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	case io.ErrUnexpectedEOF:
+		last = true
+	case io.EOF:
+		return ErrTruncated
+	default:
+		return err
+	}
+	if n < r.s.aead.Overhead() {
+		return ErrTruncated
+	}
+	nonce, err := r.s.next(last)
+	if err != nil {
+		return err
+	}
+	r.pt, err = r.s.aead.Open(r.buf[:0], nonce, r.buf[:n], r.s.header[:])
+	if err != nil {
+		return fmt.Errorf("gogost/mgmstream: chunk %d: %w", r.s.ctr-1, err)
+	}
+	r.done = last
+	return nil
+}
+
+func (r *Reader) Read(p []byte) (n int, err error) {
+	for len(r.pt) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.err = r.open(); r.err != nil {
+			return 0, r.err
+		}
+	}
+	n = copy(p, r.pt)
+	r.pt = r.pt[n:]
+	return
+}
@@ -0,0 +1,211 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package mgmstream
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+	"testing/quick"
+
+	"github.com/pedroalbanese/gogost/mgm"
+)
+
+const testChunkSize = 100
+
+func seal(t *testing.T, key, pt []byte, algo Algo) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, key, algo, testChunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(pt); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func open(key, ct []byte) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(ct), key)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestSymmetric(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	for _, algo := range []Algo{AlgoKuznechik, AlgoMagma} {
+		f := func(pt []byte, chunks []uint8) bool {
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, key, algo, testChunkSize)
+			if err != nil {
+				return false
+			}
+			data := pt
+			for len(data) > 0 {
+				n := len(data)
+				if len(chunks) > 0 {
+					if int(chunks[0]) < n {
+						n = int(chunks[0])
+					}
+					chunks = chunks[1:]
+				}
+				if _, err = w.Write(data[:n]); err != nil {
+					return false
+				}
+				data = data[n:]
+			}
+			if w.Close() != nil {
+				return false
+			}
+			got, err := open(key, buf.Bytes())
+			return err == nil && bytes.Equal(got, pt)
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestChunkBoundaries(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	for _, algo := range []Algo{AlgoKuznechik, AlgoMagma} {
+		for _, size := range []int{0, 1, testChunkSize, 2 * testChunkSize, 2*testChunkSize + 1} {
+			pt := make([]byte, size)
+			rand.Read(pt)
+			ct := seal(t, key, pt, algo)
+			tagSize := 16
+			if algo == AlgoMagma {
+				tagSize = 8
+			}
+			// Only the last chunk, possibly empty one, is shorter
+			chunks := (size + testChunkSize - 1) / testChunkSize
+			if chunks == 0 {
+				chunks = 1
+			}
+			if len(ct) != HeaderSize+size+chunks*tagSize {
+				t.Fatal("unexpected length", algo, size)
+			}
+			got, err := open(key, ct)
+			if err != nil || !bytes.Equal(got, pt) {
+				t.Fatal("failed", algo, size, err)
+			}
+		}
+	}
+}
+
+func TestTampering(t *testing.T) {
+	key := make([]byte, KeySize)
+	rand.Read(key)
+	pt := make([]byte, 3*testChunkSize+10)
+	rand.Read(pt)
+	ct := seal(t, key, pt, AlgoKuznechik)
+	chunk := testChunkSize + 16
+	body := ct[HeaderSize:]
+
+	t.Run("truncated at chunk boundary", func(t *testing.T) {
+		if _, err := open(key, ct[:HeaderSize+2*chunk]); err == nil {
+			t.FailNow()
+		}
+	})
+	t.Run("truncated last chunk", func(t *testing.T) {
+		if _, err := open(key, ct[:len(ct)-1]); !errors.Is(err, mgm.InvalidTag) {
+			t.FailNow()
+		}
+	})
+	t.Run("no chunks", func(t *testing.T) {
+		if _, err := open(key, ct[:HeaderSize]); err != ErrTruncated {
+			t.FailNow()
+		}
+	})
+	t.Run("reordered", func(t *testing.T) {
+		swapped := append([]byte{}, ct[:HeaderSize]...)
+		swapped = append(swapped, body[chunk:2*chunk]...)
+		swapped = append(swapped, body[:chunk]...)
+		swapped = append(swapped, body[2*chunk:]...)
+		if _, err := open(key, swapped); !errors.Is(err, mgm.InvalidTag) {
+			t.FailNow()
+		}
+	})
+	t.Run("appended", func(t *testing.T) {
+		if _, err := open(key, append(append([]byte{}, ct...), 0)); err == nil {
+			t.FailNow()
+		}
+	})
+	t.Run("modified header", func(t *testing.T) {
+		modified := append([]byte{}, ct...)
+		modified[HeaderSize-1] ^= 1
+		if _, err := open(key, modified); !errors.Is(err, mgm.InvalidTag) {
+			t.FailNow()
+		}
+	})
+	t.Run("modified data", func(t *testing.T) {
+		modified := append([]byte{}, ct...)
+		modified[HeaderSize+chunk+3] ^= 1
+		got, err := open(key, modified)
+		if !errors.Is(err, mgm.InvalidTag) {
+			t.FailNow()
+		}
+		if !bytes.Equal(got, pt[:testChunkSize]) {
+			t.FailNow()
+		}
+	})
+	t.Run("wrong key", func(t *testing.T) {
+		other := make([]byte, KeySize)
+		if _, err := open(other, ct); !errors.Is(err, mgm.InvalidTag) {
+			t.FailNow()
+		}
+	})
+	t.Run("bad magic", func(t *testing.T) {
+		modified := append([]byte{}, ct...)
+		modified[0] ^= 1
+		if _, err := open(key, modified); err != ErrBadHeader {
+			t.FailNow()
+		}
+	})
+}
+
+func TestUniqueStreams(t *testing.T) {
+	key := make([]byte, KeySize)
+	pt := make([]byte, 10)
+	ct1 := seal(t, key, pt, AlgoMagma)
+	ct2 := seal(t, key, pt, AlgoMagma)
+	if bytes.Equal(ct1[HeaderSize:], ct2[HeaderSize:]) {
+		t.FailNow()
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	key := make([]byte, KeySize)
+	buf := make([]byte, DefaultChunkSize)
+	w, err := NewWriter(io.Discard, key, AlgoKuznechik, DefaultChunkSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.Write(buf)
+	}
+}
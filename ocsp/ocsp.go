@@ -0,0 +1,623 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Online Certificate Status Protocol (RFC 6960) with GOST R 34.11-2012
+// CertID hashes and GOST R 34.10 signed responses.
+package ocsp
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+// Certificate statuses.
+const (
+	Good    = 0
+	Revoked = 1
+	Unknown = 2
+)
+
+// Response statuses.
+const (
+	Success           = 0
+	Malformed         = 1
+	InternalError     = 2
+	TryLater          = 3
+	SignatureRequired = 5
+	Unauthorized      = 6
+)
+
+const NonceSize = 16
+
+var (
+	OIDBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	OIDNonce         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+
+	ErrMismatch = errors.New("gogost/ocsp: response does not match the request")
+)
+
+type certID struct {
+	HashAlgorithm  pkix.AlgorithmIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+}
+
+type oneRequest struct {
+	Cert       certID
+	Extensions []pkix.Extension `asn1:"optional,explicit,tag:0"`
+}
+
+type tbsRequest struct {
+	Version       int           `asn1:"optional,explicit,default:0,tag:0"`
+	RequestorName asn1.RawValue `asn1:"optional,explicit,tag:1"`
+	RequestList   []oneRequest
+	Extensions    []pkix.Extension `asn1:"optional,explicit,tag:2"`
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+	Signature  asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"optional,explicit,tag:0"`
+}
+
+type basicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type responseData struct {
+	Version     int `asn1:"optional,explicit,default:0,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []singleResponse
+	Extensions  []pkix.Extension `asn1:"optional,explicit,tag:1"`
+}
+
+type singleResponse struct {
+	Cert       certID
+	Status     asn1.RawValue
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"optional,explicit,tag:1"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional,default:-1"`
+}
+
+func unmarshal(der []byte, v interface{}, what string) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("gogost/ocsp: invalid %s: %w", what, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/ocsp: trailing data after %s", what)
+	}
+	return nil
+}
+
+func nonceExtension(nonce []byte) ([]pkix.Extension, error) {
+	if nonce == nil {
+		return nil, nil
+	}
+	der, err := asn1.Marshal(nonce)
+	if err != nil {
+		return nil, err
+	}
+	return []pkix.Extension{{Id: OIDNonce, Value: der}}, nil
+}
+
+func findNonce(exts []pkix.Extension) ([]byte, error) {
+	for _, ext := range exts {
+		if !ext.Id.Equal(OIDNonce) {
+			continue
+		}
+		var nonce []byte
+		if err := unmarshal(ext.Value, &nonce, "nonce"); err != nil {
+			return nil, err
+		}
+		return nonce, nil
+	}
+	return nil, nil
+}
+
+// Hashes of issuer's name and its subjectPublicKey value.
+func issuerHashes(issuer *x509.Certificate, digest asn1.ObjectIdentifier) (nameHash, keyHash []byte, err error) {
+	h := gost3410.NewHash(digest)
+	if h == nil {
+		return nil, nil, fmt.Errorf("gogost/ocsp: unsupported digest algorithm %s", digest)
+	}
+	var spki struct {
+		Algo      pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if err = unmarshal(issuer.RawSubjectPublicKeyInfo, &spki, "SubjectPublicKeyInfo"); err != nil {
+		return
+	}
+	h.Write(issuer.RawSubject)
+	nameHash = h.Sum(nil)
+	h.Reset()
+	h.Write(spki.PublicKey.RightAlign())
+	keyHash = h.Sum(nil)
+	return
+}
+
+// SHA-1 hash of the certificate's subjectPublicKey value, used in
+// byKey ResponderID.
+func responderKeyHash(cert *x509.Certificate) ([]byte, error) {
+	var spki struct {
+		Algo      pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if err := unmarshal(cert.RawSubjectPublicKeyInfo, &spki, "SubjectPublicKeyInfo"); err != nil {
+		return nil, err
+	}
+	h := sha1.Sum(spki.PublicKey.RightAlign())
+	return h[:], nil
+}
+
+// Check that byName or byKey ResponderID identifies the certificate.
+func responderIs(id asn1.RawValue, cert *x509.Certificate) bool {
+	if id.Class != asn1.ClassContextSpecific {
+		return false
+	}
+	switch id.Tag {
+	case 1:
+		return bytes.Equal(id.Bytes, cert.RawSubject)
+	case 2:
+		var keyHash []byte
+		if err := unmarshal(id.Bytes, &keyHash, "KeyHash"); err != nil {
+			return false
+		}
+		expected, err := responderKeyHash(cert)
+		return err == nil && bytes.Equal(keyHash, expected)
+	}
+	return false
+}
+
+// Status request of single certificate.
+type Request struct {
+	// Digest algorithm of CertID hashes.
+	HashAlgorithm  asn1.ObjectIdentifier
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+
+	// Optional nonce, that must be repeated in the response.
+	Nonce []byte
+}
+
+// Create the request for the certificate issued by the issuer. CertID
+// is made with the digest algorithm (for example
+// gost3410.OIDTc26Gost34112012256). Random nonce is added if rand is
+// not nil.
+func NewRequest(
+	rand io.Reader,
+	cert, issuer *x509.Certificate,
+	digest asn1.ObjectIdentifier,
+) (*Request, error) {
+	nameHash, keyHash, err := issuerHashes(issuer, digest)
+	if err != nil {
+		return nil, err
+	}
+	req := Request{
+		HashAlgorithm:  digest,
+		IssuerNameHash: nameHash,
+		IssuerKeyHash:  keyHash,
+		SerialNumber:   cert.SerialNumber,
+	}
+	if rand != nil {
+		req.Nonce = make([]byte, NonceSize)
+		if _, err = io.ReadFull(rand, req.Nonce); err != nil {
+			return nil, err
+		}
+	}
+	return &req, nil
+}
+
+func (req *Request) certID() certID {
+	return certID{
+		HashAlgorithm:  pkix.AlgorithmIdentifier{Algorithm: req.HashAlgorithm},
+		IssuerNameHash: req.IssuerNameHash,
+		IssuerKeyHash:  req.IssuerKeyHash,
+		SerialNumber:   req.SerialNumber,
+	}
+}
+
+// DER encoded unsigned OCSPRequest.
+func (req *Request) Marshal() ([]byte, error) {
+	exts, err := nonceExtension(req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ocspRequest{TBSRequest: tbsRequest{
+		RequestList: []oneRequest{{Cert: req.certID()}},
+		Extensions:  exts,
+	}})
+}
+
+// Parse DER encoded OCSPRequest with single certificate. Signature,
+// if any, is ignored.
+func ParseRequest(der []byte) (*Request, error) {
+	var req ocspRequest
+	if err := unmarshal(der, &req, "OCSPRequest"); err != nil {
+		return nil, err
+	}
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, errors.New("gogost/ocsp: only single certificate requests are supported")
+	}
+	id := req.TBSRequest.RequestList[0].Cert
+	nonce, err := findNonce(req.TBSRequest.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	return &Request{
+		HashAlgorithm:  id.HashAlgorithm.Algorithm,
+		IssuerNameHash: id.IssuerNameHash,
+		IssuerKeyHash:  id.IssuerKeyHash,
+		SerialNumber:   id.SerialNumber,
+		Nonce:          nonce,
+	}, nil
+}
+
+// Is the request about certificate issued by the issuer.
+func (req *Request) Matches(issuer *x509.Certificate) bool {
+	nameHash, keyHash, err := issuerHashes(issuer, req.HashAlgorithm)
+	return err == nil &&
+		bytes.Equal(nameHash, req.IssuerNameHash) &&
+		bytes.Equal(keyHash, req.IssuerKeyHash)
+}
+
+// Non-successful response status.
+type ResponseError struct {
+	Status int
+}
+
+func (err ResponseError) Error() string {
+	return fmt.Sprintf("gogost/ocsp: response status %d", err.Status)
+}
+
+// Single certificate status response.
+type Response struct {
+	// Good, Revoked or Unknown.
+	Status       int
+	SerialNumber *big.Int
+
+	ProducedAt time.Time
+	ThisUpdate time.Time
+	NextUpdate time.Time
+
+	RevokedAt        time.Time
+	RevocationReason int
+
+	// Digest algorithm of CertID hashes, as in the request.
+	IssuerHash asn1.ObjectIdentifier
+
+	Nonce []byte
+
+	// Delegated responder's certificate, nil if the issuer signed it.
+	Certificate *x509.Certificate
+
+	// Responder is identified by SHA-1 hash of its public key (byKey)
+	// instead of its name.
+	ResponderByKey bool
+
+	// DER encoded OCSPResponse.
+	Raw []byte
+}
+
+// Create DER encoded unsuccessful OCSPResponse.
+func CreateErrorResponse(status int) []byte {
+	der, err := asn1.Marshal(ocspResponse{Status: asn1.Enumerated(status)})
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+// Create DER encoded successful OCSPResponse signed with the
+// responder's certificate key (either the issuer itself or delegated
+// responder with OCSPSigning extended key usage). Status,
+// SerialNumber, ThisUpdate, NextUpdate, RevokedAt, RevocationReason,
+// IssuerHash, Nonce and ResponderByKey are taken from the template.
+// ProducedAt is current time if zero. signer is used as in
+// x509gost.CreateCertificate.
+func CreateResponse(
+	rand io.Reader,
+	issuer, responder *x509.Certificate,
+	template Response,
+	signer crypto.Signer,
+) ([]byte, error) {
+	_, keyAlgo, err := x509gost.PublicKey(responder)
+	if err != nil {
+		return nil, err
+	}
+	digest := template.IssuerHash
+	if digest == nil {
+		digest = gost3410.OIDTc26Gost34112012256
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, digest)
+	if err != nil {
+		return nil, err
+	}
+	var status asn1.RawValue
+	switch template.Status {
+	case Good:
+		status = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: []byte{}}
+	case Unknown:
+		status = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 2, Bytes: []byte{}}
+	case Revoked:
+		info := revokedInfo{
+			RevocationTime: template.RevokedAt.UTC(),
+			Reason:         asn1.Enumerated(template.RevocationReason),
+		}
+		if template.RevocationReason == 0 {
+			info.Reason = -1
+		}
+		der, err := asn1.Marshal(info)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = asn1.Unmarshal(der, &status); err != nil {
+			return nil, err
+		}
+		status = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, IsCompound: true, Bytes: status.Bytes}
+	default:
+		return nil, fmt.Errorf("gogost/ocsp: invalid status %d", template.Status)
+	}
+	exts, err := nonceExtension(template.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	producedAt := template.ProducedAt
+	if producedAt.IsZero() {
+		producedAt = time.Now()
+	}
+	single := singleResponse{
+		Cert: certID{
+			HashAlgorithm:  pkix.AlgorithmIdentifier{Algorithm: digest},
+			IssuerNameHash: nameHash,
+			IssuerKeyHash:  keyHash,
+			SerialNumber:   template.SerialNumber,
+		},
+		Status:     status,
+		ThisUpdate: template.ThisUpdate.UTC(),
+	}
+	if !template.NextUpdate.IsZero() {
+		single.NextUpdate = template.NextUpdate.UTC()
+	}
+	responderID := asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        1,
+		IsCompound: true,
+		Bytes:      responder.RawSubject,
+	}
+	if template.ResponderByKey {
+		keyHash, err := responderKeyHash(responder)
+		if err != nil {
+			return nil, err
+		}
+		responderID.Tag = 2
+		if responderID.Bytes, err = asn1.Marshal(keyHash); err != nil {
+			return nil, err
+		}
+	}
+	tbs, err := asn1.Marshal(responseData{
+		ResponderID: responderID,
+		ProducedAt:  producedAt.UTC().Truncate(time.Second),
+		Responses:   []singleResponse{single},
+		Extensions:  exts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	algo, sig, err := x509gost.SignData(rand, signer, keyAlgo, tbs)
+	if err != nil {
+		return nil, err
+	}
+	basic := basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: algo,
+		Signature:          asn1.BitString{Bytes: sig, BitLength: 8 * len(sig)},
+	}
+	if !responder.Equal(issuer) {
+		basic.Certificates = []asn1.RawValue{{FullBytes: responder.Raw}}
+	}
+	der, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ocspResponse{
+		Status:   Success,
+		Response: responseBytes{ResponseType: OIDBasicResponse, Response: der},
+	})
+}
+
+func hasOCSPSigning(cert *x509.Certificate) bool {
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			return true
+		}
+	}
+	return false
+}
+
+// Find included responder's certificate identified by ResponderID and
+// issued by the issuer for OCSP signing.
+func delegatedResponder(
+	certs []asn1.RawValue,
+	issuer *x509.Certificate,
+	id asn1.RawValue,
+) (*x509.Certificate, error) {
+	for _, raw := range certs {
+		cert, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !responderIs(id, cert) ||
+			!bytes.Equal(cert.RawIssuer, issuer.RawSubject) ||
+			x509gost.CheckSignatureFrom(cert, issuer) != nil {
+			continue
+		}
+		if !hasOCSPSigning(cert) {
+			return nil, errors.New("gogost/ocsp: responder certificate is not for OCSP signing")
+		}
+		return cert, nil
+	}
+	return nil, errors.New("gogost/ocsp: no responder certificate issued by the issuer")
+}
+
+// Parse DER encoded OCSPResponse and verify its signature made either
+// by the issuer, or by included delegated responder's certificate
+// issued by it with OCSPSigning extended key usage. ResponseError is
+// returned for unsuccessful responses.
+func ParseResponse(der []byte, issuer *x509.Certificate) (*Response, error) {
+	var resp ocspResponse
+	if err := unmarshal(der, &resp, "OCSPResponse"); err != nil {
+		return nil, err
+	}
+	if resp.Status != Success {
+		return nil, ResponseError{int(resp.Status)}
+	}
+	if !resp.Response.ResponseType.Equal(OIDBasicResponse) {
+		return nil, fmt.Errorf("gogost/ocsp: unsupported response type %s", resp.Response.ResponseType)
+	}
+	var basic basicResponse
+	if err := unmarshal(resp.Response.Response, &basic, "BasicOCSPResponse"); err != nil {
+		return nil, err
+	}
+	var data responseData
+	if err := unmarshal(basic.TBSResponseData.FullBytes, &data, "ResponseData"); err != nil {
+		return nil, err
+	}
+	if len(data.Responses) != 1 {
+		return nil, errors.New("gogost/ocsp: only single certificate responses are supported")
+	}
+
+	signer := issuer
+	var delegated *x509.Certificate
+	if !responderIs(data.ResponderID, issuer) {
+		var err error
+		if delegated, err = delegatedResponder(basic.Certificates, issuer, data.ResponderID); err != nil {
+			return nil, err
+		}
+		signer = delegated
+	}
+	pub, keyAlgo, err := x509gost.PublicKey(signer)
+	if err != nil {
+		return nil, err
+	}
+	if basic.Signature.BitLength%8 != 0 {
+		return nil, errors.New("gogost/ocsp: invalid signature bit string")
+	}
+	err = x509gost.CheckSignature(
+		basic.SignatureAlgorithm.Algorithm, keyAlgo, pub,
+		basic.TBSResponseData.FullBytes, basic.Signature.Bytes,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	single := data.Responses[0]
+	r := Response{
+		SerialNumber: single.Cert.SerialNumber,
+		ProducedAt:   data.ProducedAt,
+		ThisUpdate:   single.ThisUpdate,
+		NextUpdate:   single.NextUpdate,
+		IssuerHash:   single.Cert.HashAlgorithm.Algorithm,
+		Certificate:  delegated,
+		Raw:          der,
+
+		ResponderByKey: data.ResponderID.Tag == 2,
+	}
+	nameHash, keyHash, err := issuerHashes(issuer, r.IssuerHash)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(nameHash, single.Cert.IssuerNameHash) || !bytes.Equal(keyHash, single.Cert.IssuerKeyHash) {
+		return nil, errors.New("gogost/ocsp: response is for another issuer")
+	}
+	if single.Status.Class != asn1.ClassContextSpecific {
+		return nil, errors.New("gogost/ocsp: invalid certificate status")
+	}
+	switch single.Status.Tag {
+	case 0:
+		r.Status = Good
+	case 1:
+		r.Status = Revoked
+		var info revokedInfo
+		raw, err := asn1.Marshal(asn1.RawValue{
+			Class:      asn1.ClassUniversal,
+			Tag:        asn1.TagSequence,
+			IsCompound: true,
+			Bytes:      single.Status.Bytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = unmarshal(raw, &info, "RevokedInfo"); err != nil {
+			return nil, err
+		}
+		r.RevokedAt = info.RevocationTime
+		if info.Reason > 0 {
+			r.RevocationReason = int(info.Reason)
+		}
+	case 2:
+		r.Status = Unknown
+	default:
+		return nil, errors.New("gogost/ocsp: invalid certificate status")
+	}
+	if r.Nonce, err = findNonce(data.Extensions); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Check that the response corresponds to the request: the same
+// certificate and nonce, if it was requested.
+func (r *Response) Match(req *Request) error {
+	if r.SerialNumber.Cmp(req.SerialNumber) != 0 || !r.IssuerHash.Equal(req.HashAlgorithm) {
+		return ErrMismatch
+	}
+	if req.Nonce != nil && !bytes.Equal(req.Nonce, r.Nonce) {
+		return ErrMismatch
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ocsp
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/testpki"
+)
+
+var testNow = time.Now().UTC().Truncate(time.Second)
+
+// Issue certificate by the parent (self-signed CA if nil) with the new
+// key.
+func issue(
+	t *testing.T,
+	serial int64,
+	parent *x509.Certificate,
+	parentPrv *gost3410.PrivateKey,
+	eku []x509.ExtKeyUsage,
+) (*x509.Certificate, *gost3410.PrivateKey) {
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: big.NewInt(serial).String()},
+		ExtKeyUsage:  eku,
+	}
+	if parent == nil {
+		tmpl.BasicConstraintsValid, tmpl.IsCA = true, true
+	}
+	return testpki.New(t, gost3410.CurveIdtc26gost34102012256paramSetA(), &tmpl, parent, parentPrv)
+}
+
+func lookup(serial *big.Int) (Response, error) {
+	switch serial.Int64() {
+	case 10:
+		return Response{Status: Good}, nil
+	case 11:
+		return Response{Status: Revoked, RevokedAt: testNow, RevocationReason: 1}, nil
+	case 12:
+		return Response{}, errors.New("database failure")
+	}
+	return Response{Status: Unknown}, nil
+}
+
+func TestQuery(t *testing.T) {
+	ca, caPrv := issue(t, 1, nil, nil, nil)
+	delegated, delegatedPrv := issue(t, 2, ca, caPrv, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning})
+	for _, r := range []*Responder{
+		{Issuer: ca, Key: caPrv, Lookup: lookup, Validity: time.Hour},
+		{Issuer: ca, Certificate: delegated, Key: delegatedPrv, Lookup: lookup},
+	} {
+		srv := httptest.NewServer(r)
+		for _, tc := range []struct {
+			serial int64
+			status int
+		}{{10, Good}, {11, Revoked}, {13, Unknown}} {
+			cert, _ := issue(t, tc.serial, ca, caPrv, nil)
+			for _, digest := range []asn1.ObjectIdentifier{
+				gost3410.OIDTc26Gost34112012256,
+				gost3410.OIDTc26Gost34112012512,
+			} {
+				req, err := NewRequest(rand.Reader, cert, ca, digest)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := Query(srv.Client(), srv.URL, req, ca)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if resp.Status != tc.status {
+					t.Fatal("status differs", tc.serial)
+				}
+				if (r.Certificate == nil) != (resp.Certificate == nil) {
+					t.Fatal("responder certificate differs")
+				}
+				if r.Validity > 0 && !resp.NextUpdate.Equal(resp.ThisUpdate.Add(r.Validity)) {
+					t.Fatal("NextUpdate differs")
+				}
+				if tc.status == Revoked &&
+					(!resp.RevokedAt.Equal(testNow) || resp.RevocationReason != 1) {
+					t.Fatal("revocation differs")
+				}
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestGET(t *testing.T) {
+	ca, caPrv := issue(t, 1, nil, nil, nil)
+	cert, _ := issue(t, 10, ca, caPrv, nil)
+	srv := httptest.NewServer(&Responder{Issuer: ca, Key: caPrv, Lookup: lookup})
+	defer srv.Close()
+	req, err := NewRequest(nil, cert, ca, gost3410.OIDTc26Gost34112012256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqDER, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpResp, err := srv.Client().Get(srv.URL + "/" + base64.StdEncoding.EncodeToString(reqDER))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+	der, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := ParseResponse(der, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != Good || resp.Match(req) != nil {
+		t.Fatal("response differs")
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		t.Fatal(httpResp.Status)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ca, caPrv := issue(t, 1, nil, nil, nil)
+	other, otherPrv := issue(t, 1, nil, nil, nil)
+	r := Responder{Issuer: ca, Key: caPrv, Lookup: lookup}
+
+	cert, _ := issue(t, 12, ca, caPrv, nil)
+	req, err := NewRequest(rand.Reader, cert, ca, gost3410.OIDTc26Gost34112012256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqDER, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseResponse(r.Respond(reqDER), ca); err != (ResponseError{InternalError}) {
+		t.Fatal("internal error expected", err)
+	}
+	if _, err = ParseResponse(r.Respond([]byte("junk")), ca); err != (ResponseError{Malformed}) {
+		t.Fatal("malformed expected", err)
+	}
+
+	foreign, _ := issue(t, 10, other, otherPrv, nil)
+	if req, err = NewRequest(rand.Reader, foreign, other, gost3410.OIDTc26Gost34112012256); err != nil {
+		t.Fatal(err)
+	}
+	if reqDER, err = req.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseResponse(r.Respond(reqDER), ca); err != (ResponseError{Unauthorized}) {
+		t.Fatal("unauthorized expected", err)
+	}
+
+	// Response signed by the same named, but another key
+	forged := Responder{Issuer: other, Key: otherPrv, Lookup: lookup}
+	resp, err := ParseResponse(forged.Respond(reqDER), other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseResponse(resp.Raw, ca); err == nil {
+		t.Fatal("foreign signature accepted")
+	}
+
+	// Non-OCSP signing delegated responder
+	delegated, delegatedPrv := issue(t, 2, ca, caPrv, nil)
+	cert, _ = issue(t, 10, ca, caPrv, nil)
+	if req, err = NewRequest(rand.Reader, cert, ca, gost3410.OIDTc26Gost34112012256); err != nil {
+		t.Fatal(err)
+	}
+	if reqDER, err = req.Marshal(); err != nil {
+		t.Fatal(err)
+	}
+	r = Responder{Issuer: ca, Certificate: delegated, Key: delegatedPrv, Lookup: lookup}
+	if _, err = ParseResponse(r.Respond(reqDER), ca); err == nil {
+		t.Fatal("non OCSP signing responder accepted")
+	}
+
+	// Nonce mismatch
+	r = Responder{Issuer: ca, Key: caPrv, Lookup: lookup}
+	if resp, err = ParseResponse(r.Respond(reqDER), ca); err != nil {
+		t.Fatal(err)
+	}
+	req.Nonce[0] ^= 1
+	if resp.Match(req) != ErrMismatch {
+		t.Fatal("nonce mismatch accepted")
+	}
+}
+
+func TestResponderByKey(t *testing.T) {
+	ca, caPrv := issue(t, 1, nil, nil, nil)
+	other, otherPrv := issue(t, 1, nil, nil, nil)
+	delegated, delegatedPrv := issue(t, 2, ca, caPrv, []x509.ExtKeyUsage{x509.ExtKeyUsageOCSPSigning})
+	cert, _ := issue(t, 10, ca, caPrv, nil)
+	tmpl := Response{
+		Status:         Good,
+		SerialNumber:   cert.SerialNumber,
+		ThisUpdate:     testNow,
+		ResponderByKey: true,
+	}
+	for _, tc := range []struct {
+		responder *x509.Certificate
+		prv       *gost3410.PrivateKey
+	}{{ca, caPrv}, {delegated, delegatedPrv}} {
+		der, err := CreateResponse(rand.Reader, ca, tc.responder, tmpl, tc.prv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := ParseResponse(der, ca)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.ResponderByKey || resp.Status != Good ||
+			(tc.responder == ca) != (resp.Certificate == nil) {
+			t.Fatal("response differs")
+		}
+	}
+
+	// Issuer with the same name, but another key
+	der, err := CreateResponse(rand.Reader, ca, other, tmpl, otherPrv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseResponse(der, ca); err == nil {
+		t.Fatal("another key's response accepted")
+	}
+}
@@ -0,0 +1,182 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package ocsp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+const (
+	ContentTypeRequest  = "application/ocsp-request"
+	ContentTypeResponse = "application/ocsp-response"
+
+	// Maximal accepted HTTP request size.
+	MaxRequestSize = 1 << 14
+)
+
+// OCSP responder for the certificates of single issuer.
+type Responder struct {
+	Issuer *x509.Certificate
+
+	// Responder's certificate: either the Issuer itself (if nil) or
+	// delegated one with OCSPSigning extended key usage.
+	Certificate *x509.Certificate
+	Key         *gost3410.PrivateKey
+
+	// Get status of the certificate with the serial number: Status,
+	// RevokedAt and RevocationReason fields are used. Error leads to
+	// internalError response.
+	Lookup func(serial *big.Int) (Response, error)
+
+	// Validity period of the responses, NextUpdate is omitted if zero.
+	Validity time.Duration
+
+	// Source of randomness, crypto/rand by default.
+	Rand io.Reader
+
+	// Current time source, time.Now by default.
+	Now func() time.Time
+}
+
+// Answer DER encoded OCSPRequest with DER encoded OCSPResponse.
+func (r *Responder) Respond(reqDER []byte) []byte {
+	req, err := ParseRequest(reqDER)
+	if err != nil {
+		return CreateErrorResponse(Malformed)
+	}
+	if !req.Matches(r.Issuer) {
+		return CreateErrorResponse(Unauthorized)
+	}
+	tmpl, err := r.Lookup(req.SerialNumber)
+	if err != nil {
+		return CreateErrorResponse(InternalError)
+	}
+	now := time.Now
+	if r.Now != nil {
+		now = r.Now
+	}
+	tmpl.SerialNumber = req.SerialNumber
+	tmpl.IssuerHash = req.HashAlgorithm
+	tmpl.Nonce = req.Nonce
+	tmpl.ProducedAt = now()
+	tmpl.ThisUpdate = tmpl.ProducedAt.Truncate(time.Second)
+	if r.Validity > 0 {
+		tmpl.NextUpdate = tmpl.ThisUpdate.Add(r.Validity)
+	}
+	rnd := r.Rand
+	if rnd == nil {
+		rnd = rand.Reader
+	}
+	responder := r.Certificate
+	if responder == nil {
+		responder = r.Issuer
+	}
+	der, err := CreateResponse(rnd, r.Issuer, responder, tmpl, r.Key)
+	if err != nil {
+		return CreateErrorResponse(InternalError)
+	}
+	return der
+}
+
+// Serve RFC 6960 HTTP transport: POSTed request, or base64 encoded
+// one in GET's path.
+func (r *Responder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var reqDER []byte
+	var err error
+	switch req.Method {
+	case http.MethodGet:
+		path := strings.TrimPrefix(req.URL.Path, "/")
+		if unescaped, err := url.PathUnescape(path); err == nil {
+			path = unescaped
+		}
+		if reqDER, err = base64.StdEncoding.DecodeString(path); err != nil {
+			http.Error(w, "invalid base64", http.StatusBadRequest)
+			return
+		}
+	case http.MethodPost:
+		if req.Header.Get("Content-Type") != ContentTypeRequest {
+			http.Error(w, "unexpected Content-Type", http.StatusUnsupportedMediaType)
+			return
+		}
+		reqDER, err = io.ReadAll(io.LimitReader(req.Body, MaxRequestSize+1))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(reqDER) > MaxRequestSize {
+			http.Error(w, "too big request", http.StatusRequestEntityTooLarge)
+			return
+		}
+	default:
+		http.Error(w, "GET or POST expected", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", ContentTypeResponse)
+	w.Write(r.Respond(reqDER))
+}
+
+// POST the request to the responder over HTTP, verify the response
+// and check that it matches the request. http.DefaultClient is used if
+// client is nil.
+func Query(
+	client *http.Client,
+	url string,
+	req *Request,
+	issuer *x509.Certificate,
+) (*Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	reqDER, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	httpResp, err := client.Post(url, ContentTypeRequest, bytes.NewReader(reqDER))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gogost/ocsp: HTTP status %s", httpResp.Status)
+	}
+	if ct := httpResp.Header.Get("Content-Type"); ct != ContentTypeResponse {
+		return nil, fmt.Errorf("gogost/ocsp: unexpected Content-Type %q", ct)
+	}
+	der, err := io.ReadAll(io.LimitReader(httpResp.Body, MaxRequestSize<<4))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ParseResponse(der, issuer)
+	if err != nil {
+		return nil, err
+	}
+	if err = resp.Match(req); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
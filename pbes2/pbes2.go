@@ -0,0 +1,378 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// PKCS#5 PBES2 password-based encryption with PBKDF2 HMAC-Streebog (or
+// legacy HMAC-GOST R 34.11-94) and GOST ciphers (RFC 9337):
+// Kuznechik/Magma CTR-ACPKM, CTR-ACPKM-OMAC and GOST 28147-89 CFB.
+//
+// In CTR-ACPKM-OMAC schemes the encryption and MAC keys are derived
+// with KDF_TREE_GOSTR3411_2012_256 from the PBKDF2 output and the seed,
+// the OMAC of the plaintext is appended to it and encrypted together.
+// Sections of 4096 (Kuznechik) and 1024 (Magma) bytes are used for the
+// ACPKM key meshing.
+package pbes2
+
+import (
+	"crypto/hmac"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/pedroalbanese/gogost/acpkm"
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/gost341194"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	KeySize = 32
+
+	DefaultIterations = 2000
+	DefaultSaltSize   = 32
+)
+
+var (
+	OIDPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	OIDPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	OIDHMACGostR341194     = asn1.ObjectIdentifier{1, 2, 643, 2, 2, 10}
+	OIDHMACGost34112012256 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 4, 1}
+	OIDHMACGost34112012512 = asn1.ObjectIdentifier{1, 2, 643, 7, 1, 1, 4, 2}
+
+	OIDMagmaCTRACPKM         = acpkm.OIDMagmaCTRACPKM
+	OIDMagmaCTRACPKMOMAC     = acpkm.OIDMagmaCTRACPKMOMAC
+	OIDKuznechikCTRACPKM     = acpkm.OIDKuznechikCTRACPKM
+	OIDKuznechikCTRACPKMOMAC = acpkm.OIDKuznechikCTRACPKMOMAC
+
+	ErrIntegrity = errors.New("gogost/pbes2: integrity check failed")
+)
+
+type pbes2Params struct {
+	KDF              pkix.AlgorithmIdentifier
+	EncryptionScheme pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// Gost28147-89-Parameters.
+type gost28147Params struct {
+	IV                 []byte
+	EncryptionParamSet asn1.ObjectIdentifier
+}
+
+type Opts struct {
+	// Encryption scheme identifier, either one of CTR-ACPKM(-OMAC),
+	// or gost28147.OIDGost2814789. OIDKuznechikCTRACPKMOMAC by default.
+	Cipher asn1.ObjectIdentifier
+
+	// Sbox for GOST 28147-89 CFB. gost28147.SboxDefault by default.
+	Sbox *gost28147.Sbox
+
+	// PBKDF2 PRF identifier. OIDHMACGost34112012512 by default.
+	PRF asn1.ObjectIdentifier
+
+	Iterations int
+	SaltSize   int
+}
+
+func newGostR341194() hash.Hash {
+	return gost341194.New(&gost28147.SboxIdGostR341194CryptoProParamSet)
+}
+
+func prfHash(oid asn1.ObjectIdentifier) func() hash.Hash {
+	switch {
+	case oid.Equal(OIDHMACGost34112012512):
+		return gost34112012512.New
+	case oid.Equal(OIDHMACGost34112012256):
+		return gost34112012256.New
+	case oid.Equal(OIDHMACGostR341194):
+		return newGostR341194
+	}
+	return nil
+}
+
+func marshalAlgo(oid asn1.ObjectIdentifier, params interface{}) (pkix.AlgorithmIdentifier, error) {
+	ai := pkix.AlgorithmIdentifier{Algorithm: oid}
+	der, err := asn1.Marshal(params)
+	if err != nil {
+		return ai, err
+	}
+	ai.Parameters = asn1.RawValue{FullBytes: der}
+	return ai, nil
+}
+
+func unmarshalParams(ai pkix.AlgorithmIdentifier, params interface{}) error {
+	rest, err := asn1.Unmarshal(ai.Parameters.FullBytes, params)
+	if err != nil {
+		return fmt.Errorf("gogost/pbes2: invalid %s parameters: %w", ai.Algorithm, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/pbes2: trailing data after %s parameters", ai.Algorithm)
+	}
+	return nil
+}
+
+// Encrypt data with the key derived from the password. PBES2 algorithm
+// identifier and ciphertext are returned.
+func Encrypt(
+	rand io.Reader,
+	password, data []byte,
+	opts *Opts,
+) (pkix.AlgorithmIdentifier, []byte, error) {
+	var ai pkix.AlgorithmIdentifier
+	if opts == nil {
+		opts = &Opts{}
+	}
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = DefaultIterations
+	}
+	saltSize := opts.SaltSize
+	if saltSize == 0 {
+		saltSize = DefaultSaltSize
+	}
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return ai, nil, err
+	}
+	prfOID := opts.PRF
+	if prfOID == nil {
+		prfOID = OIDHMACGost34112012512
+	}
+	h := prfHash(prfOID)
+	if h == nil {
+		return ai, nil, fmt.Errorf("gogost/pbes2: unsupported PRF %s", prfOID)
+	}
+	prf, err := marshalAlgo(prfOID, asn1.NullRawValue)
+	if err != nil {
+		return ai, nil, err
+	}
+	kdf, err := marshalAlgo(OIDPBKDF2, pbkdf2Params{
+		Salt:           salt,
+		IterationCount: iterations,
+		KeyLength:      KeySize,
+		PRF:            prf,
+	})
+	if err != nil {
+		return ai, nil, err
+	}
+	key := pbkdf2.Key(password, salt, iterations, KeySize, h)
+
+	oid := opts.Cipher
+	if oid == nil {
+		oid = OIDKuznechikCTRACPKMOMAC
+	}
+	var enc pkix.AlgorithmIdentifier
+	var ct []byte
+	if oid.Equal(gost28147.OIDGost2814789) {
+		sbox := opts.Sbox
+		if sbox == nil {
+			sbox = gost28147.SboxDefault
+		}
+		paramSet := sbox.OID()
+		if paramSet == nil {
+			return ai, nil, errors.New("gogost/pbes2: Sbox has no identifier")
+		}
+		iv := make([]byte, gost28147.BlockSize)
+		if _, err = io.ReadFull(rand, iv); err != nil {
+			return ai, nil, err
+		}
+		enc, err = marshalAlgo(oid, gost28147Params{IV: iv, EncryptionParamSet: paramSet})
+		if err != nil {
+			return ai, nil, err
+		}
+		ct = make([]byte, len(data))
+		_, meshing := gost28147.SboxByOID(paramSet)
+		c := gost28147.NewCipher(key, sbox)
+		if meshing {
+			c.NewCFBEncrypterMeshing(iv).XORKeyStream(ct, data)
+		} else {
+			c.NewCFBEncrypter(iv).XORKeyStream(ct, data)
+		}
+	} else {
+		s := acpkm.SchemeByOID(oid)
+		if s == nil {
+			return ai, nil, fmt.Errorf("gogost/pbes2: unsupported cipher %s", oid)
+		}
+		ukm := make([]byte, s.UKMSize())
+		if _, err = io.ReadFull(rand, ukm); err != nil {
+			return ai, nil, err
+		}
+		enc, err = marshalAlgo(oid, acpkm.Params{UKM: ukm})
+		if err != nil {
+			return ai, nil, err
+		}
+		iv := ukm[:s.BlockSize/2]
+		ct = make([]byte, len(data), len(data)+s.BlockSize)
+		copy(ct, data)
+		if s.OMAC {
+			var kMac []byte
+			key, kMac = acpkm.OMACKeys(key, ukm[s.BlockSize/2:])
+			ct = append(ct, s.MAC(kMac, data)...)
+		}
+		s.Crypt(key, iv, ct, ct)
+	}
+	ai, err = marshalAlgo(OIDPBES2, pbes2Params{KDF: kdf, EncryptionScheme: enc})
+	return ai, ct, err
+}
+
+func deriveKey(password []byte, kdf pkix.AlgorithmIdentifier) ([]byte, error) {
+	if !kdf.Algorithm.Equal(OIDPBKDF2) {
+		return nil, fmt.Errorf("gogost/pbes2: unsupported KDF %s", kdf.Algorithm)
+	}
+	var params pbkdf2Params
+	if err := unmarshalParams(kdf, &params); err != nil {
+		return nil, err
+	}
+	if params.IterationCount <= 0 {
+		return nil, errors.New("gogost/pbes2: invalid iteration count")
+	}
+	if params.KeyLength != 0 && params.KeyLength != KeySize {
+		return nil, fmt.Errorf("gogost/pbes2: invalid key length %d", params.KeyLength)
+	}
+	h := prfHash(params.PRF.Algorithm)
+	if h == nil {
+		return nil, fmt.Errorf("gogost/pbes2: unsupported PRF %s", params.PRF.Algorithm)
+	}
+	return pbkdf2.Key(password, params.Salt, params.IterationCount, KeySize, h), nil
+}
+
+// Decrypt ciphertext with the password, using PBES2 algorithm
+// identifier. ErrIntegrity is returned if OMAC does not match.
+func Decrypt(password []byte, ai pkix.AlgorithmIdentifier, ct []byte) ([]byte, error) {
+	if !ai.Algorithm.Equal(OIDPBES2) {
+		return nil, fmt.Errorf("gogost/pbes2: unsupported algorithm %s", ai.Algorithm)
+	}
+	var params pbes2Params
+	if err := unmarshalParams(ai, &params); err != nil {
+		return nil, err
+	}
+	key, err := deriveKey(password, params.KDF)
+	if err != nil {
+		return nil, err
+	}
+	enc := params.EncryptionScheme
+	if enc.Algorithm.Equal(gost28147.OIDGost2814789) {
+		var p gost28147Params
+		if err = unmarshalParams(enc, &p); err != nil {
+			return nil, err
+		}
+		if len(p.IV) != gost28147.BlockSize {
+			return nil, errors.New("gogost/pbes2: invalid IV length")
+		}
+		sbox, meshing := gost28147.SboxByOID(p.EncryptionParamSet)
+		if sbox == nil {
+			return nil, fmt.Errorf("gogost/pbes2: unknown parameter set %s", p.EncryptionParamSet)
+		}
+		pt := make([]byte, len(ct))
+		c := gost28147.NewCipher(key, sbox)
+		if meshing {
+			c.NewCFBDecrypterMeshing(p.IV).XORKeyStream(pt, ct)
+		} else {
+			c.NewCFBDecrypter(p.IV).XORKeyStream(pt, ct)
+		}
+		return pt, nil
+	}
+	s := acpkm.SchemeByOID(enc.Algorithm)
+	if s == nil {
+		return nil, fmt.Errorf("gogost/pbes2: unsupported cipher %s", enc.Algorithm)
+	}
+	var p acpkm.Params
+	if err = unmarshalParams(enc, &p); err != nil {
+		return nil, err
+	}
+	if len(p.UKM) != s.UKMSize() {
+		return nil, fmt.Errorf("gogost/pbes2: invalid UKM length (%d!=%d)", len(p.UKM), s.UKMSize())
+	}
+	iv := p.UKM[:s.BlockSize/2]
+	if !s.OMAC {
+		pt := make([]byte, len(ct))
+		s.Crypt(key, iv, pt, ct)
+		return pt, nil
+	}
+	if len(ct) < s.BlockSize {
+		return nil, ErrIntegrity
+	}
+	key, kMac := acpkm.OMACKeys(key, p.UKM[s.BlockSize/2:])
+	pt := make([]byte, len(ct))
+	s.Crypt(key, iv, pt, ct)
+	pt, tag := pt[:len(pt)-s.BlockSize], pt[len(pt)-s.BlockSize:]
+	if !hmac.Equal(s.MAC(kMac, pt), tag) {
+		return nil, ErrIntegrity
+	}
+	return pt, nil
+}
+
+// EncryptedPrivateKeyInfo (RFC 5958).
+type EncryptedPrivateKeyInfo struct {
+	Algo          pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// Encrypt PKCS#8 PrivateKeyInfo DER to EncryptedPrivateKeyInfo DER.
+func EncryptPKCS8(rand io.Reader, password, der []byte, opts *Opts) ([]byte, error) {
+	ai, ct, err := Encrypt(rand, password, der, opts)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(EncryptedPrivateKeyInfo{Algo: ai, EncryptedData: ct})
+}
+
+// Decrypt EncryptedPrivateKeyInfo DER to PKCS#8 PrivateKeyInfo DER.
+func DecryptPKCS8(password, der []byte) ([]byte, error) {
+	var info EncryptedPrivateKeyInfo
+	rest, err := asn1.Unmarshal(der, &info)
+	if err != nil {
+		return nil, fmt.Errorf("gogost/pbes2: invalid EncryptedPrivateKeyInfo: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("gogost/pbes2: trailing data after EncryptedPrivateKeyInfo")
+	}
+	return Decrypt(password, info.Algo, info.EncryptedData)
+}
+
+// Encrypt the private key to EncryptedPrivateKeyInfo DER.
+func EncryptPrivateKey(
+	rand io.Reader,
+	password []byte,
+	prv *gost3410.PrivateKey,
+	opts *Opts,
+) ([]byte, error) {
+	der, err := gost3410.MarshalPKCS8PrivateKey(prv)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptPKCS8(rand, password, der, opts)
+}
+
+// Decrypt the private key from EncryptedPrivateKeyInfo DER.
+func DecryptPrivateKey(password, der []byte) (*gost3410.PrivateKey, error) {
+	pt, err := DecryptPKCS8(password, der)
+	if err != nil {
+		return nil, err
+	}
+	return gost3410.ParsePKCS8PrivateKey(pt)
+}
@@ -0,0 +1,162 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pbes2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/asn1"
+	"testing"
+	"testing/quick"
+
+	"github.com/pedroalbanese/gogost/acpkm"
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+var testCiphers = []asn1.ObjectIdentifier{
+	OIDKuznechikCTRACPKM,
+	OIDKuznechikCTRACPKMOMAC,
+	OIDMagmaCTRACPKM,
+	OIDMagmaCTRACPKMOMAC,
+	gost28147.OIDGost2814789,
+}
+
+func TestRoundtrip(t *testing.T) {
+	for _, oid := range testCiphers {
+		opts := &Opts{Cipher: oid, Iterations: 10}
+		f := func(password, data []byte) bool {
+			ai, ct, err := Encrypt(rand.Reader, password, data, opts)
+			if err != nil {
+				return false
+			}
+			pt, err := Decrypt(password, ai, ct)
+			if err != nil {
+				return false
+			}
+			return bytes.Equal(pt, data)
+		}
+		if err := quick.Check(f, nil); err != nil {
+			t.Error(oid, err)
+		}
+	}
+}
+
+func TestSections(t *testing.T) {
+	data := make([]byte, 3*4096+123)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatal(err)
+	}
+	for _, oid := range testCiphers {
+		ai, ct, err := Encrypt(rand.Reader, []byte("password"), data, &Opts{
+			Cipher: oid, Iterations: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt, err := Decrypt([]byte("password"), ai, ct)
+		if err != nil {
+			t.Fatal(oid, err)
+		}
+		if !bytes.Equal(pt, data) {
+			t.Fatal(oid)
+		}
+	}
+}
+
+func TestOMACIntegrity(t *testing.T) {
+	for _, oid := range []asn1.ObjectIdentifier{
+		OIDKuznechikCTRACPKMOMAC, OIDMagmaCTRACPKMOMAC,
+	} {
+		ai, ct, err := Encrypt(rand.Reader, []byte("password"), []byte("data"), &Opts{
+			Cipher: oid, Iterations: 1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = Decrypt([]byte("passwore"), ai, ct); err != ErrIntegrity {
+			t.Fatal(oid, "wrong password accepted")
+		}
+		ct[0] ^= 1
+		if _, err = Decrypt([]byte("password"), ai, ct); err != ErrIntegrity {
+			t.Fatal(oid, "modified ciphertext accepted")
+		}
+	}
+}
+
+func TestPrivateKey(t *testing.T) {
+	for _, c := range []*gost3410.Curve{
+		gost3410.CurveIdtc26gost34102012256paramSetA(),
+		gost3410.CurveIdtc26gost34102012512paramSetC(),
+	} {
+		prv, err := gost3410.GenPrivateKey(c, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, oid := range testCiphers {
+			der, err := EncryptPrivateKey(rand.Reader, []byte("password"), prv, &Opts{
+				Cipher: oid, Iterations: 1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecryptPrivateKey([]byte("password"), der)
+			if err != nil {
+				t.Fatal(oid, err)
+			}
+			if got.Key.Cmp(prv.Key) != 0 || !got.C.Equal(c) {
+				t.Fatal(oid)
+			}
+		}
+	}
+}
+
+func TestParameters(t *testing.T) {
+	ai, _, err := Encrypt(rand.Reader, []byte("password"), []byte("data"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var params pbes2Params
+	if err = unmarshalParams(ai, &params); err != nil {
+		t.Fatal(err)
+	}
+	var kdf pbkdf2Params
+	if err = unmarshalParams(params.KDF, &kdf); err != nil {
+		t.Fatal(err)
+	}
+	if len(kdf.Salt) != DefaultSaltSize ||
+		kdf.IterationCount != DefaultIterations ||
+		kdf.KeyLength != KeySize ||
+		!kdf.PRF.Algorithm.Equal(OIDHMACGost34112012512) {
+		t.Fatal("invalid PBKDF2 parameters")
+	}
+	if !params.EncryptionScheme.Algorithm.Equal(OIDKuznechikCTRACPKMOMAC) {
+		t.Fatal("invalid default cipher")
+	}
+	var enc acpkm.Params
+	if err = unmarshalParams(params.EncryptionScheme, &enc); err != nil {
+		t.Fatal(err)
+	}
+	if len(enc.UKM) != 8+acpkm.SeedSize {
+		t.Fatal("invalid UKM length")
+	}
+}
+
+func BenchmarkPBKDF2(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		Encrypt(rand.Reader, []byte("password"), nil, nil)
+	}
+}
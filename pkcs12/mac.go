@@ -0,0 +1,122 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pkcs12
+
+import (
+	"crypto/hmac"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"hash"
+	"unicode/utf16"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/gost341194"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// TC26 profile takes the last 32 bytes of 96 bytes PBKDF2 output as the
+// HMAC key (R 50.1.112-2016).
+const (
+	macKeySize   = 32
+	macKDFOutput = 96
+)
+
+func newGostR341194() hash.Hash {
+	return gost341194.New(&gost28147.SboxIdGostR341194CryptoProParamSet)
+}
+
+// Password as NULL-terminated BMPString, as required by PKCS#12 KDF.
+func bmpString(password string) []byte {
+	var b []byte
+	for _, r := range utf16.Encode([]rune(password)) {
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return append(b, 0, 0)
+}
+
+// PKCS#12 key derivation function (RFC 7292 appendix B.2).
+func pkcs12KDF(h func() hash.Hash, v int, password, salt []byte, id byte, iterations, size int) []byte {
+	fill := func(src []byte) []byte {
+		if len(src) == 0 {
+			return nil
+		}
+		dst := make([]byte, v*((len(src)+v-1)/v))
+		for i := range dst {
+			dst[i] = src[i%len(src)]
+		}
+		return dst
+	}
+	d := make([]byte, v)
+	for i := range d {
+		d[i] = id
+	}
+	i := append(fill(salt), fill(password)...)
+	var out []byte
+	hsh := h()
+	for len(out) < size {
+		hsh.Reset()
+		hsh.Write(d)
+		hsh.Write(i)
+		a := hsh.Sum(nil)
+		for j := 1; j < iterations; j++ {
+			hsh.Reset()
+			hsh.Write(a)
+			a = hsh.Sum(a[:0])
+		}
+		out = append(out, a...)
+		// I_j = (I_j + B + 1) mod 2^v for each v-byte block of I
+		b := fill(a)[:v]
+		for j := 0; j < len(i); j += v {
+			var c uint16 = 1
+			for k := v - 1; k >= 0; k-- {
+				c += uint16(i[j+k]) + uint16(b[k])
+				i[j+k] = byte(c)
+				c >>= 8
+			}
+		}
+	}
+	return out[:size]
+}
+
+// Compute PFX MAC over authSafe contents. TC26 profile is used with
+// Streebog-512 digest, legacy PKCS#12 KDF with GOST R 34.11-94.
+func computeMAC(algo pkix.AlgorithmIdentifier, password string, salt []byte, iterations int, data []byte) ([]byte, error) {
+	if iterations <= 0 {
+		return nil, errors.New("gogost/pkcs12: invalid MAC iterations")
+	}
+	var h func() hash.Hash
+	var key []byte
+	switch {
+	case algo.Algorithm.Equal(gost3410.OIDTc26Gost34112012512):
+		h = gost34112012512.New
+		key = pbkdf2.Key([]byte(password), salt, iterations, macKDFOutput, h)
+		key = key[macKDFOutput-macKeySize:]
+	case algo.Algorithm.Equal(gost3410.OIDGostR341194):
+		h = newGostR341194
+		key = pkcs12KDF(
+			h, gost341194.BlockSize, bmpString(password), salt, 3,
+			iterations, macKeySize,
+		)
+	default:
+		return nil, fmt.Errorf("gogost/pkcs12: unsupported MAC algorithm %s", algo.Algorithm)
+	}
+	m := hmac.New(h, key)
+	m.Write(data)
+	return m.Sum(nil), nil
+}
@@ -0,0 +1,399 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// PKCS#12 (PFX) containers with GOST algorithms (RFC 7292, R 50.1.112-2016).
+//
+// TC26 profile uses PBES2 with PBKDF2 HMAC-Streebog-512 for the bags
+// encryption and HMAC-Streebog-512 for integrity, its key is taken from
+// PBKDF2 output. Legacy profile uses PBES2 with PBKDF2 HMAC-GOST R
+// 34.11-94 and GOST 28147-89 CFB, and HMAC-GOST R 34.11-94 with PKCS#12
+// key derivation for integrity. Both are recognized while decoding.
+package pkcs12
+
+import (
+	"crypto/hmac"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/pedroalbanese/gogost/gost28147"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/internal/ber"
+	"github.com/pedroalbanese/gogost/pbes2"
+)
+
+const (
+	DefaultIterations = 2000
+
+	macSaltSize = 32
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+
+	oidX509Certificate = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidLocalKeyID      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+
+	ErrMAC = errors.New("gogost/pkcs12: MAC verification failed, wrong password?")
+)
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           asn1.RawValue `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue `asn1:"tag:0,explicit"`
+	Attributes []attribute   `asn1:"set,optional"`
+}
+
+type attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+// Explicitly [0] tagged value. encoding/asn1 ignores tagging
+// parameters of RawValue during marshaling, and leaves the tag in it
+// during unmarshaling, so its Bytes hold the tagged element.
+func explicit0(der []byte) asn1.RawValue {
+	return asn1.RawValue{
+		Class:      asn1.ClassContextSpecific,
+		Tag:        0,
+		IsCompound: true,
+		Bytes:      der,
+	}
+}
+
+// Contents of data ContentInfo.
+func dataContent(ci contentInfo) ([]byte, error) {
+	var data []byte
+	if err := unmarshal(ci.Content.Bytes, &data, "data content"); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func unmarshal(der []byte, v interface{}, what string) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("gogost/pkcs12: invalid %s: %w", what, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/pkcs12: trailing data after %s", what)
+	}
+	return nil
+}
+
+// Decode PFX, verifying its MAC if present. The private key (nil if
+// there is none) and DER encoded certificates are returned.
+func Decode(data []byte, password string) (*gost3410.PrivateKey, [][]byte, error) {
+	der, err := ber.ToDER(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pfx pfxPdu
+	if err = unmarshal(der, &pfx, "PFX"); err != nil {
+		return nil, nil, err
+	}
+	if pfx.Version != 3 {
+		return nil, nil, fmt.Errorf("gogost/pkcs12: unsupported version %d", pfx.Version)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidData) {
+		return nil, nil, errors.New("gogost/pkcs12: only password integrity mode is supported")
+	}
+	authSafe, err := dataContent(pfx.AuthSafe)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pfx.MacData.Mac.Algorithm.Algorithm != nil {
+		mac, err := computeMAC(
+			pfx.MacData.Mac.Algorithm, password,
+			pfx.MacData.MacSalt, pfx.MacData.Iterations, authSafe,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !hmac.Equal(mac, pfx.MacData.Mac.Digest) {
+			return nil, nil, ErrMAC
+		}
+	}
+	var cis []contentInfo
+	if err = unmarshal(authSafe, &cis, "AuthenticatedSafe"); err != nil {
+		return nil, nil, err
+	}
+	var prv *gost3410.PrivateKey
+	var certs [][]byte
+	for _, ci := range cis {
+		var safeContents []byte
+		switch {
+		case ci.ContentType.Equal(oidData):
+			safeContents, err = dataContent(ci)
+		case ci.ContentType.Equal(oidEncryptedData):
+			safeContents, err = decryptData(ci.Content.Bytes, password)
+		default:
+			err = fmt.Errorf("gogost/pkcs12: unsupported content type %s", ci.ContentType)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		var bags []safeBag
+		if err = unmarshal(safeContents, &bags, "SafeContents"); err != nil {
+			return nil, nil, err
+		}
+		for _, bag := range bags {
+			var keyDER []byte
+			switch {
+			case bag.ID.Equal(oidKeyBag):
+				keyDER = bag.Value.Bytes
+			case bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+				if keyDER, err = pbes2.DecryptPKCS8([]byte(password), bag.Value.Bytes); err != nil {
+					return nil, nil, err
+				}
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if err = unmarshal(bag.Value.Bytes, &cb, "CertBag"); err != nil {
+					return nil, nil, err
+				}
+				if cb.ID.Equal(oidX509Certificate) {
+					certs = append(certs, cb.Data)
+				}
+				continue
+			default:
+				continue
+			}
+			if prv != nil {
+				return nil, nil, errors.New("gogost/pkcs12: more than one private key")
+			}
+			if prv, err = gost3410.ParsePKCS8PrivateKey(keyDER); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return prv, certs, nil
+}
+
+func decryptData(der []byte, password string) ([]byte, error) {
+	var ed encryptedData
+	if err := unmarshal(der, &ed, "EncryptedData"); err != nil {
+		return nil, err
+	}
+	eci := ed.EncryptedContentInfo
+	if !eci.ContentType.Equal(oidData) {
+		return nil, fmt.Errorf("gogost/pkcs12: unsupported encrypted content type %s", eci.ContentType)
+	}
+	ct, err := ber.Octets(eci.EncryptedContent)
+	if err != nil {
+		return nil, err
+	}
+	return pbes2.Decrypt([]byte(password), eci.ContentEncryptionAlgorithm, ct)
+}
+
+type Opts struct {
+	// Use legacy 28147-89 CFB and GOST R 34.11-94 based profile with
+	// 34.10-2001 key encoding. Only 256-bit keys are allowed.
+	Legacy bool
+
+	// PBES2 encryption scheme for TC26 profile,
+	// pbes2.OIDKuznechikCTRACPKMOMAC by default.
+	Cipher asn1.ObjectIdentifier
+
+	Iterations int
+}
+
+// Encode the private key and DER encoded certificates to PFX. The key
+// is placed in PKCS#8 shrouded key bag, certificates are encrypted.
+// The first certificate is linked to the key with localKeyId.
+func Encode(
+	rand io.Reader,
+	prv *gost3410.PrivateKey,
+	certs [][]byte,
+	password string,
+	opts *Opts,
+) ([]byte, error) {
+	if opts == nil {
+		opts = &Opts{}
+	}
+	iterations := opts.Iterations
+	if iterations == 0 {
+		iterations = DefaultIterations
+	}
+	pbOpts := &pbes2.Opts{Cipher: opts.Cipher, Iterations: iterations}
+	macAlgo := pkix.AlgorithmIdentifier{Algorithm: gost3410.OIDTc26Gost34112012512}
+	var keyDER []byte
+	var err error
+	if opts.Legacy {
+		pbOpts.Cipher = gost28147.OIDGost2814789
+		pbOpts.PRF = pbes2.OIDHMACGostR341194
+		macAlgo = pkix.AlgorithmIdentifier{
+			Algorithm:  gost3410.OIDGostR341194,
+			Parameters: asn1.NullRawValue,
+		}
+		keyDER, err = gost3410.MarshalPKCS8PrivateKey2001(prv)
+	} else {
+		keyDER, err = gost3410.MarshalPKCS8PrivateKey(prv)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var attrs []attribute
+	if len(certs) > 0 {
+		id := gost34112012256.New()
+		id.Write(certs[0])
+		value, err := asn1.MarshalWithParams([][]byte{id.Sum(nil)}, "set")
+		if err != nil {
+			return nil, err
+		}
+		attrs = []attribute{{
+			ID:    oidLocalKeyID,
+			Value: asn1.RawValue{FullBytes: value},
+		}}
+	}
+
+	var cis []contentInfo
+	if len(certs) > 0 {
+		bags := make([]safeBag, 0, len(certs))
+		for i, cert := range certs {
+			der, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: cert})
+			if err != nil {
+				return nil, err
+			}
+			bag := safeBag{ID: oidCertBag, Value: explicit0(der)}
+			if i == 0 {
+				bag.Attributes = attrs
+			}
+			bags = append(bags, bag)
+		}
+		safeContents, err := asn1.Marshal(bags)
+		if err != nil {
+			return nil, err
+		}
+		ai, ct, err := pbes2.Encrypt(rand, []byte(password), safeContents, pbOpts)
+		if err != nil {
+			return nil, err
+		}
+		der, err := asn1.Marshal(encryptedData{
+			EncryptedContentInfo: encryptedContentInfo{
+				ContentType:                oidData,
+				ContentEncryptionAlgorithm: ai,
+				EncryptedContent: asn1.RawValue{
+					Class: asn1.ClassContextSpecific,
+					Tag:   0,
+					Bytes: ct,
+				},
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		cis = append(cis, contentInfo{
+			ContentType: oidEncryptedData,
+			Content:     explicit0(der),
+		})
+	}
+
+	shrouded, err := pbes2.EncryptPKCS8(rand, []byte(password), keyDER, pbOpts)
+	if err != nil {
+		return nil, err
+	}
+	safeContents, err := asn1.Marshal([]safeBag{{
+		ID:         oidPKCS8ShroudedKeyBag,
+		Value:      explicit0(shrouded),
+		Attributes: attrs,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if cis, err = appendData(cis, safeContents); err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal(cis)
+	if err != nil {
+		return nil, err
+	}
+	salt := make([]byte, macSaltSize)
+	if _, err = io.ReadFull(rand, salt); err != nil {
+		return nil, err
+	}
+	mac, err := computeMAC(macAlgo, password, salt, iterations, authSafe)
+	if err != nil {
+		return nil, err
+	}
+	pfx := pfxPdu{
+		Version: 3,
+		MacData: macData{
+			Mac:        digestInfo{Algorithm: macAlgo, Digest: mac},
+			MacSalt:    salt,
+			Iterations: iterations,
+		},
+	}
+	if cis, err = appendData(nil, authSafe); err != nil {
+		return nil, err
+	}
+	pfx.AuthSafe = cis[0]
+	return asn1.Marshal(pfx)
+}
+
+// Append data ContentInfo with the OCTET STRING.
+func appendData(cis []contentInfo, data []byte) ([]contentInfo, error) {
+	der, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return append(cis, contentInfo{ContentType: oidData, Content: explicit0(der)}), nil
+}
@@ -0,0 +1,150 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/ber"
+	"github.com/pedroalbanese/gogost/pbes2"
+)
+
+// Re-encode all constructed DER elements with indefinite length.
+func der2ber(t *testing.T, der []byte) []byte {
+	var out []byte
+	for len(der) > 0 {
+		tag, compound, l, hl, err := ber.Header(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if compound {
+			out = append(out, tag...)
+			out = append(out, 0x80)
+			out = append(out, der2ber(t, der[hl:hl+l])...)
+			out = append(out, 0, 0)
+		} else {
+			out = append(out, der[:hl+l]...)
+		}
+		der = der[hl+l:]
+	}
+	return out
+}
+
+func TestPKCS12KDF(t *testing.T) {
+	// Vectors from golang.org/x/crypto/pkcs12
+	if key := pkcs12KDF(
+		sha1.New, 64, bmpString("sesame"),
+		[]byte("\xff\xff\xff\xff\xff\xff\xff\xff"), 1, 2048, 24,
+	); !bytes.Equal(key, []byte(
+		"\x7c\xd9\xfd\x3e\x2b\x3b\xe7\x69\x1a\x44\xe3\xbe\xf0\xf9\xea\x0f"+
+			"\xb9\xb8\x97\xd4\xe3\x25\xd9\xd1",
+	)) {
+		t.Fatalf("%x", key)
+	}
+	if key := pkcs12KDF(
+		sha1.New, 64, []byte("\x00\x00"),
+		[]byte("\xf3\x7e\x05\xb5\x18\x32\x4b\x4b"), 1, 2048, 24,
+	); !bytes.Equal(key, []byte(
+		"\x00\xf7\x59\xff\x47\xd1\x4d\xd0\x36\x65\xd5\x94\x3c\xb3\xc4\xa3"+
+			"\x9a\x25\x55\xc0\x2a\xed\x66\xe1",
+	)) {
+		t.Fatalf("%x", key)
+	}
+}
+
+func testRoundtrip(t *testing.T, c *gost3410.Curve, opts *Opts) {
+	prv, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs := [][]byte{
+		{0x30, 0x03, 0x02, 0x01, 0x01},
+		{0x30, 0x03, 0x02, 0x01, 0x02},
+	}
+	pfx, err := Encode(rand.Reader, prv, certs, "пароль", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{pfx, der2ber(t, pfx)} {
+		got, gotCerts, err := Decode(data, "пароль")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.C.Equal(c) || got.Key.Cmp(prv.Key) != 0 {
+			t.Fatal("private key differs")
+		}
+		if len(gotCerts) != len(certs) {
+			t.Fatal("certificates count differs")
+		}
+		for i := range certs {
+			if !bytes.Equal(gotCerts[i], certs[i]) {
+				t.Fatal("certificate differs")
+			}
+		}
+	}
+	if _, _, err = Decode(pfx, "парол"); err != ErrMAC {
+		t.Fatal("wrong password accepted")
+	}
+}
+
+func TestTC26(t *testing.T) {
+	for _, cipher := range []asn1.ObjectIdentifier{
+		nil,
+		pbes2.OIDKuznechikCTRACPKM,
+		pbes2.OIDMagmaCTRACPKMOMAC,
+	} {
+		testRoundtrip(t, gost3410.CurveIdtc26gost34102012256paramSetA(), &Opts{
+			Cipher: cipher, Iterations: 10,
+		})
+	}
+	testRoundtrip(t, gost3410.CurveIdtc26gost34102012512paramSetA(), nil)
+}
+
+func TestLegacy(t *testing.T) {
+	testRoundtrip(t, gost3410.CurveIdGostR34102001CryptoProAParamSet(), &Opts{
+		Legacy: true, Iterations: 10,
+	})
+	prv, err := gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012512paramSetA(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Encode(rand.Reader, prv, nil, "", &Opts{Legacy: true}); err == nil {
+		t.Fatal("512-bit legacy key accepted")
+	}
+}
+
+func TestNoCertificates(t *testing.T) {
+	prv, err := gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012256paramSetB(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pfx, err := Encode(rand.Reader, prv, nil, "", &Opts{Iterations: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, certs, err := Decode(pfx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Key.Cmp(prv.Key) != 0 || len(certs) != 0 {
+		t.FailNow()
+	}
+}
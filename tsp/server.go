@@ -0,0 +1,215 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tsp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/pedroalbanese/gogost/cms"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+const (
+	ContentTypeQuery = "application/timestamp-query"
+	ContentTypeReply = "application/timestamp-reply"
+
+	// Maximal accepted HTTP request body size.
+	MaxRequestSize = 1 << 16
+
+	serialSize = 16
+)
+
+// Time-stamping authority issuing tokens signed with its key.
+type Server struct {
+	// TSA certificate, with timeStamping extended key usage.
+	Certificate *x509.Certificate
+	Key         *gost3410.PrivateKey
+
+	// Additional DER encoded certificates to include, like the
+	// intermediate CA ones.
+	Certificates [][]byte
+
+	// TSA policy the tokens are issued under.
+	Policy asn1.ObjectIdentifier
+
+	// Declared time accuracy, omitted if zero.
+	Accuracy time.Duration
+
+	// Source of randomness, crypto/rand by default.
+	Rand io.Reader
+
+	// Current time source, time.Now by default.
+	Now func() time.Time
+}
+
+func failInfo(bit int) asn1.BitString {
+	bs := asn1.BitString{Bytes: make([]byte, bit/8+1), BitLength: bit + 1}
+	bs.Bytes[bit/8] = 0x80 >> (bit % 8)
+	return bs
+}
+
+func reject(bit int, text string) []byte {
+	der, err := asn1.Marshal(timeStampResp{Status: pkiStatusInfo{
+		Status:       StatusRejection,
+		StatusString: []string{text},
+		FailInfo:     failInfo(bit),
+	}})
+	if err != nil {
+		panic(err)
+	}
+	return der
+}
+
+// Issue the time-stamp token for DER encoded TimeStampReq. Either
+// granted or rejecting TimeStampResp is always returned.
+func (s *Server) Respond(reqDER []byte) []byte {
+	req, err := ParseRequest(reqDER)
+	if err != nil {
+		return reject(FailBadDataFormat, err.Error())
+	}
+	h := gost3410.NewHash(req.HashAlgorithm)
+	if h == nil {
+		return reject(FailBadAlg, "unsupported digest algorithm")
+	}
+	if len(req.HashedMessage) != h.Size() {
+		return reject(FailBadDataFormat, "invalid message imprint length")
+	}
+	if req.Policy != nil && !req.Policy.Equal(s.Policy) {
+		return reject(FailUnacceptedPolicy, "unaccepted policy")
+	}
+	token, err := s.issue(req)
+	if err != nil {
+		return reject(FailSystemFailure, "can not issue the token")
+	}
+	der, err := asn1.Marshal(timeStampResp{
+		Status:         pkiStatusInfo{Status: StatusGranted},
+		TimeStampToken: asn1.RawValue{FullBytes: token},
+	})
+	if err != nil {
+		return reject(FailSystemFailure, "can not encode the response")
+	}
+	return der
+}
+
+func (s *Server) issue(req *Request) ([]byte, error) {
+	rnd := s.Rand
+	if rnd == nil {
+		rnd = rand.Reader
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	genTime := now().UTC().Truncate(time.Second)
+	serial := make([]byte, serialSize)
+	if _, err := io.ReadFull(rnd, serial); err != nil {
+		return nil, err
+	}
+	info := tstInfo{
+		Version: 1,
+		Policy:  s.Policy,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: req.HashAlgorithm},
+			HashedMessage: req.HashedMessage,
+		},
+		SerialNumber: new(big.Int).SetBytes(serial),
+		GenTime:      genTime,
+		Accuracy: accuracy{
+			Seconds: int(s.Accuracy / time.Second),
+			Millis:  int(s.Accuracy % time.Second / time.Millisecond),
+			Micros:  int(s.Accuracy % time.Millisecond / time.Microsecond),
+		},
+		Nonce: req.Nonce,
+	}
+	content, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	return cms.Sign(rnd, content, s.Certificate, s.Key, &cms.SignOpts{
+		ContentType:    OIDCTTSTInfo,
+		SigningTime:    genTime,
+		Certificates:   s.Certificates,
+		NoCertificates: !req.CertReq,
+	})
+}
+
+// Serve RFC 3161 HTTP transport: POSTed TimeStampReq is answered
+// with TimeStampResp.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "POST expected", http.StatusMethodNotAllowed)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != ContentTypeQuery {
+		http.Error(w, "unexpected Content-Type", http.StatusUnsupportedMediaType)
+		return
+	}
+	reqDER, err := io.ReadAll(io.LimitReader(r.Body, MaxRequestSize+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(reqDER) > MaxRequestSize {
+		http.Error(w, "too big request", http.StatusRequestEntityTooLarge)
+		return
+	}
+	w.Header().Set("Content-Type", ContentTypeReply)
+	w.Write(s.Respond(reqDER))
+}
+
+// Request the time-stamp over HTTP and verify the response with
+// ParseResponse. http.DefaultClient is used if client is nil.
+func Query(
+	client *http.Client,
+	url string,
+	req *Request,
+	certs []*x509.Certificate,
+	roots *x509gost.CertPool,
+) (*Token, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	reqDER, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Post(url, ContentTypeQuery, bytes.NewReader(reqDER))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gogost/tsp: HTTP status %s", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != ContentTypeReply {
+		return nil, fmt.Errorf("gogost/tsp: unexpected Content-Type %q", ct)
+	}
+	respDER, err := io.ReadAll(io.LimitReader(resp.Body, MaxRequestSize<<4))
+	if err != nil {
+		return nil, err
+	}
+	return ParseResponse(respDER, req, certs, roots)
+}
@@ -0,0 +1,363 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Time-Stamp Protocol (RFC 3161) with GOST R 34.11-2012 message
+// imprints and GOST R 34.10 signed tokens.
+package tsp
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/pedroalbanese/gogost/cms"
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+const (
+	StatusGranted                = 0
+	StatusGrantedWithMods        = 1
+	StatusRejection              = 2
+	StatusWaiting                = 3
+	StatusRevocationWarning      = 4
+	StatusRevocationNotification = 5
+
+	FailBadAlg              = 0
+	FailBadRequest          = 2
+	FailBadDataFormat       = 5
+	FailTimeNotAvailable    = 14
+	FailUnacceptedPolicy    = 15
+	FailUnacceptedExtension = 16
+	FailAddInfoNotAvailable = 17
+	FailSystemFailure       = 25
+
+	NonceSize = 8
+)
+
+var (
+	OIDCTTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+
+	ErrMismatch = errors.New("gogost/tsp: token does not match the request")
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional,default:false"`
+	Extensions     []pkix.Extension      `asn1:"optional,tag:0"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []string       `asn1:"optional"`
+	FailInfo     asn1.BitString `asn1:"optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"optional,tag:0"`
+	Micros  int `asn1:"optional,tag:1"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	GenTime        time.Time        `asn1:"generalized"`
+	Accuracy       accuracy         `asn1:"optional"`
+	Ordering       bool             `asn1:"optional,default:false"`
+	Nonce          *big.Int         `asn1:"optional"`
+	TSA            asn1.RawValue    `asn1:"optional,tag:0"`
+	Extensions     []pkix.Extension `asn1:"optional,tag:1"`
+}
+
+func unmarshal(der []byte, v interface{}, what string) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("gogost/tsp: invalid %s: %w", what, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/tsp: trailing data after %s", what)
+	}
+	return nil
+}
+
+// Time-stamp request.
+type Request struct {
+	// Digest algorithm of the message imprint.
+	HashAlgorithm asn1.ObjectIdentifier
+	HashedMessage []byte
+
+	// Requested TSA policy, optional.
+	Policy asn1.ObjectIdentifier
+
+	// Optional nonce, that must be repeated in the token.
+	Nonce *big.Int
+
+	// Ask TSA to include its certificate in the token.
+	CertReq bool
+}
+
+// Create the request with data's message imprint made with the digest
+// algorithm (for example gost3410.OIDTc26Gost34112012256), random
+// nonce and certificate requested.
+func NewRequest(rand io.Reader, data []byte, digest asn1.ObjectIdentifier) (*Request, error) {
+	h := gost3410.NewHash(digest)
+	if h == nil {
+		return nil, fmt.Errorf("gogost/tsp: unsupported digest algorithm %s", digest)
+	}
+	h.Write(data)
+	nonce := make([]byte, NonceSize)
+	if _, err := io.ReadFull(rand, nonce); err != nil {
+		return nil, err
+	}
+	return &Request{
+		HashAlgorithm: digest,
+		HashedMessage: h.Sum(nil),
+		Nonce:         new(big.Int).SetBytes(nonce),
+		CertReq:       true,
+	}, nil
+}
+
+// DER encoded TimeStampReq.
+func (req *Request) Marshal() ([]byte, error) {
+	return asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: req.HashAlgorithm},
+			HashedMessage: req.HashedMessage,
+		},
+		ReqPolicy: req.Policy,
+		Nonce:     req.Nonce,
+		CertReq:   req.CertReq,
+	})
+}
+
+// Parse DER encoded TimeStampReq. Requests with critical extensions
+// are rejected, as none is supported.
+func ParseRequest(der []byte) (*Request, error) {
+	var req timeStampReq
+	if err := unmarshal(der, &req, "TimeStampReq"); err != nil {
+		return nil, err
+	}
+	if req.Version != 1 {
+		return nil, fmt.Errorf("gogost/tsp: unsupported version %d", req.Version)
+	}
+	for _, ext := range req.Extensions {
+		if ext.Critical {
+			return nil, fmt.Errorf("gogost/tsp: unsupported critical extension %s", ext.Id)
+		}
+	}
+	return &Request{
+		HashAlgorithm: req.MessageImprint.HashAlgorithm.Algorithm,
+		HashedMessage: req.MessageImprint.HashedMessage,
+		Policy:        req.ReqPolicy,
+		Nonce:         req.Nonce,
+		CertReq:       req.CertReq,
+	}, nil
+}
+
+// Non-granted response status.
+type StatusError struct {
+	Status int
+	Text   string
+
+	// Failure information bit, -1 if absent.
+	FailInfo int
+}
+
+func (err *StatusError) Error() string {
+	s := fmt.Sprintf("gogost/tsp: status %d", err.Status)
+	if err.Text != "" {
+		s += ": " + err.Text
+	}
+	if err.FailInfo != -1 {
+		s += fmt.Sprintf(" (failure %d)", err.FailInfo)
+	}
+	return s
+}
+
+// Verified time-stamp token.
+type Token struct {
+	// DER encoded token itself: SignedData ContentInfo.
+	Raw []byte
+
+	Policy        asn1.ObjectIdentifier
+	HashAlgorithm asn1.ObjectIdentifier
+	HashedMessage []byte
+	SerialNumber  *big.Int
+	GenTime       time.Time
+	Accuracy      time.Duration
+	Nonce         *big.Int
+
+	// TSA's certificate the token is signed with.
+	Certificate *x509.Certificate
+}
+
+func hasTimeStamping(cert *x509.Certificate) bool {
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageTimeStamping {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify the time-stamp token signature and parse its TSTInfo. TSA's
+// certificate is looked up among included and provided ones, must have
+// timeStamping extended key usage and chain to the roots at the token
+// generation time. Included and provided certificates are used as
+// intermediates.
+func VerifyToken(token []byte, certs []*x509.Certificate, roots *x509gost.CertPool) (*Token, error) {
+	signed, err := cms.Verify(token, nil, certs)
+	if err != nil {
+		return nil, err
+	}
+	if !signed.ContentType.Equal(OIDCTTSTInfo) {
+		return nil, fmt.Errorf("gogost/tsp: unexpected content type %s", signed.ContentType)
+	}
+	if len(signed.Signers) != 1 {
+		return nil, errors.New("gogost/tsp: token must have single signer")
+	}
+	cert := signed.Signers[0]
+	if !hasTimeStamping(cert) {
+		return nil, errors.New("gogost/tsp: certificate is not for time-stamping")
+	}
+	var info tstInfo
+	if err = unmarshal(signed.Content, &info, "TSTInfo"); err != nil {
+		return nil, err
+	}
+	if info.Version != 1 {
+		return nil, fmt.Errorf("gogost/tsp: unsupported TSTInfo version %d", info.Version)
+	}
+	intermediates := x509gost.NewCertPool()
+	for _, c := range append(signed.Certificates, certs...) {
+		intermediates.AddCert(c)
+	}
+	if _, err = x509gost.Verify(cert, x509gost.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return nil, fmt.Errorf("gogost/tsp: TSA certificate: %w", err)
+	}
+	return &Token{
+		Raw:           token,
+		Policy:        info.Policy,
+		HashAlgorithm: info.MessageImprint.HashAlgorithm.Algorithm,
+		HashedMessage: info.MessageImprint.HashedMessage,
+		SerialNumber:  info.SerialNumber,
+		GenTime:       info.GenTime,
+		Accuracy: time.Duration(info.Accuracy.Seconds)*time.Second +
+			time.Duration(info.Accuracy.Millis)*time.Millisecond +
+			time.Duration(info.Accuracy.Micros)*time.Microsecond,
+		Nonce:       info.Nonce,
+		Certificate: cert,
+	}, nil
+}
+
+// Check that the token corresponds to the request: the same message
+// imprint, nonce and policy (if it was requested).
+func (t *Token) Match(req *Request) error {
+	if !t.HashAlgorithm.Equal(req.HashAlgorithm) ||
+		!bytes.Equal(t.HashedMessage, req.HashedMessage) {
+		return ErrMismatch
+	}
+	if (req.Nonce == nil) != (t.Nonce == nil) ||
+		(req.Nonce != nil && req.Nonce.Cmp(t.Nonce) != 0) {
+		return ErrMismatch
+	}
+	if req.Policy != nil && !req.Policy.Equal(t.Policy) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// Check that the token is made over the data.
+func (t *Token) Verify(data []byte) error {
+	h := gost3410.NewHash(t.HashAlgorithm)
+	if h == nil {
+		return fmt.Errorf("gogost/tsp: unsupported digest algorithm %s", t.HashAlgorithm)
+	}
+	h.Write(data)
+	if !bytes.Equal(h.Sum(nil), t.HashedMessage) {
+		return ErrMismatch
+	}
+	return nil
+}
+
+// Parse DER encoded TimeStampResp, verify its token with VerifyToken
+// and check it against the request. *StatusError is returned if
+// time-stamp is not granted.
+func ParseResponse(
+	der []byte,
+	req *Request,
+	certs []*x509.Certificate,
+	roots *x509gost.CertPool,
+) (*Token, error) {
+	var resp timeStampResp
+	if err := unmarshal(der, &resp, "TimeStampResp"); err != nil {
+		return nil, err
+	}
+	status := resp.Status
+	if status.Status != StatusGranted && status.Status != StatusGrantedWithMods {
+		serr := StatusError{Status: status.Status, FailInfo: -1}
+		for i, s := range status.StatusString {
+			if i > 0 {
+				serr.Text += "; "
+			}
+			serr.Text += s
+		}
+		for i := 0; i < status.FailInfo.BitLength; i++ {
+			if status.FailInfo.At(i) == 1 {
+				serr.FailInfo = i
+				break
+			}
+		}
+		return nil, &serr
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("gogost/tsp: no token in granted response")
+	}
+	token, err := VerifyToken(resp.TimeStampToken.FullBytes, certs, roots)
+	if err != nil {
+		return nil, err
+	}
+	if err = token.Match(req); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
@@ -0,0 +1,207 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package tsp
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/testpki"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+// Create TSA certificate with the new key on the curve, issued by the
+// new CA, returned in the roots pool.
+func newTestCert(
+	t *testing.T,
+	c *gost3410.Curve,
+	tsa bool,
+) (*x509.Certificate, *gost3410.PrivateKey, *x509gost.CertPool) {
+	ca, caPrv := testpki.New(t, c, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "CA"},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, nil)
+	tmpl := x509.Certificate{Subject: pkix.Name{CommonName: "TSA"}}
+	if tsa {
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping}
+	}
+	cert, prv := testpki.New(t, c, &tmpl, ca, caPrv)
+	roots := x509gost.NewCertPool()
+	roots.AddCert(ca)
+	return cert, prv, roots
+}
+
+var testPolicy = asn1.ObjectIdentifier{1, 2, 3, 4}
+
+func TestQuery(t *testing.T) {
+	data := []byte("data to be time-stamped")
+	for _, c := range []*gost3410.Curve{
+		gost3410.CurveIdtc26gost34102012256paramSetA(),
+		gost3410.CurveIdtc26gost34102012512paramSetA(),
+	} {
+		cert, prv, roots := newTestCert(t, c, true)
+		srv := httptest.NewServer(&Server{
+			Certificate: cert,
+			Key:         prv,
+			Policy:      testPolicy,
+			Accuracy:    1500 * time.Millisecond,
+		})
+		for _, digest := range []asn1.ObjectIdentifier{
+			gost3410.OIDTc26Gost34112012256,
+			gost3410.OIDTc26Gost34112012512,
+		} {
+			req, err := NewRequest(rand.Reader, data, digest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := time.Now().Add(-time.Second)
+			token, err := Query(srv.Client(), srv.URL, req, nil, roots)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = token.Verify(data); err != nil {
+				t.Fatal(err)
+			}
+			if err = token.Verify([]byte("another data")); err != ErrMismatch {
+				t.Fatal("another data accepted")
+			}
+			if !token.Policy.Equal(testPolicy) ||
+				token.Accuracy != 1500*time.Millisecond ||
+				!token.Certificate.Equal(cert) ||
+				token.GenTime.Before(before) ||
+				token.GenTime.After(time.Now()) {
+				t.Fatal("token differs")
+			}
+			again, err := VerifyToken(token.Raw, nil, roots)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = VerifyToken(token.Raw, nil, nil); err == nil {
+				t.Fatal("token verified without trust anchors")
+			}
+			if again.SerialNumber.Cmp(token.SerialNumber) != 0 {
+				t.Fatal("serial differs")
+			}
+		}
+		srv.Close()
+	}
+}
+
+func TestNoCertReq(t *testing.T) {
+	cert, prv, roots := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetB(), true)
+	s := Server{Certificate: cert, Key: prv, Policy: testPolicy}
+	req, err := NewRequest(rand.Reader, []byte("data"), gost3410.OIDTc26Gost34112012256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.CertReq = false
+	reqDER, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := s.Respond(reqDER)
+	if _, err = ParseResponse(resp, req, nil, roots); err == nil {
+		t.Fatal("verified without certificate")
+	}
+	if _, err = ParseResponse(resp, req, []*x509.Certificate{cert}, roots); err != nil {
+		t.Fatal(err)
+	}
+	other := *req
+	other.Nonce = big.NewInt(123)
+	if _, err = ParseResponse(resp, &other, []*x509.Certificate{cert}, roots); err != ErrMismatch {
+		t.Fatal("nonce mismatch accepted")
+	}
+}
+
+func TestRejection(t *testing.T) {
+	cert, prv, roots := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), true)
+	s := Server{Certificate: cert, Key: prv, Policy: testPolicy}
+	req, err := NewRequest(rand.Reader, []byte("data"), gost3410.OIDTc26Gost34112012256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		modify func(*Request)
+		fail   int
+	}{
+		{func(r *Request) { r.Policy = asn1.ObjectIdentifier{1, 2, 3, 5} }, FailUnacceptedPolicy},
+		{func(r *Request) { r.HashAlgorithm = asn1.ObjectIdentifier{1, 2, 3} }, FailBadAlg},
+		{func(r *Request) { r.HashedMessage = r.HashedMessage[1:] }, FailBadDataFormat},
+	} {
+		bad := *req
+		tc.modify(&bad)
+		reqDER, err := bad.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ParseResponse(s.Respond(reqDER), &bad, nil, roots)
+		var serr *StatusError
+		if !errors.As(err, &serr) || serr.Status != StatusRejection || serr.FailInfo != tc.fail {
+			t.Fatal("unexpected rejection", err)
+		}
+	}
+}
+
+func TestNonTSACertificate(t *testing.T) {
+	cert, prv, roots := newTestCert(t, gost3410.CurveIdtc26gost34102012256paramSetA(), false)
+	s := Server{Certificate: cert, Key: prv, Policy: testPolicy}
+	req, err := NewRequest(rand.Reader, []byte("data"), gost3410.OIDTc26Gost34112012256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqDER, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ParseResponse(s.Respond(reqDER), req, nil, roots); err == nil {
+		t.Fatal("non time-stamping certificate accepted")
+	}
+}
+
+func TestUntrustedTSA(t *testing.T) {
+	c := gost3410.CurveIdtc26gost34102012256paramSetA()
+	_, _, roots := newTestCert(t, c, true)
+	cert, prv, _ := newTestCert(t, c, true)
+	selfSigned, selfPrv := testpki.New(t, c, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "TSA"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}, nil, nil)
+	req, err := NewRequest(rand.Reader, []byte("data"), gost3410.OIDTc26Gost34112012256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reqDER, err := req.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []Server{
+		{Certificate: cert, Key: prv, Policy: testPolicy},
+		{Certificate: selfSigned, Key: selfPrv, Policy: testPolicy},
+	} {
+		if _, err = ParseResponse(s.Respond(reqDER), req, nil, roots); err == nil {
+			t.Fatal("untrusted TSA accepted")
+		}
+	}
+}
@@ -0,0 +1,543 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package x509gost
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+)
+
+var (
+	oidExtSubjectKeyID          = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidExtKeyUsage              = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtSubjectAltName        = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtBasicConstraints      = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidExtCRLDistributionPoints = asn1.ObjectIdentifier{2, 5, 29, 31}
+	oidExtCertificatePolicies   = asn1.ObjectIdentifier{2, 5, 29, 32}
+	oidExtAuthorityKeyID        = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtExtendedKeyUsage      = asn1.ObjectIdentifier{2, 5, 29, 37}
+	oidExtAuthorityInfoAccess   = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}
+
+	oidAccessOCSP      = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1}
+	oidAccessCAIssuers = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 2}
+
+	oidExtensionRequest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+)
+
+var extKeyUsageOIDs = []struct {
+	usage x509.ExtKeyUsage
+	oid   asn1.ObjectIdentifier
+}{
+	{x509.ExtKeyUsageAny, asn1.ObjectIdentifier{2, 5, 29, 37, 0}},
+	{x509.ExtKeyUsageServerAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}},
+	{x509.ExtKeyUsageClientAuth, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}},
+	{x509.ExtKeyUsageCodeSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}},
+	{x509.ExtKeyUsageEmailProtection, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}},
+	{x509.ExtKeyUsageIPSECEndSystem, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 5}},
+	{x509.ExtKeyUsageIPSECTunnel, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 6}},
+	{x509.ExtKeyUsageIPSECUser, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 7}},
+	{x509.ExtKeyUsageTimeStamping, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}},
+	{x509.ExtKeyUsageOCSPSigning, asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}},
+}
+
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validity
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	Extensions         []pkix.Extension `asn1:"optional,explicit,tag:3"`
+}
+
+type validity struct {
+	NotBefore, NotAfter asn1.RawValue
+}
+
+type certificationRequestInfo struct {
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  asn1.RawValue
+	Attributes []attribute `asn1:"tag:0"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+type accessDescription struct {
+	Method   asn1.ObjectIdentifier
+	Location asn1.RawValue
+}
+
+type distributionPoint struct {
+	Name asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+type policyInformation struct {
+	Policy asn1.ObjectIdentifier
+}
+
+// UTCTime till 2049 inclusive, GeneralizedTime after, as RFC 5280
+// requires.
+func marshalTime(t time.Time) (asn1.RawValue, error) {
+	t = t.UTC()
+	params := "utc"
+	if t.Year() >= 2050 {
+		params = "generalized"
+	}
+	der, err := asn1.MarshalWithParams(t, params)
+	return asn1.RawValue{FullBytes: der}, err
+}
+
+// Key identifier: 160 leftmost bits of Streebog-256 hash of the
+// subjectPublicKey value.
+func keyID(spki []byte) ([]byte, error) {
+	var info subjectPublicKeyInfo
+	if err := unmarshal(spki, &info, "SubjectPublicKeyInfo"); err != nil {
+		return nil, err
+	}
+	h := gost34112012256.New()
+	h.Write(info.PublicKey.RightAlign())
+	return h.Sum(nil)[:20], nil
+}
+
+// DER encoded SubjectPublicKeyInfo of *gost3410.PublicKey (34.10-2012
+// algorithm) or already encoded one as []byte, that is checked.
+func marshalSPKI(pub any) ([]byte, *gost3410.PublicKey, error) {
+	switch pub := pub.(type) {
+	case *gost3410.PublicKey:
+		spki, err := gost3410.MarshalPKIXPublicKey(pub)
+		return spki, pub, err
+	case []byte:
+		parsed, _, err := ParseSubjectPublicKeyInfo(pub)
+		return pub, parsed, err
+	}
+	return nil, nil, fmt.Errorf("gogost/x509gost: unsupported public key type %T", pub)
+}
+
+// Sign the data with the signer having the key of keyAlgo algorithm,
+// returning signature algorithm identifier and the signature.
+// *gost3410.PrivateKeyReverseDigest is used as is, other signers are
+// given already reversed digest, as *gost3410.PrivateKey expects.
+func SignData(
+	rand io.Reader,
+	signer crypto.Signer,
+	keyAlgo asn1.ObjectIdentifier,
+	data []byte,
+) (pkix.AlgorithmIdentifier, []byte, error) {
+	digestOID, sigOID := gost3410.SignatureAlgorithms(keyAlgo)
+	if sigOID == nil {
+		return pkix.AlgorithmIdentifier{}, nil, fmt.Errorf("gogost/x509gost: unsupported key algorithm %s", keyAlgo)
+	}
+	h := gost3410.NewHash(digestOID)
+	h.Write(data)
+	digest := h.Sum(nil)
+	if _, ok := signer.(*gost3410.PrivateKeyReverseDigest); !ok {
+		for i, j := 0, len(digest)-1; i < j; i, j = i+1, j-1 {
+			digest[i], digest[j] = digest[j], digest[i]
+		}
+	}
+	sig, err := signer.Sign(rand, digest, nil)
+	return pkix.AlgorithmIdentifier{Algorithm: sigOID}, sig, err
+}
+
+func marshalSigned(data []byte, algo pkix.AlgorithmIdentifier, sig []byte) ([]byte, error) {
+	return asn1.Marshal(signed{
+		Data:      asn1.RawValue{FullBytes: data},
+		Algo:      algo,
+		Signature: asn1.BitString{Bytes: sig, BitLength: 8 * len(sig)},
+	})
+}
+
+func marshalName(raw []byte, name pkix.Name) ([]byte, error) {
+	if len(raw) > 0 {
+		return raw, nil
+	}
+	return asn1.Marshal(name.ToRDNSequence())
+}
+
+func marshalKeyUsage(ku x509.KeyUsage) (pkix.Extension, error) {
+	var bs asn1.BitString
+	for i := 0; i < 9; i++ {
+		if ku&(1<<i) == 0 {
+			continue
+		}
+		for len(bs.Bytes) <= i/8 {
+			bs.Bytes = append(bs.Bytes, 0)
+		}
+		bs.Bytes[i/8] |= 0x80 >> (i % 8)
+		bs.BitLength = i + 1
+	}
+	der, err := asn1.Marshal(bs)
+	return pkix.Extension{Id: oidExtKeyUsage, Critical: true, Value: der}, err
+}
+
+func marshalExtKeyUsage(usages []x509.ExtKeyUsage, unknown []asn1.ObjectIdentifier) (pkix.Extension, error) {
+	oids := make([]asn1.ObjectIdentifier, 0, len(usages)+len(unknown))
+	critical := false
+Usages:
+	for _, usage := range usages {
+		for _, u := range extKeyUsageOIDs {
+			if u.usage == usage {
+				oids = append(oids, u.oid)
+				// RFC 3161 requires critical extension for TSA
+				critical = critical || usage == x509.ExtKeyUsageTimeStamping
+				continue Usages
+			}
+		}
+		return pkix.Extension{}, fmt.Errorf("gogost/x509gost: unsupported extended key usage %d", usage)
+	}
+	der, err := asn1.Marshal(append(oids, unknown...))
+	return pkix.Extension{Id: oidExtExtendedKeyUsage, Critical: critical, Value: der}, err
+}
+
+func generalName(tag int, value []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: tag, Bytes: value}
+}
+
+func marshalSAN(
+	dnsNames, emails []string,
+	ips []net.IP,
+	uris []*url.URL,
+) ([]byte, error) {
+	var names []asn1.RawValue
+	for _, name := range dnsNames {
+		names = append(names, generalName(2, []byte(name)))
+	}
+	for _, email := range emails {
+		names = append(names, generalName(1, []byte(email)))
+	}
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		names = append(names, generalName(7, ip))
+	}
+	for _, uri := range uris {
+		names = append(names, generalName(6, []byte(uri.String())))
+	}
+	return asn1.Marshal(names)
+}
+
+func hasSAN(dnsNames, emails []string, ips []net.IP, uris []*url.URL) bool {
+	return len(dnsNames)+len(emails)+len(ips)+len(uris) > 0
+}
+
+// Append extra extensions, replacing already present ones.
+func appendExtra(exts, extra []pkix.Extension) []pkix.Extension {
+	for _, e := range extra {
+		replaced := false
+		for i := range exts {
+			if exts[i].Id.Equal(e.Id) {
+				exts[i], replaced = e, true
+			}
+		}
+		if !replaced {
+			exts = append(exts, e)
+		}
+	}
+	return exts
+}
+
+func certExtensions(tmpl *x509.Certificate, subject, ski, aki []byte) (exts []pkix.Extension, err error) {
+	var der []byte
+	if len(ski) > 0 {
+		if der, err = asn1.Marshal(ski); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtSubjectKeyID, Value: der})
+	}
+	if len(aki) > 0 {
+		if der, err = asn1.Marshal(authorityKeyID{aki}); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtAuthorityKeyID, Value: der})
+	}
+	if tmpl.KeyUsage != 0 {
+		var ext pkix.Extension
+		if ext, err = marshalKeyUsage(tmpl.KeyUsage); err != nil {
+			return
+		}
+		exts = append(exts, ext)
+	}
+	if len(tmpl.ExtKeyUsage)+len(tmpl.UnknownExtKeyUsage) > 0 {
+		var ext pkix.Extension
+		if ext, err = marshalExtKeyUsage(tmpl.ExtKeyUsage, tmpl.UnknownExtKeyUsage); err != nil {
+			return
+		}
+		exts = append(exts, ext)
+	}
+	if tmpl.BasicConstraintsValid {
+		bc := basicConstraints{IsCA: tmpl.IsCA, MaxPathLen: tmpl.MaxPathLen}
+		if !tmpl.IsCA || (bc.MaxPathLen == 0 && !tmpl.MaxPathLenZero) {
+			bc.MaxPathLen = -1
+		}
+		if der, err = asn1.Marshal(bc); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtBasicConstraints, Critical: true, Value: der})
+	}
+	if hasSAN(tmpl.DNSNames, tmpl.EmailAddresses, tmpl.IPAddresses, tmpl.URIs) {
+		if der, err = marshalSAN(tmpl.DNSNames, tmpl.EmailAddresses, tmpl.IPAddresses, tmpl.URIs); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{
+			Id:       oidExtSubjectAltName,
+			Critical: len(subject) == 2, // empty SEQUENCE
+			Value:    der,
+		})
+	}
+	if len(tmpl.OCSPServer)+len(tmpl.IssuingCertificateURL) > 0 {
+		var ads []accessDescription
+		for _, uri := range tmpl.OCSPServer {
+			ads = append(ads, accessDescription{oidAccessOCSP, generalName(6, []byte(uri))})
+		}
+		for _, uri := range tmpl.IssuingCertificateURL {
+			ads = append(ads, accessDescription{oidAccessCAIssuers, generalName(6, []byte(uri))})
+		}
+		if der, err = asn1.Marshal(ads); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtAuthorityInfoAccess, Value: der})
+	}
+	if len(tmpl.CRLDistributionPoints) > 0 {
+		var dps []distributionPoint
+		for _, uri := range tmpl.CRLDistributionPoints {
+			uriDER, err := asn1.Marshal(generalName(6, []byte(uri)))
+			if err != nil {
+				return nil, err
+			}
+			// fullName [0] GeneralNames inside explicitly tagged CHOICE
+			fullName, err := asn1.Marshal(asn1.RawValue{
+				Class:      asn1.ClassContextSpecific,
+				Tag:        0,
+				IsCompound: true,
+				Bytes:      uriDER,
+			})
+			if err != nil {
+				return nil, err
+			}
+			dps = append(dps, distributionPoint{asn1.RawValue{
+				Class:      asn1.ClassContextSpecific,
+				Tag:        0,
+				IsCompound: true,
+				Bytes:      fullName,
+			}})
+		}
+		if der, err = asn1.Marshal(dps); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtCRLDistributionPoints, Value: der})
+	}
+	if len(tmpl.PolicyIdentifiers) > 0 {
+		policies := make([]policyInformation, 0, len(tmpl.PolicyIdentifiers))
+		for _, oid := range tmpl.PolicyIdentifiers {
+			policies = append(policies, policyInformation{oid})
+		}
+		if der, err = asn1.Marshal(policies); err != nil {
+			return
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtCertificatePolicies, Value: der})
+	}
+	return appendExtra(exts, tmpl.ExtraExtensions), nil
+}
+
+// Create DER encoded certificate from the template, like
+// x509.CreateCertificate does. Subject, SerialNumber, NotBefore,
+// NotAfter, KeyUsage, ExtKeyUsage, UnknownExtKeyUsage, basic
+// constraints, SubjectKeyId, subject alternative names, OCSPServer,
+// IssuingCertificateURL, CRLDistributionPoints, PolicyIdentifiers and
+// ExtraExtensions are used. pub is *gost3410.PublicKey or DER encoded
+// SubjectPublicKeyInfo (for example CSR's one, or 34.10-2001 key). The
+// parent is the template itself for self-signed certificates. signer
+// must hold the parent's GOST private key: *gost3410.PrivateKey,
+// *gost3410.PrivateKeyReverseDigest or any crypto.Signer following
+// gost3410.PrivateKey.Sign convention.
+func CreateCertificate(
+	rand io.Reader,
+	template, parent *x509.Certificate,
+	pub any,
+	signer crypto.Signer,
+) ([]byte, error) {
+	if template.SerialNumber == nil || template.SerialNumber.Sign() <= 0 {
+		return nil, errors.New("gogost/x509gost: serial number must be positive")
+	}
+	spki, subjectPub, err := marshalSPKI(pub)
+	if err != nil {
+		return nil, err
+	}
+	signerSPKI := parent.RawSubjectPublicKeyInfo
+	if len(signerSPKI) == 0 {
+		signerSPKI = spki
+	}
+	signerPub, keyAlgo, err := ParseSubjectPublicKeyInfo(signerSPKI)
+	if err != nil {
+		return nil, err
+	}
+	if !signerPub.Equal(signer.Public()) {
+		return nil, errors.New("gogost/x509gost: signer's key does not match the parent")
+	}
+	subject, err := marshalName(template.RawSubject, template.Subject)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := marshalName(parent.RawSubject, parent.Subject)
+	if err != nil {
+		return nil, err
+	}
+	ski := template.SubjectKeyId
+	if len(ski) == 0 {
+		if ski, err = keyID(spki); err != nil {
+			return nil, err
+		}
+	}
+	aki := parent.SubjectKeyId
+	if len(parent.Raw) == 0 && subjectPub.Equal(signerPub) {
+		aki = ski // self-signed
+	}
+	exts, err := certExtensions(template, subject, ski, aki)
+	if err != nil {
+		return nil, err
+	}
+	tbs := tbsCertificate{
+		Version:      2,
+		SerialNumber: template.SerialNumber,
+		Issuer:       asn1.RawValue{FullBytes: issuer},
+		Subject:      asn1.RawValue{FullBytes: subject},
+		PublicKey:    asn1.RawValue{FullBytes: spki},
+		Extensions:   exts,
+	}
+	if tbs.Validity.NotBefore, err = marshalTime(template.NotBefore); err != nil {
+		return nil, err
+	}
+	if tbs.Validity.NotAfter, err = marshalTime(template.NotAfter); err != nil {
+		return nil, err
+	}
+	_, tbs.SignatureAlgorithm.Algorithm = gost3410.SignatureAlgorithms(keyAlgo)
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	algo, sig, err := SignData(rand, signer, keyAlgo, tbsDER)
+	if err != nil {
+		return nil, err
+	}
+	der, err := marshalSigned(tbsDER, algo, sig)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = CheckSignedSignature(der, signerSPKI); err != nil {
+		return nil, fmt.Errorf("gogost/x509gost: signer produced invalid signature: %w", err)
+	}
+	return der, nil
+}
+
+// Create DER encoded certification request from the template, like
+// x509.CreateCertificateRequest does. Subject, subject alternative
+// names and ExtraExtensions are used. SubjectPublicKeyInfo is taken
+// from template's RawSubjectPublicKeyInfo if set (for 34.10-2001
+// keys), or made of signer's 34.10-2012 public key.
+func CreateCertificateRequest(
+	rand io.Reader,
+	template *x509.CertificateRequest,
+	signer crypto.Signer,
+) ([]byte, error) {
+	signerPub, ok := signer.Public().(*gost3410.PublicKey)
+	if !ok {
+		return nil, errors.New("gogost/x509gost: signer has no GOST public key")
+	}
+	spki := template.RawSubjectPublicKeyInfo
+	if len(spki) == 0 {
+		var err error
+		if spki, err = gost3410.MarshalPKIXPublicKey(signerPub); err != nil {
+			return nil, err
+		}
+	}
+	pub, keyAlgo, err := ParseSubjectPublicKeyInfo(spki)
+	if err != nil {
+		return nil, err
+	}
+	if !pub.Equal(signerPub) {
+		return nil, errors.New("gogost/x509gost: signer's key does not match SubjectPublicKeyInfo")
+	}
+	subject, err := marshalName(template.RawSubject, template.Subject)
+	if err != nil {
+		return nil, err
+	}
+	var exts []pkix.Extension
+	if hasSAN(template.DNSNames, template.EmailAddresses, template.IPAddresses, template.URIs) {
+		der, err := marshalSAN(template.DNSNames, template.EmailAddresses, template.IPAddresses, template.URIs)
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtSubjectAltName, Value: der})
+	}
+	exts = appendExtra(exts, template.ExtraExtensions)
+	cri := certificationRequestInfo{
+		Subject:    asn1.RawValue{FullBytes: subject},
+		PublicKey:  asn1.RawValue{FullBytes: spki},
+		Attributes: []attribute{},
+	}
+	if len(exts) > 0 {
+		der, err := asn1.Marshal(exts)
+		if err != nil {
+			return nil, err
+		}
+		cri.Attributes = append(cri.Attributes, attribute{
+			Type:   oidExtensionRequest,
+			Values: []asn1.RawValue{{FullBytes: der}},
+		})
+	}
+	criDER, err := asn1.Marshal(cri)
+	if err != nil {
+		return nil, err
+	}
+	algo, sig, err := SignData(rand, signer, keyAlgo, criDER)
+	if err != nil {
+		return nil, err
+	}
+	return marshalSigned(criDER, algo, sig)
+}
+
+// Check GOST self-signature of the certification request.
+func CheckCertificateRequestSignature(csr *x509.CertificateRequest) error {
+	_, err := CheckSignedSignature(csr.Raw, csr.RawSubjectPublicKeyInfo)
+	return err
+}
@@ -0,0 +1,167 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package x509gost
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func TestCreateFromCSR(t *testing.T) {
+	caPrv, err := gost3410.GenPrivateKey(gost3410.CurveIdtc26gost34102012512paramSetA(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA", Country: []string{"RU"}},
+		NotBefore:             testNow.Add(-time.Hour),
+		NotAfter:              time.Date(2060, 1, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            0,
+		MaxPathLenZero:        true,
+	}
+	caDER, err := CreateCertificate(rand.Reader, &caTmpl, &caTmpl, caPrv.Public(), caPrv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ca.IsCA || ca.MaxPathLen != 0 || !ca.MaxPathLenZero ||
+		ca.KeyUsage != caTmpl.KeyUsage || !ca.NotAfter.Equal(caTmpl.NotAfter) ||
+		!bytes.Equal(ca.SubjectKeyId, ca.AuthorityKeyId) || len(ca.SubjectKeyId) != 20 {
+		t.Fatal("CA certificate differs")
+	}
+
+	for _, legacy := range []bool{false, true} {
+		eePrv, err := gost3410.GenPrivateKey(gost3410.CurveIdGostR34102001CryptoProXchAParamSet(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csrTmpl := x509.CertificateRequest{
+			Subject:     pkix.Name{CommonName: "example.com"},
+			DNSNames:    []string{"example.com", "www.example.com"},
+			IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		}
+		if legacy {
+			csrTmpl.RawSubjectPublicKeyInfo, err = gost3410.MarshalPKIXPublicKey2001(eePrv.Public().(*gost3410.PublicKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		csrDER, err := CreateCertificateRequest(
+			rand.Reader, &csrTmpl, &gost3410.PrivateKeyReverseDigest{Prv: eePrv},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		csr, err := x509.ParseCertificateRequest(csrDER)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = CheckCertificateRequestSignature(csr); err != nil {
+			t.Fatal(err)
+		}
+		if csr.Subject.CommonName != "example.com" ||
+			!reflect.DeepEqual(csr.DNSNames, csrTmpl.DNSNames) ||
+			len(csr.IPAddresses) != 2 {
+			t.Fatal("CSR differs")
+		}
+		if _, keyAlgo, _ := ParseSubjectPublicKeyInfo(csr.RawSubjectPublicKeyInfo); keyAlgo.Equal(gost3410.OIDGostR34102001) != legacy {
+			t.Fatal("CSR key algorithm differs")
+		}
+
+		ocsp, _ := url.Parse("spiffe://example.com/ee")
+		tmpl := x509.Certificate{
+			SerialNumber:          big.NewInt(12345),
+			Subject:               csr.Subject,
+			NotBefore:             testNow.Add(-time.Hour),
+			NotAfter:              testNow.Add(time.Hour),
+			KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+			ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+			BasicConstraintsValid: true,
+			DNSNames:              csr.DNSNames,
+			IPAddresses:           csr.IPAddresses,
+			URIs:                  []*url.URL{ocsp},
+			OCSPServer:            []string{"http://ocsp.example.com"},
+			IssuingCertificateURL: []string{"http://ca.example.com/ca.cer"},
+			CRLDistributionPoints: []string{"http://ca.example.com/ca.crl"},
+			PolicyIdentifiers:     []asn1.ObjectIdentifier{{1, 2, 643, 100, 113, 1}},
+		}
+		der, err := CreateCertificate(rand.Reader, &tmpl, ca, csr.RawSubjectPublicKeyInfo, caPrv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cert.IsCA || !cert.BasicConstraintsValid ||
+			cert.KeyUsage != tmpl.KeyUsage ||
+			!reflect.DeepEqual(cert.ExtKeyUsage, tmpl.ExtKeyUsage) ||
+			!reflect.DeepEqual(cert.DNSNames, tmpl.DNSNames) ||
+			len(cert.IPAddresses) != 2 || len(cert.URIs) != 1 ||
+			!reflect.DeepEqual(cert.OCSPServer, tmpl.OCSPServer) ||
+			!reflect.DeepEqual(cert.IssuingCertificateURL, tmpl.IssuingCertificateURL) ||
+			!reflect.DeepEqual(cert.CRLDistributionPoints, tmpl.CRLDistributionPoints) ||
+			len(cert.PolicyIdentifiers) != 1 ||
+			!bytes.Equal(cert.AuthorityKeyId, ca.SubjectKeyId) ||
+			!bytes.Equal(cert.RawSubjectPublicKeyInfo, csr.RawSubjectPublicKeyInfo) {
+			t.Fatal("certificate differs")
+		}
+		roots := NewCertPool()
+		roots.AddCert(ca)
+		if _, err = Verify(cert, VerifyOptions{Roots: roots, DNSName: "www.example.com"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCreateWrongSigner(t *testing.T) {
+	c := gost3410.CurveIdtc26gost34102012256paramSetA()
+	prv, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := gost3410.GenPrivateKey(c, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "self"},
+		NotBefore:    testNow,
+		NotAfter:     testNow.Add(time.Hour),
+	}
+	if _, err = CreateCertificate(rand.Reader, &tmpl, &tmpl, prv.Public(), other); err == nil {
+		t.Fatal("foreign signer accepted")
+	}
+}
@@ -0,0 +1,151 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package x509gost
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+var (
+	oidExtCRLNumber  = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+)
+
+type tbsCertList struct {
+	Version             int `asn1:"optional,default:0"`
+	Signature           pkix.AlgorithmIdentifier
+	Issuer              asn1.RawValue
+	ThisUpdate          asn1.RawValue
+	NextUpdate          asn1.RawValue        `asn1:"optional"`
+	RevokedCertificates []revokedCertificate `asn1:"optional"`
+	Extensions          []pkix.Extension     `asn1:"optional,explicit,tag:0"`
+}
+
+type revokedCertificate struct {
+	SerialNumber   *big.Int
+	RevocationTime asn1.RawValue
+	Extensions     []pkix.Extension `asn1:"optional"`
+}
+
+// CRL entry extension with the revocation reason code (RFC 5280
+// 5.3.1).
+func ReasonCodeExtension(reason int) (pkix.Extension, error) {
+	der, err := asn1.Marshal(asn1.Enumerated(reason))
+	return pkix.Extension{Id: oidExtReasonCode, Value: der}, err
+}
+
+// Create DER encoded CRL v2 from the template, like
+// x509.CreateRevocationList does. Number, ThisUpdate, NextUpdate,
+// RevokedCertificates and ExtraExtensions are used. The issuer must
+// have cRLSign key usage, if its key usage is set. signer must hold
+// the issuer's GOST private key, as in CreateCertificate.
+func CreateRevocationList(
+	rand io.Reader,
+	template *x509.RevocationList,
+	issuer *x509.Certificate,
+	signer crypto.Signer,
+) ([]byte, error) {
+	if template.Number == nil {
+		return nil, errors.New("gogost/x509gost: template has no CRL number")
+	}
+	if template.Number.Sign() < 0 || template.Number.BitLen() > 20*8 {
+		return nil, fmt.Errorf("gogost/x509gost: invalid CRL number %s", template.Number)
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&x509.KeyUsageCRLSign == 0 {
+		return nil, errors.New("gogost/x509gost: issuer can not sign CRLs")
+	}
+	if !template.NextUpdate.IsZero() && template.NextUpdate.Before(template.ThisUpdate) {
+		return nil, errors.New("gogost/x509gost: NextUpdate is before ThisUpdate")
+	}
+	signerPub, keyAlgo, err := PublicKey(issuer)
+	if err != nil {
+		return nil, err
+	}
+	if !signerPub.Equal(signer.Public()) {
+		return nil, errors.New("gogost/x509gost: signer's key does not match the issuer")
+	}
+	tbs := tbsCertList{
+		Version: 1,
+		Issuer:  asn1.RawValue{FullBytes: issuer.RawSubject},
+	}
+	_, tbs.Signature.Algorithm = gost3410.SignatureAlgorithms(keyAlgo)
+	if tbs.ThisUpdate, err = marshalTime(template.ThisUpdate); err != nil {
+		return nil, err
+	}
+	if !template.NextUpdate.IsZero() {
+		if tbs.NextUpdate, err = marshalTime(template.NextUpdate); err != nil {
+			return nil, err
+		}
+	}
+	for _, rc := range template.RevokedCertificates {
+		entry := revokedCertificate{SerialNumber: rc.SerialNumber, Extensions: rc.Extensions}
+		if entry.RevocationTime, err = marshalTime(rc.RevocationTime); err != nil {
+			return nil, err
+		}
+		tbs.RevokedCertificates = append(tbs.RevokedCertificates, entry)
+	}
+	var exts []pkix.Extension
+	if len(issuer.SubjectKeyId) > 0 {
+		der, err := asn1.Marshal(authorityKeyID{issuer.SubjectKeyId})
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtAuthorityKeyID, Value: der})
+	}
+	der, err := asn1.Marshal(template.Number)
+	if err != nil {
+		return nil, err
+	}
+	exts = append(exts, pkix.Extension{Id: oidExtCRLNumber, Value: der})
+	tbs.Extensions = appendExtra(exts, template.ExtraExtensions)
+	tbsDER, err := asn1.Marshal(tbs)
+	if err != nil {
+		return nil, err
+	}
+	algo, sig, err := SignData(rand, signer, keyAlgo, tbsDER)
+	if err != nil {
+		return nil, err
+	}
+	return marshalSigned(tbsDER, algo, sig)
+}
+
+// Check GOST signature of the CRL made by the issuer.
+func CheckRevocationListSignature(crl *x509.RevocationList, issuer *x509.Certificate) error {
+	if string(crl.RawIssuer) != string(issuer.RawSubject) {
+		return errors.New("gogost/x509gost: CRL issuer differs")
+	}
+	_, err := CheckSignedSignature(crl.Raw, issuer.RawSubjectPublicKeyInfo)
+	return err
+}
+
+// Is the certificate with the serial number revoked by the CRL.
+func IsRevoked(crl *x509.RevocationList, serial *big.Int) bool {
+	for _, rc := range crl.RevokedCertificates {
+		if rc.SerialNumber.Cmp(serial) == 0 {
+			return true
+		}
+	}
+	return false
+}
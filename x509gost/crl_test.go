@@ -0,0 +1,77 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package x509gost
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+func TestCreateRevocationList(t *testing.T) {
+	for _, c := range []*gost3410.Curve{
+		gost3410.CurveIdtc26gost34102012256paramSetA(),
+		gost3410.CurveIdtc26gost34102012512paramSetC(),
+	} {
+		ca, caPrv := issue(t, testCert{cn: "CA", ca: true, maxPathLen: -1, curve: c}, nil, nil)
+		reason, err := ReasonCodeExtension(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tmpl := x509.RevocationList{
+			Number:     big.NewInt(3),
+			ThisUpdate: testNow,
+			NextUpdate: testNow.Add(24 * time.Hour),
+			RevokedCertificates: []pkix.RevokedCertificate{
+				{SerialNumber: big.NewInt(10), RevocationTime: testNow.Add(-time.Hour)},
+				{
+					SerialNumber:   big.NewInt(11),
+					RevocationTime: testNow.Add(-time.Minute),
+					Extensions:     []pkix.Extension{reason},
+				},
+			},
+		}
+		der, err := CreateRevocationList(rand.Reader, &tmpl, ca, caPrv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = CheckRevocationListSignature(crl, ca); err != nil {
+			t.Fatal(err)
+		}
+		if crl.Number.Cmp(tmpl.Number) != 0 ||
+			!crl.ThisUpdate.Equal(tmpl.ThisUpdate) ||
+			!crl.NextUpdate.Equal(tmpl.NextUpdate) ||
+			!IsRevoked(crl, big.NewInt(10)) || !IsRevoked(crl, big.NewInt(11)) ||
+			IsRevoked(crl, big.NewInt(12)) ||
+			len(crl.RevokedCertificates[1].Extensions) != 1 ||
+			string(crl.AuthorityKeyId) != string(ca.SubjectKeyId) {
+			t.Fatal("CRL differs")
+		}
+		other, _ := issue(t, testCert{cn: "CA", ca: true, maxPathLen: -1, curve: c}, nil, nil)
+		if err = CheckRevocationListSignature(crl, other); err == nil {
+			t.Fatal("foreign issuer accepted")
+		}
+	}
+}
@@ -0,0 +1,367 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// X.509 certificates with GOST R 34.10 keys and signatures for the
+// stock crypto/x509, that parses them but can not check signatures.
+//
+// Signatures follow RFC 4491/9215 conventions: the digest is reversed
+// before signing and signature is s||r.
+package x509gost
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+// Maximal length of the built chain.
+const MaxChainLen = 16
+
+var ErrSignature = errors.New("gogost/x509gost: invalid signature")
+
+func unmarshal(der []byte, v interface{}, what string) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return fmt.Errorf("gogost/x509gost: invalid %s: %w", what, err)
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("gogost/x509gost: trailing data after %s", what)
+	}
+	return nil
+}
+
+// Certificate, CertificateList and CertificationRequest share the
+// same signed structure.
+type signed struct {
+	Data      asn1.RawValue
+	Algo      pkix.AlgorithmIdentifier
+	Signature asn1.BitString
+}
+
+type subjectPublicKeyInfo struct {
+	Algo      pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// Parse GOST public key from DER encoded SubjectPublicKeyInfo,
+// returning also its public key algorithm identifier.
+func ParseSubjectPublicKeyInfo(der []byte) (*gost3410.PublicKey, asn1.ObjectIdentifier, error) {
+	var spki subjectPublicKeyInfo
+	if err := unmarshal(der, &spki, "SubjectPublicKeyInfo"); err != nil {
+		return nil, nil, err
+	}
+	pub, err := gost3410.ParsePublicKey(spki.Algo, spki.PublicKey.RightAlign())
+	if err != nil {
+		return nil, nil, err
+	}
+	return pub, spki.Algo.Algorithm, nil
+}
+
+// GOST public key of the certificate and its algorithm identifier.
+func PublicKey(cert *x509.Certificate) (*gost3410.PublicKey, asn1.ObjectIdentifier, error) {
+	return ParseSubjectPublicKeyInfo(cert.RawSubjectPublicKeyInfo)
+}
+
+// Check the signature over signed data made with the GOST public key
+// of keyAlgo algorithm. Signature algorithm must correspond to the
+// key: GOST R 34.11-94 with 34.10-2001 or Streebog with 34.10-2012.
+func CheckSignature(
+	sigAlgo, keyAlgo asn1.ObjectIdentifier,
+	pub *gost3410.PublicKey,
+	data, signature []byte,
+) error {
+	digest, expected := gost3410.SignatureAlgorithms(keyAlgo)
+	if expected == nil {
+		return fmt.Errorf("gogost/x509gost: unsupported key algorithm %s", keyAlgo)
+	}
+	if !sigAlgo.Equal(expected) {
+		return fmt.Errorf("gogost/x509gost: signature algorithm %s does not match the key", sigAlgo)
+	}
+	h := gost3410.NewHash(digest)
+	h.Write(data)
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(h.Sum(nil), signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrSignature
+	}
+	return nil
+}
+
+// Check the signature of DER encoded signed structure (Certificate,
+// CertificateList, CertificationRequest) with the DER encoded
+// SubjectPublicKeyInfo. The signed TBS data is returned.
+func CheckSignedSignature(der, spki []byte) ([]byte, error) {
+	var s signed
+	if err := unmarshal(der, &s, "signed structure"); err != nil {
+		return nil, err
+	}
+	pub, keyAlgo, err := ParseSubjectPublicKeyInfo(spki)
+	if err != nil {
+		return nil, err
+	}
+	if s.Signature.BitLength%8 != 0 {
+		return nil, errors.New("gogost/x509gost: invalid signature bit string")
+	}
+	err = CheckSignature(s.Algo.Algorithm, keyAlgo, pub, s.Data.FullBytes, s.Signature.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return s.Data.FullBytes, nil
+}
+
+// Check that the certificate's GOST signature is made by the parent.
+// Unlike x509.Certificate.CheckSignatureFrom, it does not check
+// parent's CA constraints.
+func CheckSignatureFrom(cert, parent *x509.Certificate) error {
+	_, err := CheckSignedSignature(cert.Raw, parent.RawSubjectPublicKeyInfo)
+	return err
+}
+
+// Set of certificates to build chains with.
+type CertPool struct {
+	certs []*x509.Certificate
+}
+
+func NewCertPool() *CertPool {
+	return &CertPool{}
+}
+
+func (p *CertPool) AddCert(cert *x509.Certificate) {
+	if !p.contains(cert) {
+		p.certs = append(p.certs, cert)
+	}
+}
+
+// Add certificates from PEM CERTIFICATE blocks. False is returned if
+// none of them was parsed.
+func (p *CertPool) AppendCertsFromPEM(pemCerts []byte) (ok bool) {
+	var block *pem.Block
+	for len(pemCerts) > 0 {
+		block, pemCerts = pem.Decode(pemCerts)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" || len(block.Headers) != 0 {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		p.AddCert(cert)
+		ok = true
+	}
+	return
+}
+
+func (p *CertPool) contains(cert *x509.Certificate) bool {
+	if p == nil {
+		return false
+	}
+	for _, c := range p.certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// Candidate issuers of the certificate: subject name equal to its
+// issuer and matching key identifiers, if both are present.
+func (p *CertPool) issuers(cert *x509.Certificate) (issuers []*x509.Certificate) {
+	if p == nil {
+		return nil
+	}
+	for _, c := range p.certs {
+		if string(c.RawSubject) != string(cert.RawIssuer) {
+			continue
+		}
+		if len(c.SubjectKeyId) > 0 && len(cert.AuthorityKeyId) > 0 &&
+			string(c.SubjectKeyId) != string(cert.AuthorityKeyId) {
+			continue
+		}
+		issuers = append(issuers, c)
+	}
+	return
+}
+
+type VerifyOptions struct {
+	Roots         *CertPool
+	Intermediates *CertPool
+
+	// Time to check validity periods at, current one by default.
+	CurrentTime time.Time
+
+	// If set, leaf certificate must be valid for the host name.
+	DNSName string
+
+	// Acceptable extended key usages, x509.ExtKeyUsageServerAuth by
+	// default. x509.ExtKeyUsageAny accepts any.
+	KeyUsages []x509.ExtKeyUsage
+}
+
+func checkCert(cert *x509.Certificate, now time.Time) error {
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return x509.CertificateInvalidError{
+			Cert:   cert,
+			Reason: x509.Expired,
+			Detail: fmt.Sprintf(
+				"current time %s is outside of %s..%s",
+				now.Format(time.RFC3339),
+				cert.NotBefore.Format(time.RFC3339),
+				cert.NotAfter.Format(time.RFC3339),
+			),
+		}
+	}
+	if len(cert.UnhandledCriticalExtensions) > 0 {
+		return x509.UnhandledCriticalExtension{}
+	}
+	return nil
+}
+
+// Check that the parent may issue the certificate at the end of the
+// chain, including its signature.
+func checkIssuer(parent *x509.Certificate, chain []*x509.Certificate, now time.Time) error {
+	if err := checkCert(parent, now); err != nil {
+		return err
+	}
+	if !parent.BasicConstraintsValid || !parent.IsCA {
+		return x509.CertificateInvalidError{Cert: parent, Reason: x509.NotAuthorizedToSign}
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return x509.CertificateInvalidError{Cert: parent, Reason: x509.NotAuthorizedToSign}
+	}
+	if parent.MaxPathLen > 0 || (parent.MaxPathLen == 0 && parent.MaxPathLenZero) {
+		if len(chain)-1 > parent.MaxPathLen {
+			return x509.CertificateInvalidError{Cert: parent, Reason: x509.TooManyIntermediates}
+		}
+	}
+	return CheckSignatureFrom(chain[len(chain)-1], parent)
+}
+
+func buildChains(
+	chain []*x509.Certificate,
+	opts *VerifyOptions,
+	now time.Time,
+) (chains [][]*x509.Certificate, err error) {
+	cert := chain[len(chain)-1]
+	if opts.Roots.contains(cert) {
+		return [][]*x509.Certificate{chain}, nil
+	}
+	for _, root := range opts.Roots.issuers(cert) {
+		if err = checkIssuer(root, chain, now); err != nil {
+			continue
+		}
+		chains = append(chains, append(chain[:len(chain):len(chain)], root))
+	}
+	if len(chain) >= MaxChainLen {
+		return chains, err
+	}
+Intermediates:
+	for _, inter := range opts.Intermediates.issuers(cert) {
+		for _, c := range chain {
+			if c.Equal(inter) {
+				continue Intermediates
+			}
+		}
+		if err = checkIssuer(inter, chain, now); err != nil {
+			continue
+		}
+		var found [][]*x509.Certificate
+		found, err = buildChains(append(chain[:len(chain):len(chain)], inter), opts, now)
+		chains = append(chains, found...)
+	}
+	return chains, err
+}
+
+func checkKeyUsages(chain []*x509.Certificate, usages []x509.ExtKeyUsage) bool {
+	for _, usage := range usages {
+		if usage == x509.ExtKeyUsageAny {
+			return true
+		}
+	}
+	for _, cert := range chain {
+		if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+			continue
+		}
+		ok := false
+	Usages:
+		for _, have := range cert.ExtKeyUsage {
+			if have == x509.ExtKeyUsageAny {
+				ok = true
+				break
+			}
+			for _, usage := range usages {
+				if have == usage {
+					ok = true
+					break Usages
+				}
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify the GOST certificate by building chains from it to the
+// roots, checking signatures, validity periods, CA basic constraints,
+// path length, certificate signing key usage and extended key usages.
+// Each returned chain starts with the certificate and ends with the
+// root. Name constraints are not checked.
+func Verify(cert *x509.Certificate, opts VerifyOptions) ([][]*x509.Certificate, error) {
+	now := opts.CurrentTime
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err := checkCert(cert, now); err != nil {
+		return nil, err
+	}
+	if opts.DNSName != "" {
+		if err := cert.VerifyHostname(opts.DNSName); err != nil {
+			return nil, err
+		}
+	}
+	chains, err := buildChains([]*x509.Certificate{cert}, &opts, now)
+	if len(chains) == 0 {
+		if err != nil {
+			return nil, err
+		}
+		return nil, x509.UnknownAuthorityError{Cert: cert}
+	}
+	usages := opts.KeyUsages
+	if len(usages) == 0 {
+		usages = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	}
+	valid := chains[:0]
+	for _, chain := range chains {
+		if checkKeyUsages(chain, usages) {
+			valid = append(valid, chain)
+		}
+	}
+	if len(valid) == 0 {
+		return nil, x509.CertificateInvalidError{Cert: cert, Reason: x509.IncompatibleUsage}
+	}
+	return valid, nil
+}
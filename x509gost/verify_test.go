@@ -0,0 +1,209 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package x509gost
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+)
+
+type testCert struct {
+	cn         string
+	ca         bool
+	maxPathLen int
+	ekus       []asn1.ObjectIdentifier
+	notAfter   time.Time
+	curve      *gost3410.Curve
+	legacy     bool
+}
+
+var (
+	testNow         = time.Now().UTC().Truncate(time.Second)
+	oidKPServerAuth = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}
+)
+
+// Issue certificate by the parent (self-signed if nil) with the new
+// key.
+func issue(
+	t *testing.T,
+	tc testCert,
+	parent *x509.Certificate,
+	parentPrv *gost3410.PrivateKey,
+) (*x509.Certificate, *gost3410.PrivateKey) {
+	if tc.curve == nil {
+		tc.curve = gost3410.CurveIdtc26gost34102012256paramSetA()
+	}
+	if tc.notAfter.IsZero() {
+		tc.notAfter = testNow.Add(time.Hour)
+	}
+	prv, err := gost3410.GenPrivateKey(tc.curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := prv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var spki []byte
+	if tc.legacy {
+		spki, err = gost3410.MarshalPKIXPublicKey2001(pub)
+	} else {
+		spki, err = gost3410.MarshalPKIXPublicKey(pub)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := x509.Certificate{
+		SerialNumber:          serial.Add(serial, big.NewInt(1)),
+		Subject:               pkix.Name{CommonName: tc.cn},
+		NotBefore:             testNow.Add(-time.Hour),
+		NotAfter:              tc.notAfter,
+		UnknownExtKeyUsage:    tc.ekus,
+		BasicConstraintsValid: tc.ca,
+		IsCA:                  tc.ca,
+		MaxPathLen:            tc.maxPathLen,
+		MaxPathLenZero:        tc.maxPathLen == 0,
+	}
+	signer := prv
+	if parent == nil {
+		parent = &tmpl
+	} else {
+		signer = parentPrv
+	}
+	der, err := CreateCertificate(rand.Reader, &tmpl, parent, spki, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, prv
+}
+
+func TestCheckSignatureFrom(t *testing.T) {
+	for _, tc := range []testCert{
+		{cn: "2001", curve: gost3410.CurveIdGostR34102001CryptoProAParamSet(), legacy: true},
+		{cn: "2012-256", curve: gost3410.CurveIdtc26gost34102012256paramSetB()},
+		{cn: "2012-512", curve: gost3410.CurveIdtc26gost34102012512paramSetA()},
+	} {
+		tc.ca = true
+		ca, caPrv := issue(t, tc, nil, nil)
+		if err := CheckSignatureFrom(ca, ca); err != nil {
+			t.Fatal(tc.cn, err)
+		}
+		leaf, _ := issue(t, testCert{cn: "leaf"}, ca, caPrv)
+		if err := CheckSignatureFrom(leaf, ca); err != nil {
+			t.Fatal(tc.cn, err)
+		}
+		if err := CheckSignatureFrom(ca, leaf); err == nil {
+			t.Fatal(tc.cn, "foreign signature accepted")
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	root, rootPrv := issue(t, testCert{cn: "root", ca: true, maxPathLen: -1}, nil, nil)
+	inter, interPrv := issue(t, testCert{
+		cn: "inter", ca: true, maxPathLen: 0,
+		curve: gost3410.CurveIdtc26gost34102012512paramSetB(),
+	}, root, rootPrv)
+	leaf, _ := issue(t, testCert{cn: "leaf", ekus: []asn1.ObjectIdentifier{oidKPServerAuth}}, inter, interPrv)
+
+	roots := NewCertPool()
+	roots.AddCert(root)
+	inters := NewCertPool()
+	inters.AddCert(inter)
+	chains, err := Verify(leaf, VerifyOptions{Roots: roots, Intermediates: inters})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chains) != 1 || len(chains[0]) != 3 ||
+		!chains[0][0].Equal(leaf) || !chains[0][1].Equal(inter) || !chains[0][2].Equal(root) {
+		t.Fatal("unexpected chains")
+	}
+	if chains, err = Verify(root, VerifyOptions{Roots: roots}); err != nil || len(chains[0]) != 1 {
+		t.Fatal("root is not verified", err)
+	}
+
+	var unknown x509.UnknownAuthorityError
+	if _, err = Verify(leaf, VerifyOptions{Roots: roots}); !errors.As(err, &unknown) {
+		t.Fatal("no intermediate accepted", err)
+	}
+
+	var invalid x509.CertificateInvalidError
+	_, err = Verify(leaf, VerifyOptions{
+		Roots: roots, Intermediates: inters,
+		CurrentTime: testNow.Add(2 * time.Hour),
+	})
+	if !errors.As(err, &invalid) || invalid.Reason != x509.Expired {
+		t.Fatal("expired accepted", err)
+	}
+
+	_, err = Verify(leaf, VerifyOptions{
+		Roots: roots, Intermediates: inters,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	})
+	if !errors.As(err, &invalid) || invalid.Reason != x509.IncompatibleUsage {
+		t.Fatal("incompatible usage accepted", err)
+	}
+}
+
+func TestVerifyConstraints(t *testing.T) {
+	root, rootPrv := issue(t, testCert{cn: "root", ca: true, maxPathLen: 0}, nil, nil)
+	roots := NewCertPool()
+	roots.AddCert(root)
+
+	// Intermediate is forbidden by root's zero path length
+	inter, interPrv := issue(t, testCert{cn: "inter", ca: true, maxPathLen: -1}, root, rootPrv)
+	leaf, _ := issue(t, testCert{cn: "leaf"}, inter, interPrv)
+	inters := NewCertPool()
+	inters.AddCert(inter)
+	var invalid x509.CertificateInvalidError
+	_, err := Verify(leaf, VerifyOptions{Roots: roots, Intermediates: inters})
+	if !errors.As(err, &invalid) || invalid.Reason != x509.TooManyIntermediates {
+		t.Fatal("path length exceeded", err)
+	}
+
+	// Non-CA can not issue
+	nonCA, nonCAPrv := issue(t, testCert{cn: "non-ca"}, root, rootPrv)
+	leaf, _ = issue(t, testCert{cn: "leaf"}, nonCA, nonCAPrv)
+	inters = NewCertPool()
+	inters.AddCert(nonCA)
+	_, err = Verify(leaf, VerifyOptions{Roots: roots, Intermediates: inters})
+	if !errors.As(err, &invalid) || invalid.Reason != x509.NotAuthorizedToSign {
+		t.Fatal("non-CA issuer accepted", err)
+	}
+
+	// Forged issuer with the same name
+	forged, forgedPrv := issue(t, testCert{cn: "root", ca: true, maxPathLen: -1}, nil, nil)
+	leaf, _ = issue(t, testCert{cn: "leaf"}, forged, forgedPrv)
+	if _, err = Verify(leaf, VerifyOptions{Roots: roots}); err == nil {
+		t.Fatal("forged signature accepted", err)
+	}
+}
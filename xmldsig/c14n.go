@@ -0,0 +1,206 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xmldsig
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Exclusive XML Canonicalization Version 1.0.
+type canonicalizer struct {
+	buf          bytes.Buffer
+	inclusive    map[string]bool
+	exclude      *Element
+	withComments bool
+}
+
+func (c *canonicalizer) element(e *Element, rendered map[string]string) error {
+	if e == c.exclude {
+		return nil
+	}
+	utilized := map[string]bool{e.Prefix: true}
+	var attrs []Attr
+	for _, a := range e.Attrs {
+		if a.isNS() {
+			continue
+		}
+		attrs = append(attrs, a)
+		if a.Prefix != "" && a.Prefix != "xml" {
+			utilized[a.Prefix] = true
+		}
+	}
+	for prefix := range c.inclusive {
+		if _, ok := e.LookupNS(prefix); ok {
+			utilized[prefix] = true
+		}
+	}
+	prefixes := make([]string, 0, len(utilized))
+	for prefix := range utilized {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	c.buf.WriteByte('<')
+	c.buf.WriteString(e.qname())
+	var ours map[string]string
+	for _, prefix := range prefixes {
+		ns, ok := e.LookupNS(prefix)
+		if prefix == "xml" {
+			continue
+		}
+		if prefix != "" && !ok {
+			return fmt.Errorf("gogost/xmldsig: undeclared prefix %s", prefix)
+		}
+		if rendered[prefix] == ns {
+			continue
+		}
+		if ours == nil {
+			ours = make(map[string]string, len(rendered)+len(prefixes))
+			for k, v := range rendered {
+				ours[k] = v
+			}
+		}
+		ours[prefix] = ns
+		if prefix == "" {
+			c.buf.WriteString(" xmlns=\"")
+		} else {
+			c.buf.WriteString(" xmlns:")
+			c.buf.WriteString(prefix)
+			c.buf.WriteString("=\"")
+		}
+		attrEscaper.WriteString(&c.buf, ns)
+		c.buf.WriteByte('"')
+	}
+	if ours != nil {
+		rendered = ours
+	}
+
+	keys := make([]string, len(attrs))
+	for i, a := range attrs {
+		if a.Prefix != "" {
+			ns, _ := e.LookupNS(a.Prefix)
+			keys[i] = ns
+		}
+		keys[i] += " " + a.Local
+	}
+	sort.Sort(attrsByKey{attrs, keys})
+	for _, a := range attrs {
+		c.buf.WriteByte(' ')
+		c.buf.WriteString(a.qname())
+		c.buf.WriteString("=\"")
+		attrEscaper.WriteString(&c.buf, a.Value)
+		c.buf.WriteByte('"')
+	}
+	c.buf.WriteByte('>')
+	for _, n := range e.Children {
+		if err := c.node(n, rendered); err != nil {
+			return err
+		}
+	}
+	c.buf.WriteString("</")
+	c.buf.WriteString(e.qname())
+	c.buf.WriteByte('>')
+	return nil
+}
+
+func (c *canonicalizer) node(n Node, rendered map[string]string) error {
+	switch t := n.(type) {
+	case *Element:
+		return c.element(t, rendered)
+	case CharData:
+		textEscaper.WriteString(&c.buf, string(t))
+	case Comment:
+		if c.withComments {
+			writeNode(&c.buf, t)
+		}
+	case ProcInst:
+		writeNode(&c.buf, t)
+	}
+	return nil
+}
+
+type attrsByKey struct {
+	attrs []Attr
+	keys  []string
+}
+
+func (a attrsByKey) Len() int           { return len(a.attrs) }
+func (a attrsByKey) Less(i, j int) bool { return a.keys[i] < a.keys[j] }
+func (a attrsByKey) Swap(i, j int) {
+	a.attrs[i], a.attrs[j] = a.attrs[j], a.attrs[i]
+	a.keys[i], a.keys[j] = a.keys[j], a.keys[i]
+}
+
+func newCanonicalizer(prefixList string, exclude *Element, withComments bool) *canonicalizer {
+	c := canonicalizer{exclude: exclude, withComments: withComments}
+	if prefixes := strings.Fields(prefixList); len(prefixes) > 0 {
+		c.inclusive = make(map[string]bool, len(prefixes))
+		for _, prefix := range prefixes {
+			if prefix == "#default" {
+				prefix = ""
+			}
+			c.inclusive[prefix] = true
+		}
+	}
+	return &c
+}
+
+// Exclusive canonicalization of the element's subtree. prefixList is
+// the whitespace separated InclusiveNamespaces PrefixList, with
+// "#default" for the default namespace. Exclude element (with its
+// subtree) is omitted, as enveloped signature transform requires.
+func CanonicalizeElement(e *Element, prefixList string, exclude *Element, withComments bool) ([]byte, error) {
+	c := newCanonicalizer(prefixList, exclude, withComments)
+	if err := c.element(e, map[string]string{}); err != nil {
+		return nil, err
+	}
+	return c.buf.Bytes(), nil
+}
+
+// Is document level node (outside the root element) rendered.
+func (c *canonicalizer) rendered(n Node) bool {
+	switch t := n.(type) {
+	case ProcInst:
+		return t.Target != "xml"
+	case Comment:
+		return c.withComments
+	}
+	return false
+}
+
+// Exclusive canonicalization of the whole document.
+func Canonicalize(doc *Document, prefixList string, exclude *Element, withComments bool) ([]byte, error) {
+	c := newCanonicalizer(prefixList, exclude, withComments)
+	for _, n := range doc.Prolog {
+		if c.rendered(n) {
+			c.node(n, nil)
+			c.buf.WriteByte('\n')
+		}
+	}
+	if err := c.element(doc.Root, map[string]string{}); err != nil {
+		return nil, err
+	}
+	for _, n := range doc.Epilogue {
+		if c.rendered(n) {
+			c.buf.WriteByte('\n')
+			c.node(n, nil)
+		}
+	}
+	return c.buf.Bytes(), nil
+}
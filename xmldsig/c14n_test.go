@@ -0,0 +1,113 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xmldsig
+
+import (
+	"testing"
+)
+
+func canonical(t *testing.T, data string) string {
+	doc, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Canonicalize(doc, "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestCanonicalize(t *testing.T) {
+	// Output checked against xmllint --exc-c14n
+	data := "<?xml version=\"1.0\"?>\n<!-- before --><?pi before?>\n" +
+		"<n0:local xmlns:n0=\"foo:bar\" xmlns:n3=\"ftp://example.org\" xmlns=\"http://def\" " +
+		"b=\"2\" a='1 &amp; \"q\"\ttab&#9;ref\nnl'>\n" +
+		"<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\" n3:z=\"1\" n1:a=\"2\" c=\"&lt;x&gt;\">" +
+		"<n3:stuff xmlns:n3=\"ftp://example.org\"/>" +
+		"<inner xmlns=\"\"><deeper/></inner>" +
+		"<def><![CDATA[ a < b & c > d ]]>&#13;</def><!-- inside --></n1:elem2>" +
+		"</n0:local>\n<?pi after?>\n"
+	expected := "<?pi before?>\n" +
+		"<n0:local xmlns:n0=\"foo:bar\" a=\"1 &amp; &quot;q&quot; tab&#x9;ref nl\" b=\"2\">\n" +
+		"<n1:elem2 xmlns:n1=\"http://example.net\" xmlns:n3=\"ftp://example.org\" " +
+		"c=\"&lt;x>\" n3:z=\"1\" n1:a=\"2\" xml:lang=\"en\">" +
+		"<n3:stuff></n3:stuff>" +
+		"<inner><deeper></deeper></inner>" +
+		"<def xmlns=\"http://def\"> a &lt; b &amp; c &gt; d &#xD;</def></n1:elem2>" +
+		"</n0:local>\n<?pi after?>"
+	if got := canonical(t, data); got != expected {
+		t.Fatalf("\n%s\n%s", got, expected)
+	}
+}
+
+func TestCanonicalizeElement(t *testing.T) {
+	// Exclusive XML Canonicalization specification's example
+	doc, err := Parse([]byte(
+		"<n0:local xmlns:n0=\"foo:bar\" xmlns:n3=\"ftp://example.org\">" +
+			"<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">" +
+			"<n3:stuff xmlns:n3=\"ftp://example.org\"/>" +
+			"</n1:elem2></n0:local>",
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	elem2 := doc.Root.Children[0].(*Element)
+	out, err := CanonicalizeElement(elem2, "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "<n1:elem2 xmlns:n1=\"http://example.net\" xml:lang=\"en\">"+
+		"<n3:stuff xmlns:n3=\"ftp://example.org\"></n3:stuff></n1:elem2>" {
+		t.Fatal(string(out))
+	}
+	out, err = CanonicalizeElement(elem2, "n0 n3 absent", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "<n1:elem2 xmlns:n0=\"foo:bar\" xmlns:n1=\"http://example.net\" "+
+		"xmlns:n3=\"ftp://example.org\" xml:lang=\"en\">"+
+		"<n3:stuff></n3:stuff></n1:elem2>" {
+		t.Fatal(string(out))
+	}
+	out, err = CanonicalizeElement(doc.Root, "", elem2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "<n0:local xmlns:n0=\"foo:bar\"></n0:local>" {
+		t.Fatal(string(out))
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	data := "<?xml version=\"1.0\"?>\n<!DOCTYPE a>\n<a xmlns=\"urn:a\" b=\"&quot;\">" +
+		"<c/>text &amp; <!--comment--><?pi x?></a>\n"
+	doc, err := Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc.Bytes()) != data {
+		t.Fatal(string(doc.Bytes()))
+	}
+	if doc.Root.Children[0].(*Element).NS() != "urn:a" {
+		t.FailNow()
+	}
+	for _, bad := range []string{"", "<a>", "<a></b>", "<a/><b/>", "<a/>text"} {
+		if _, err = Parse([]byte(bad)); err == nil {
+			t.Fatal("accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xmldsig
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Namespace bound to the xml prefix.
+const NSXML = "http://www.w3.org/XML/1998/namespace"
+
+// Document's node: *Element, CharData, Comment, ProcInst or Directive.
+type Node interface{}
+
+type (
+	CharData  string
+	Comment   string
+	Directive string
+)
+
+type ProcInst struct {
+	Target string
+	Inst   string
+}
+
+// Attribute, including namespace declarations: xmlns="..." has empty
+// prefix and "xmlns" local name, xmlns:p="..." has "xmlns" prefix.
+type Attr struct {
+	Prefix string
+	Local  string
+	Value  string
+}
+
+func (a *Attr) isNS() bool {
+	return a.Prefix == "xmlns" || (a.Prefix == "" && a.Local == "xmlns")
+}
+
+func (a *Attr) qname() string {
+	if a.Prefix == "" {
+		return a.Local
+	}
+	return a.Prefix + ":" + a.Local
+}
+
+type Element struct {
+	Prefix   string
+	Local    string
+	Attrs    []Attr
+	Children []Node
+	Parent   *Element
+}
+
+// Create element, declaring its prefix with the namespace.
+func NewElement(prefix, local, ns string) *Element {
+	e := &Element{Prefix: prefix, Local: local}
+	if ns != "" {
+		if prefix == "" {
+			e.SetAttr("", "xmlns", ns)
+		} else {
+			e.SetAttr("xmlns", prefix, ns)
+		}
+	}
+	return e
+}
+
+func (e *Element) qname() string {
+	if e.Prefix == "" {
+		return e.Local
+	}
+	return e.Prefix + ":" + e.Local
+}
+
+// Append child element, which inherits the parent's prefix.
+func (e *Element) AddElement(local string) *Element {
+	child := &Element{Prefix: e.Prefix, Local: local}
+	e.AddChild(child)
+	return child
+}
+
+func (e *Element) AddChild(n Node) {
+	if child, ok := n.(*Element); ok {
+		child.Parent = e
+	}
+	e.Children = append(e.Children, n)
+}
+
+func (e *Element) RemoveChild(n Node) {
+	for i, c := range e.Children {
+		if c == n {
+			e.Children = append(e.Children[:i], e.Children[i+1:]...)
+			return
+		}
+	}
+}
+
+func (e *Element) SetText(text string) *Element {
+	e.Children = []Node{CharData(text)}
+	return e
+}
+
+// Concatenated character data of the element's children.
+func (e *Element) Text() string {
+	var b strings.Builder
+	for _, c := range e.Children {
+		if s, ok := c.(CharData); ok {
+			b.WriteString(string(s))
+		}
+	}
+	return b.String()
+}
+
+func (e *Element) SetAttr(prefix, local, value string) *Element {
+	for i := range e.Attrs {
+		if e.Attrs[i].Prefix == prefix && e.Attrs[i].Local == local {
+			e.Attrs[i].Value = value
+			return e
+		}
+	}
+	e.Attrs = append(e.Attrs, Attr{prefix, local, value})
+	return e
+}
+
+// Value of unqualified attribute.
+func (e *Element) Attr(local string) (string, bool) {
+	for _, a := range e.Attrs {
+		if a.Prefix == "" && a.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// Namespace bound to the prefix in the element's scope, empty prefix
+// for the default namespace.
+func (e *Element) LookupNS(prefix string) (string, bool) {
+	if prefix == "xml" {
+		return NSXML, true
+	}
+	for ; e != nil; e = e.Parent {
+		for _, a := range e.Attrs {
+			if (prefix == "" && a.Prefix == "" && a.Local == "xmlns") ||
+				(prefix != "" && a.Prefix == "xmlns" && a.Local == prefix) {
+				return a.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Element's namespace.
+func (e *Element) NS() string {
+	ns, _ := e.LookupNS(e.Prefix)
+	return ns
+}
+
+// Is element of the namespace with the local name.
+func (e *Element) Is(ns, local string) bool {
+	return e.Local == local && e.NS() == ns
+}
+
+// First child element of the namespace with the local name.
+func (e *Element) Child(ns, local string) *Element {
+	for _, c := range e.Children {
+		if child, ok := c.(*Element); ok && child.Is(ns, local) {
+			return child
+		}
+	}
+	return nil
+}
+
+// Depth-first walk over the element and its descendants, stopping when
+// the function returns false.
+func (e *Element) Walk(f func(*Element) bool) bool {
+	if !f(e) {
+		return false
+	}
+	for _, c := range e.Children {
+		if child, ok := c.(*Element); ok && !child.Walk(f) {
+			return false
+		}
+	}
+	return true
+}
+
+var idAttrs = []string{"Id", "ID", "id"}
+
+// Find the first element with the Id (or ID, or id) attribute. Use
+// CheckIDs to be sure it is the only one.
+func (e *Element) FindByID(id string) (found *Element) {
+	e.Walk(func(el *Element) bool {
+		for _, name := range idAttrs {
+			if v, ok := el.Attr(name); ok && v == id {
+				found = el
+				return false
+			}
+		}
+		return true
+	})
+	return
+}
+
+// Check that Id (or ID, or id) attribute values are unique among the
+// element and its descendants.
+func (e *Element) CheckIDs() (err error) {
+	seen := make(map[string]*Element)
+	e.Walk(func(el *Element) bool {
+		for _, name := range idAttrs {
+			v, ok := el.Attr(name)
+			if !ok {
+				continue
+			}
+			if other := seen[v]; other != nil && other != el {
+				err = fmt.Errorf("gogost/xmldsig: duplicate Id %q", v)
+				return false
+			}
+			seen[v] = el
+		}
+		return true
+	})
+	return
+}
+
+type Document struct {
+	// Nodes before and after the root element: XML declaration,
+	// document type, comments, processing instructions, whitespaces.
+	Prolog   []Node
+	Root     *Element
+	Epilogue []Node
+}
+
+// Replace literal whitespaces inside attribute values with spaces, as
+// XML attribute-value normalization requires: encoding/xml does not do
+// that and it is impossible to distinguish them from character
+// references after decoding.
+func normalizeAttrs(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	skip := func(i int, end string) int {
+		if j := bytes.Index(out[i:], []byte(end)); j != -1 {
+			return i + j + len(end)
+		}
+		return len(out)
+	}
+	for i := 0; i < len(out); {
+		if out[i] != '<' {
+			i++
+			continue
+		}
+		rest := out[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			i = skip(i, "-->")
+		case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+			i = skip(i, "]]>")
+		case bytes.HasPrefix(rest, []byte("<?")):
+			i = skip(i, "?>")
+		case bytes.HasPrefix(rest, []byte("<!")):
+			if j := bytes.IndexAny(rest, "[>"); j != -1 && rest[j] == '[' {
+				i = skip(i, "]")
+			}
+			i = skip(i, ">")
+		default:
+			var quote byte
+			for i++; i < len(out); i++ {
+				c := out[i]
+				if quote == 0 {
+					if c == '>' {
+						break
+					}
+					if c == '"' || c == '\'' {
+						quote = c
+					}
+					continue
+				}
+				switch c {
+				case quote:
+					quote = 0
+				case '\t', '\n', '\r':
+					out[i] = ' '
+				}
+			}
+		}
+	}
+	return out
+}
+
+func Parse(data []byte) (*Document, error) {
+	dec := xml.NewDecoder(bytes.NewReader(normalizeAttrs(data)))
+	var doc Document
+	var cur *Element
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var n Node
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &Element{Prefix: t.Name.Space, Local: t.Name.Local}
+			for _, a := range t.Attr {
+				e.Attrs = append(e.Attrs, Attr{a.Name.Space, a.Name.Local, a.Value})
+			}
+			if cur == nil {
+				if doc.Root != nil {
+					return nil, errors.New("gogost/xmldsig: multiple root elements")
+				}
+				doc.Root = e
+			} else {
+				cur.AddChild(e)
+			}
+			cur = e
+			continue
+		case xml.EndElement:
+			if cur == nil || cur.Prefix != t.Name.Space || cur.Local != t.Name.Local {
+				return nil, fmt.Errorf("gogost/xmldsig: unexpected end element %s", t.Name.Local)
+			}
+			cur = cur.Parent
+			continue
+		case xml.CharData:
+			n = CharData(t)
+		case xml.Comment:
+			n = Comment(t)
+		case xml.ProcInst:
+			n = ProcInst{t.Target, string(t.Inst)}
+		case xml.Directive:
+			n = Directive(t)
+		}
+		switch {
+		case cur != nil:
+			cur.AddChild(n)
+		case doc.Root == nil:
+			doc.Prolog = append(doc.Prolog, n)
+		default:
+			if s, ok := n.(CharData); ok && len(bytes.TrimSpace([]byte(s))) > 0 {
+				return nil, errors.New("gogost/xmldsig: character data after root element")
+			}
+			doc.Epilogue = append(doc.Epilogue, n)
+		}
+	}
+	if doc.Root == nil {
+		return nil, errors.New("gogost/xmldsig: no root element")
+	}
+	if cur != nil {
+		return nil, errors.New("gogost/xmldsig: unclosed element")
+	}
+	return &doc, nil
+}
+
+var (
+	textEscaper = strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;",
+	)
+	attrEscaper = strings.NewReplacer(
+		"&", "&amp;", "<", "&lt;", "\"", "&quot;",
+		"\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;",
+	)
+)
+
+func writeNode(buf *bytes.Buffer, n Node) {
+	switch t := n.(type) {
+	case *Element:
+		buf.WriteByte('<')
+		buf.WriteString(t.qname())
+		for _, a := range t.Attrs {
+			buf.WriteByte(' ')
+			buf.WriteString(a.qname())
+			buf.WriteString("=\"")
+			attrEscaper.WriteString(buf, a.Value)
+			buf.WriteByte('"')
+		}
+		if len(t.Children) == 0 {
+			buf.WriteString("/>")
+			return
+		}
+		buf.WriteByte('>')
+		for _, c := range t.Children {
+			writeNode(buf, c)
+		}
+		buf.WriteString("</")
+		buf.WriteString(t.qname())
+		buf.WriteByte('>')
+	case CharData:
+		textEscaper.WriteString(buf, string(t))
+	case Comment:
+		buf.WriteString("<!--")
+		buf.WriteString(string(t))
+		buf.WriteString("-->")
+	case ProcInst:
+		buf.WriteString("<?")
+		buf.WriteString(t.Target)
+		if t.Inst != "" {
+			buf.WriteByte(' ')
+			buf.WriteString(t.Inst)
+		}
+		buf.WriteString("?>")
+	case Directive:
+		buf.WriteString("<!")
+		buf.WriteString(string(t))
+		buf.WriteByte('>')
+	}
+}
+
+// Serialize the document back. Attributes order, namespace
+// declarations, prolog and epilogue are kept as is.
+func (doc *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, n := range doc.Prolog {
+		writeNode(&buf, n)
+	}
+	writeNode(&buf, doc.Root)
+	for _, n := range doc.Epilogue {
+		writeNode(&buf, n)
+	}
+	return buf.Bytes()
+}
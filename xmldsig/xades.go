@@ -0,0 +1,136 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xmldsig
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"time"
+)
+
+const (
+	NSXAdES = "http://uri.etsi.org/01903/v1.3.2#"
+
+	// Reference type of SignedProperties.
+	TypeSignedProperties = "http://uri.etsi.org/01903#SignedProperties"
+
+	prefixXAdES   = "xades"
+	propsIDSuffix = "-SignedProperties"
+)
+
+// Append XAdES-BES QualifyingProperties inside ds:Object to the
+// signature.
+func addXAdES(
+	sig *Element,
+	id string,
+	signingTime time.Time,
+	cert *x509.Certificate,
+	digestURI string,
+) error {
+	h, err := newHash(digestURI)
+	if err != nil {
+		return err
+	}
+	h.Write(cert.Raw)
+
+	props := NewElement(prefixXAdES, "QualifyingProperties", NSXAdES)
+	props.SetAttr("", "Target", "#"+id)
+	dsElement(sig, "Object").AddChild(props)
+	signedProps := props.AddElement("SignedProperties")
+	signedProps.SetAttr("", "Id", id+propsIDSuffix)
+	sigProps := signedProps.AddElement("SignedSignatureProperties")
+	sigProps.AddElement("SigningTime").SetText(signingTime.UTC().Format(time.RFC3339))
+	certElem := sigProps.AddElement("SigningCertificate").AddElement("Cert")
+	certDigest := certElem.AddElement("CertDigest")
+	dsElement(certDigest, "DigestMethod").SetAttr("", "Algorithm", digestURI)
+	dsElement(certDigest, "DigestValue").SetText(base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	issuerSerial := certElem.AddElement("IssuerSerial")
+	dsElement(issuerSerial, "X509IssuerName").SetText(cert.Issuer.String())
+	dsElement(issuerSerial, "X509SerialNumber").SetText(cert.SerialNumber.String())
+	return nil
+}
+
+// Fill the signing time from the referenced XAdES SignedProperties and
+// check that its signing certificate is the KeyInfo's one.
+func verifyXAdES(sig *Signature) error {
+	var signedProps *Element
+	for _, c := range sig.Element.Children {
+		object, ok := c.(*Element)
+		if !ok || !object.Is(NSDSig, "Object") {
+			continue
+		}
+		props := object.Child(NSXAdES, "QualifyingProperties")
+		if props == nil {
+			continue
+		}
+		signedProps = props.Child(NSXAdES, "SignedProperties")
+		if signedProps != nil {
+			break
+		}
+	}
+	if signedProps == nil {
+		return nil
+	}
+	signed := false
+	for _, e := range sig.References {
+		signed = signed || e == signedProps
+	}
+	if !signed {
+		return errors.New("gogost/xmldsig: unsigned XAdES SignedProperties")
+	}
+	sigProps := signedProps.Child(NSXAdES, "SignedSignatureProperties")
+	if sigProps == nil {
+		return errors.New("gogost/xmldsig: no XAdES SignedSignatureProperties")
+	}
+	if signingTime := sigProps.Child(NSXAdES, "SigningTime"); signingTime != nil {
+		var err error
+		sig.SigningTime, err = time.Parse(time.RFC3339, signingTime.Text())
+		if err != nil {
+			return err
+		}
+	}
+	signingCert := sigProps.Child(NSXAdES, "SigningCertificate")
+	if signingCert == nil || sig.Certificate == nil {
+		return nil
+	}
+	var certDigest, digestMethod, digestValue *Element
+	if certElem := signingCert.Child(NSXAdES, "Cert"); certElem != nil {
+		certDigest = certElem.Child(NSXAdES, "CertDigest")
+	}
+	if certDigest != nil {
+		digestMethod = certDigest.Child(NSDSig, "DigestMethod")
+		digestValue = certDigest.Child(NSDSig, "DigestValue")
+	}
+	if digestMethod == nil || digestValue == nil {
+		return errors.New("gogost/xmldsig: no XAdES CertDigest")
+	}
+	algo, _ := digestMethod.Attr("Algorithm")
+	h, err := newHash(algo)
+	if err != nil {
+		return err
+	}
+	h.Write(sig.Certificate.Raw)
+	expected, err := decodeBase64(digestValue)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(h.Sum(nil), expected) {
+		return errors.New("gogost/xmldsig: XAdES signing certificate differs")
+	}
+	return nil
+}
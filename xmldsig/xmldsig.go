@@ -0,0 +1,417 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// XML Signature (XMLDSig) with GOST R 34.10-2012 and Streebog, using
+// the urn:ietf:params:xml:ns:cpxmlsec:algorithms URIs, Exclusive XML
+// Canonicalization and optional XAdES-BES qualifying properties.
+package xmldsig
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/gost34112012256"
+	"github.com/pedroalbanese/gogost/gost34112012512"
+	"github.com/pedroalbanese/gogost/x509gost"
+)
+
+const (
+	NSDSig = "http://www.w3.org/2000/09/xmldsig#"
+
+	URIExcC14N             = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	URIExcC14NWithComments = "http://www.w3.org/2001/10/xml-exc-c14n#WithComments"
+	URIEnveloped           = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+
+	URIGOST34112012256 = "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34112012-256"
+	URIGOST34112012512 = "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34112012-512"
+
+	URIGOST34102012256 = "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34102012-gostr34112012-256"
+	URIGOST34102012512 = "urn:ietf:params:xml:ns:cpxmlsec:algorithms:gostr34102012-gostr34112012-512"
+)
+
+var (
+	ErrSignature = errors.New("gogost/xmldsig: invalid signature")
+	ErrDigest    = errors.New("gogost/xmldsig: reference digest mismatch")
+	ErrNoKey     = errors.New("gogost/xmldsig: no public key")
+)
+
+func newHash(uri string) (hash.Hash, error) {
+	switch uri {
+	case URIGOST34112012256:
+		return gost34112012256.New(), nil
+	case URIGOST34112012512:
+		return gost34112012512.New(), nil
+	}
+	return nil, fmt.Errorf("gogost/xmldsig: unsupported digest method %s", uri)
+}
+
+// Signature and digest methods URIs for the curve.
+func algorithms(c *gost3410.Curve) (sigURI, digestURI string) {
+	if c.PointSize() == 64 {
+		return URIGOST34102012512, URIGOST34112012512
+	}
+	return URIGOST34102012256, URIGOST34112012256
+}
+
+// Swap halves of the signature: s||r of gost3410 from/to r||s of
+// XMLDSig SignatureValue.
+func swapHalves(sig []byte) []byte {
+	half := len(sig) / 2
+	return append(append([]byte{}, sig[half:]...), sig[:half]...)
+}
+
+type SignOpts struct {
+	// Signer's certificate, put to KeyInfo.
+	Certificate *x509.Certificate
+
+	// Element to append the signature to, document's root by default.
+	Parent *Element
+
+	// Referenced element's Id, the whole document if empty.
+	Reference string
+
+	// Signature's Id, random one by default.
+	ID string
+
+	// Add XAdES-BES QualifyingProperties with signing time and
+	// certificate. Certificate must be set.
+	XAdES bool
+
+	// XAdES signing time, current one by default.
+	SigningTime time.Time
+}
+
+// Signature element prefix.
+const prefixDSig = "ds"
+
+func dsElement(parent *Element, local string) *Element {
+	e := &Element{Prefix: prefixDSig, Local: local}
+	parent.AddChild(e)
+	return e
+}
+
+func addReference(signedInfo *Element, uri, typ, digestURI string, enveloped bool) *Element {
+	ref := dsElement(signedInfo, "Reference")
+	if typ != "" {
+		ref.SetAttr("", "Type", typ)
+	}
+	ref.SetAttr("", "URI", uri)
+	transforms := dsElement(ref, "Transforms")
+	if enveloped {
+		dsElement(transforms, "Transform").SetAttr("", "Algorithm", URIEnveloped)
+	}
+	dsElement(transforms, "Transform").SetAttr("", "Algorithm", URIExcC14N)
+	dsElement(ref, "DigestMethod").SetAttr("", "Algorithm", digestURI)
+	return dsElement(ref, "DigestValue")
+}
+
+// Create enveloped signature of the document (or its element with the
+// Id) and append it to the parent element. SignatureValue is r||s over
+// the reversed Streebog digest of the canonicalized SignedInfo.
+func Sign(rand io.Reader, doc *Document, prv *gost3410.PrivateKey, opts SignOpts) (*Element, error) {
+	if opts.XAdES && opts.Certificate == nil {
+		return nil, errors.New("gogost/xmldsig: XAdES requires certificate")
+	}
+	if err := doc.Root.CheckIDs(); err != nil {
+		return nil, err
+	}
+	parent := opts.Parent
+	if parent == nil {
+		parent = doc.Root
+	}
+	id := opts.ID
+	if id == "" {
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(rand, buf); err != nil {
+			return nil, err
+		}
+		id = "xmldsig-" + hex.EncodeToString(buf)
+	}
+	sigURI, digestURI := algorithms(prv.C)
+
+	sig := NewElement(prefixDSig, "Signature", NSDSig)
+	sig.SetAttr("", "Id", id)
+	signedInfo := dsElement(sig, "SignedInfo")
+	dsElement(signedInfo, "CanonicalizationMethod").SetAttr("", "Algorithm", URIExcC14N)
+	dsElement(signedInfo, "SignatureMethod").SetAttr("", "Algorithm", sigURI)
+	uri := ""
+	if opts.Reference != "" {
+		uri = "#" + opts.Reference
+	}
+	refDigest := addReference(signedInfo, uri, "", digestURI, true)
+	sigValue := dsElement(sig, "SignatureValue")
+	if opts.Certificate != nil {
+		x509Data := dsElement(dsElement(sig, "KeyInfo"), "X509Data")
+		dsElement(x509Data, "X509Certificate").SetText(
+			base64.StdEncoding.EncodeToString(opts.Certificate.Raw),
+		)
+	}
+	parent.AddChild(sig)
+
+	var propsDigest *Element
+	if opts.XAdES {
+		signingTime := opts.SigningTime
+		if signingTime.IsZero() {
+			signingTime = time.Now()
+		}
+		if err := addXAdES(sig, id, signingTime, opts.Certificate, digestURI); err != nil {
+			parent.RemoveChild(sig)
+			return nil, err
+		}
+		propsDigest = addReference(
+			signedInfo, "#"+id+propsIDSuffix, TypeSignedProperties, digestURI, false,
+		)
+	}
+
+	for _, ref := range []*Element{refDigest, propsDigest} {
+		if ref == nil {
+			continue
+		}
+		_, digest, err := referenceDigest(doc, sig, ref.Parent)
+		if err != nil {
+			parent.RemoveChild(sig)
+			return nil, err
+		}
+		ref.SetText(base64.StdEncoding.EncodeToString(digest))
+	}
+
+	data, err := CanonicalizeElement(signedInfo, "", nil, false)
+	if err != nil {
+		parent.RemoveChild(sig)
+		return nil, err
+	}
+	h, _ := newHash(digestURI)
+	h.Write(data)
+	signature, err := (&gost3410.PrivateKeyReverseDigest{Prv: prv}).Sign(rand, h.Sum(nil), nil)
+	if err != nil {
+		parent.RemoveChild(sig)
+		return nil, err
+	}
+	sigValue.SetText(base64.StdEncoding.EncodeToString(swapHalves(signature)))
+	return sig, nil
+}
+
+// Compute the digest of the Reference element, applying its
+// transforms. Referenced element is returned, root one for the whole
+// document.
+func referenceDigest(doc *Document, sig, ref *Element) (*Element, []byte, error) {
+	uri, _ := ref.Attr("URI")
+	var target *Element
+	switch {
+	case uri == "":
+	case strings.HasPrefix(uri, "#"):
+		if target = doc.Root.FindByID(uri[1:]); target == nil {
+			return nil, nil, fmt.Errorf("gogost/xmldsig: unresolvable reference %s", uri)
+		}
+	default:
+		return nil, nil, fmt.Errorf("gogost/xmldsig: unsupported reference %s", uri)
+	}
+	var exclude *Element
+	var prefixList string
+	if transforms := ref.Child(NSDSig, "Transforms"); transforms != nil {
+		for _, c := range transforms.Children {
+			t, ok := c.(*Element)
+			if !ok || !t.Is(NSDSig, "Transform") {
+				continue
+			}
+			algo, _ := t.Attr("Algorithm")
+			switch algo {
+			case URIEnveloped:
+				exclude = sig
+			case URIExcC14N, URIExcC14NWithComments:
+				// Comments are never kept for same-document references
+				// without XPointer
+				if inclusive := t.Child(URIExcC14N, "InclusiveNamespaces"); inclusive != nil {
+					prefixList, _ = inclusive.Attr("PrefixList")
+				}
+			default:
+				return nil, nil, fmt.Errorf("gogost/xmldsig: unsupported transform %s", algo)
+			}
+		}
+	}
+	digestMethod := ref.Child(NSDSig, "DigestMethod")
+	if digestMethod == nil {
+		return nil, nil, errors.New("gogost/xmldsig: no DigestMethod")
+	}
+	digestURI, _ := digestMethod.Attr("Algorithm")
+	h, err := newHash(digestURI)
+	if err != nil {
+		return nil, nil, err
+	}
+	var data []byte
+	if target == nil {
+		target = doc.Root
+		data, err = Canonicalize(doc, prefixList, exclude, false)
+	} else {
+		data, err = CanonicalizeElement(target, prefixList, exclude, false)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	h.Write(data)
+	return target, h.Sum(nil), nil
+}
+
+type Signature struct {
+	Element *Element
+
+	// Elements referenced by SignedInfo, in its order: root element
+	// for the whole document reference. Only them are signed, so act
+	// only on them.
+	References []*Element
+
+	// Certificate from KeyInfo, if any.
+	Certificate *x509.Certificate
+
+	// XAdES signing time, if any.
+	SigningTime time.Time
+}
+
+// Find the first Signature element in the document.
+func FindSignature(doc *Document) (sig *Element) {
+	doc.Root.Walk(func(e *Element) bool {
+		if e.Is(NSDSig, "Signature") {
+			sig = e
+			return false
+		}
+		return true
+	})
+	return
+}
+
+func decodeBase64(e *Element) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(e.Text()), ""))
+}
+
+// Verify the signature element (the first one in document if nil)
+// with the public key. If the key is nil, then certificate from the
+// KeyInfo is used: it is up to the caller to verify its trust. All
+// references must match. Documents with duplicate Id values are
+// rejected, to prevent signature wrapping, and the caller must use
+// only the signed elements from Signature.References.
+func Verify(doc *Document, sigElem *Element, pub *gost3410.PublicKey) (*Signature, error) {
+	if err := doc.Root.CheckIDs(); err != nil {
+		return nil, err
+	}
+	if sigElem == nil {
+		if sigElem = FindSignature(doc); sigElem == nil {
+			return nil, errors.New("gogost/xmldsig: no Signature")
+		}
+	}
+	sig := Signature{Element: sigElem}
+	if keyInfo := sigElem.Child(NSDSig, "KeyInfo"); keyInfo != nil {
+		if x509Data := keyInfo.Child(NSDSig, "X509Data"); x509Data != nil {
+			if certElem := x509Data.Child(NSDSig, "X509Certificate"); certElem != nil {
+				der, err := decodeBase64(certElem)
+				if err != nil {
+					return nil, err
+				}
+				if sig.Certificate, err = x509.ParseCertificate(der); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if pub == nil {
+		if sig.Certificate == nil {
+			return nil, ErrNoKey
+		}
+		var err error
+		if pub, _, err = x509gost.PublicKey(sig.Certificate); err != nil {
+			return nil, err
+		}
+	}
+
+	signedInfo := sigElem.Child(NSDSig, "SignedInfo")
+	sigValue := sigElem.Child(NSDSig, "SignatureValue")
+	if signedInfo == nil || sigValue == nil {
+		return nil, errors.New("gogost/xmldsig: no SignedInfo or SignatureValue")
+	}
+	c14nMethod := signedInfo.Child(NSDSig, "CanonicalizationMethod")
+	sigMethod := signedInfo.Child(NSDSig, "SignatureMethod")
+	if c14nMethod == nil || sigMethod == nil {
+		return nil, errors.New("gogost/xmldsig: no CanonicalizationMethod or SignatureMethod")
+	}
+	var prefixList string
+	if inclusive := c14nMethod.Child(URIExcC14N, "InclusiveNamespaces"); inclusive != nil {
+		prefixList, _ = inclusive.Attr("PrefixList")
+	}
+	algo, _ := c14nMethod.Attr("Algorithm")
+	if algo != URIExcC14N && algo != URIExcC14NWithComments {
+		return nil, fmt.Errorf("gogost/xmldsig: unsupported canonicalization %s", algo)
+	}
+	data, err := CanonicalizeElement(signedInfo, prefixList, nil, algo == URIExcC14NWithComments)
+	if err != nil {
+		return nil, err
+	}
+	sigURI, digestURI := algorithms(pub.C)
+	if algo, _ = sigMethod.Attr("Algorithm"); algo != sigURI {
+		return nil, fmt.Errorf("gogost/xmldsig: unsupported signature method %s", algo)
+	}
+	signature, err := decodeBase64(sigValue)
+	if err != nil {
+		return nil, err
+	}
+	h, _ := newHash(digestURI)
+	h.Write(data)
+	valid, err := gost3410.PublicKeyReverseDigest{Pub: pub}.VerifyDigest(
+		h.Sum(nil), swapHalves(signature),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrSignature
+	}
+
+	for _, c := range signedInfo.Children {
+		ref, ok := c.(*Element)
+		if !ok || !ref.Is(NSDSig, "Reference") {
+			continue
+		}
+		target, digest, err := referenceDigest(doc, sigElem, ref)
+		if err != nil {
+			return nil, err
+		}
+		digestValue := ref.Child(NSDSig, "DigestValue")
+		if digestValue == nil {
+			return nil, errors.New("gogost/xmldsig: no DigestValue")
+		}
+		expected, err := decodeBase64(digestValue)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(digest, expected) {
+			return nil, ErrDigest
+		}
+		sig.References = append(sig.References, target)
+	}
+	if len(sig.References) == 0 {
+		return nil, errors.New("gogost/xmldsig: no references")
+	}
+	if err = verifyXAdES(&sig); err != nil {
+		return nil, err
+	}
+	return &sig, nil
+}
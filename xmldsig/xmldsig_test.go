@@ -0,0 +1,225 @@
+// GoGOST -- Pure Go GOST cryptographic functions library
+// Copyright (C) 2015-2024 Sergey Matveev <stargrave@stargrave.org>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, version 3 of the License.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package xmldsig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/pedroalbanese/gogost/gost3410"
+	"github.com/pedroalbanese/gogost/internal/testpki"
+)
+
+const testDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<Envelope xmlns="urn:example:envelope" xmlns:b="urn:example:body">
+  <Header/>
+  <b:Body Id="body">
+    <b:Data attr="value">Some &amp; data</b:Data>
+  </b:Body>
+</Envelope>
+`
+
+var signerTmpl = x509.Certificate{
+	SerialNumber: big.NewInt(12345),
+	Subject:      pkix.Name{CommonName: "Signer"},
+}
+
+// Sign, serialize, parse back and verify.
+func signReparse(t *testing.T, prv *gost3410.PrivateKey, opts SignOpts) *Document {
+	doc, err := Parse([]byte(testDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Sign(rand.Reader, doc, prv, opts); err != nil {
+		t.Fatal(err)
+	}
+	if doc, err = Parse(doc.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, curve := range []*gost3410.Curve{
+		gost3410.CurveIdtc26gost34102012256paramSetA(),
+		gost3410.CurveIdtc26gost341012512paramSetA(),
+	} {
+		cert, prv := testpki.New(t, curve, &signerTmpl, nil, nil)
+		pub, err := prv.PublicKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc := signReparse(t, prv, SignOpts{})
+		sig, err := Verify(doc, nil, pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Certificate != nil || sig.Element.Parent != doc.Root {
+			t.FailNow()
+		}
+		if _, err = Verify(doc, nil, nil); err != ErrNoKey {
+			t.Fatal("verified without key", err)
+		}
+
+		doc = signReparse(t, prv, SignOpts{Certificate: cert, Reference: "body", ID: "sig"})
+		if sig, err = Verify(doc, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if !sig.Certificate.Equal(cert) {
+			t.FailNow()
+		}
+		ref := sig.Element.Child(NSDSig, "SignedInfo").Child(NSDSig, "Reference")
+		if uri, _ := ref.Attr("URI"); uri != "#body" {
+			t.FailNow()
+		}
+	}
+}
+
+func TestXAdES(t *testing.T) {
+	cert, prv := testpki.New(t, gost3410.CurveIdtc26gost34102012256paramSetA(), &signerTmpl, nil, nil)
+	signingTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if _, err := Sign(rand.Reader, &Document{}, prv, SignOpts{XAdES: true}); err == nil {
+		t.Fatal("XAdES without certificate accepted")
+	}
+	doc := signReparse(t, prv, SignOpts{
+		Certificate: cert,
+		XAdES:       true,
+		SigningTime: signingTime,
+	})
+	sig, err := Verify(doc, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sig.SigningTime.Equal(signingTime) {
+		t.Fatal("signing time differs")
+	}
+	if len(sig.References) != 2 || sig.References[0] != doc.Root ||
+		!sig.References[1].Is(NSXAdES, "SignedProperties") {
+		t.Fatal("invalid signed references")
+	}
+
+	// KeyInfo is not signed: replace its certificate with another one
+	// for the same key
+	tmpl := signerTmpl
+	tmpl.SerialNumber = big.NewInt(54321)
+	other := testpki.Issue(t, &tmpl, prv, nil, nil)
+	sig.Element.Child(NSDSig, "KeyInfo").Child(NSDSig, "X509Data").Child(
+		NSDSig, "X509Certificate",
+	).SetText(base64.StdEncoding.EncodeToString(other.Raw))
+	if _, err = Verify(doc, nil, nil); err == nil {
+		t.Fatal("another signing certificate accepted")
+	}
+}
+
+func TestTampered(t *testing.T) {
+	prv := testpki.Key(t, gost3410.CurveIdtc26gost34102012256paramSetA())
+	pub, err := prv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := signReparse(t, prv, SignOpts{})
+	data := doc.Bytes()
+
+	tampered, err := Parse(bytes.Replace(data, []byte("Some &amp; data"), []byte("Some &amp; date"), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Verify(tampered, nil, pub); err != ErrDigest {
+		t.Fatal("tampered document accepted", err)
+	}
+
+	tampered, err = Parse(bytes.Replace(data, []byte("URI=\"\""), []byte("URI=\"#body\""), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Verify(tampered, nil, pub); err != ErrSignature {
+		t.Fatal("tampered SignedInfo accepted", err)
+	}
+
+	// Insignificant changes
+	same, err := Parse(bytes.Replace(data, []byte("attr=\"value\""), []byte("attr='value' "), 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Verify(same, nil, pub); err != nil {
+		t.Fatal(err)
+	}
+
+	otherPrv := testpki.Key(t, gost3410.CurveIdtc26gost34102012256paramSetA())
+	otherPub, err := otherPrv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Verify(doc, nil, otherPub); err != ErrSignature {
+		t.Fatal("another key accepted", err)
+	}
+}
+
+func TestWrapping(t *testing.T) {
+	prv := testpki.Key(t, gost3410.CurveIdtc26gost34102012256paramSetA())
+	pub, err := prv.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Parse([]byte(`<Env><Body Id="body"><Pay>10</Pay></Body></Env>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Sign(rand.Reader, doc, prv, SignOpts{Reference: "body"}); err != nil {
+		t.Fatal(err)
+	}
+	data := doc.Bytes()
+	if doc, err = Parse(data); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := Verify(doc, nil, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig.References) != 1 || sig.References[0] != doc.Root.FindByID("body") {
+		t.Fatal("invalid signed references")
+	}
+
+	signed := []byte(`<Body Id="body"><Pay>10</Pay></Body>`)
+	if !bytes.Contains(data, signed) {
+		t.Fatal("no signed body")
+	}
+	for _, forged := range []string{
+		`<Wrapper><Body Id="body"><Pay>10</Pay></Body></Wrapper><Body Id="body"><Pay>1000000</Pay></Body>`,
+		`<Wrapper><Body Id="body"><Pay>10</Pay></Body></Wrapper><Body id="body"><Pay>1000000</Pay></Body>`,
+	} {
+		wrapped, err := Parse(bytes.Replace(data, signed, []byte(forged), 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = Verify(wrapped, nil, pub); err == nil {
+			t.Fatal("wrapped document accepted")
+		}
+	}
+	dup, err := Parse([]byte(`<Env><Body Id="body"/><Body ID="body"/></Env>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Sign(rand.Reader, dup, prv, SignOpts{Reference: "body"}); err == nil {
+		t.Fatal("document with duplicate Id signed")
+	}
+}